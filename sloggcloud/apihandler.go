@@ -0,0 +1,207 @@
+package sloggcloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// EntryLogger は Cloud Logging API にログエントリを書き込むロガーです。
+// cloud.google.com/go/logging の *logging.Logger のように、Log は非同期でエントリをバッファリングしてまとめて送信し、
+// Flush はバッファリングされたエントリを送信することを想定しています。
+type EntryLogger interface {
+	Log(entry Entry)
+	Flush() error
+}
+
+// Entry は Cloud Logging API に書き込むログエントリです。
+// cloud.google.com/go/logging の logging.Entry に対応するフィールドを持ちます。
+type Entry struct {
+	Timestamp      time.Time
+	Severity       string
+	Payload        map[string]any
+	Labels         map[string]string
+	InsertID       string
+	HTTPRequest    *HTTPRequest
+	Operation      *Operation
+	Trace          string
+	SpanID         string
+	TraceSampled   bool
+	SourceLocation *SourceLocation
+}
+
+// SourceLocation はログを出力したソースコードの位置です。
+type SourceLocation struct {
+	File     string
+	Line     int64
+	Function string
+}
+
+// NewAPIHandler は標準出力ではなく Cloud Logging API にログを書き込む Handler を作成します。
+// 各レコードは Cloud Logging のロギングエージェントと同じ規則で Entry に変換され、logger.Log に渡されます。
+// プロセスの終了前に Handler の Close を呼び出すと、logger.Flush でバッファリングされたエントリが送信されます。
+func NewAPIHandler(logger EntryLogger, opts ...Option) *Handler {
+	w := &entryWriter{logger: logger, opts: nil}
+	h := New(w, opts...)
+	w.opts = h.opts
+	return h
+}
+
+// entryWriter は Handler が出力した JSON を Entry に変換して EntryLogger に渡す io.Writer です。
+// slog.JSONHandler は 1 レコードを 1 回の Write で書き込むため、Write ごとに 1 つの Entry を作成します。
+type entryWriter struct {
+	logger EntryLogger
+	opts   *options
+}
+
+func (w *entryWriter) Write(p []byte) (int, error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	// 大きな整数の精度が失われないように json.Number として扱う
+	dec.UseNumber()
+
+	var payload map[string]any
+	if err := dec.Decode(&payload); err != nil {
+		return 0, fmt.Errorf("failed to decode log entry: %w", err)
+	}
+
+	entry, err := w.entry(payload)
+	if err != nil {
+		return 0, err
+	}
+	w.logger.Log(entry)
+	return len(p), nil
+}
+
+func (w *entryWriter) Flush() error {
+	if err := w.logger.Flush(); err != nil {
+		return fmt.Errorf("failed to flush entry logger: %w", err)
+	}
+	return nil
+}
+
+// entry は JSON のペイロードから Cloud Logging が特別に扱うフィールドを取り出して Entry に変換します。
+// 残りのフィールドは jsonPayload として Payload に設定されます。
+func (w *entryWriter) entry(payload map[string]any) (Entry, error) {
+	entry := Entry{
+		Timestamp:      time.Time{},
+		Severity:       popString(payload, w.opts.severityKey),
+		Payload:        payload,
+		Labels:         nil,
+		InsertID:       popString(payload, insertIDKey),
+		HTTPRequest:    nil,
+		Operation:      nil,
+		Trace:          popString(payload, traceKey),
+		SpanID:         popString(payload, spanIDKey),
+		TraceSampled:   false,
+		SourceLocation: nil,
+	}
+
+	if ts := popString(payload, w.opts.timeKey); ts != "" {
+		t, err := time.Parse(w.opts.timeFormat, ts)
+		if err != nil {
+			return Entry{}, fmt.Errorf("failed to parse timestamp: %w", err)
+		}
+		entry.Timestamp = t
+	}
+	if sampled, ok := payload[traceSampledKey].(bool); ok {
+		entry.TraceSampled = sampled
+		delete(payload, traceSampledKey)
+	}
+	if labels := popObject(payload, labelsKey); labels != nil {
+		entry.Labels = make(map[string]string, len(labels))
+		for k, v := range labels {
+			entry.Labels[k] = fmt.Sprint(v)
+		}
+	}
+	if req := popObject(payload, httpRequestKey); req != nil {
+		httpReq, err := httpRequestFromPayload(req)
+		if err != nil {
+			return Entry{}, err
+		}
+		entry.HTTPRequest = httpReq
+	}
+	if op := popObject(payload, operationKey); op != nil {
+		entry.Operation = &Operation{
+			ID:       popString(op, "id"),
+			Producer: popString(op, "producer"),
+			First:    op["first"] == true,
+			Last:     op["last"] == true,
+		}
+	}
+	if loc := popObject(payload, w.opts.sourceKey); loc != nil {
+		var line int64
+		if n, ok := loc["line"].(json.Number); ok {
+			line, _ = n.Int64()
+		}
+		entry.SourceLocation = &SourceLocation{
+			File:     popString(loc, "file"),
+			Line:     line,
+			Function: popString(loc, "function"),
+		}
+	}
+	return entry, nil
+}
+
+// httpRequestFromPayload は JSON の httpRequest を HTTPRequest に変換します。
+func httpRequestFromPayload(m map[string]any) (*HTTPRequest, error) {
+	req := &HTTPRequest{
+		RequestMethod: popString(m, "requestMethod"),
+		RequestURL:    popString(m, "requestUrl"),
+		RequestSize:   0,
+		Status:        0,
+		ResponseSize:  0,
+		UserAgent:     popString(m, "userAgent"),
+		RemoteIP:      popString(m, "remoteIp"),
+		ServerIP:      popString(m, "serverIp"),
+		Referer:       popString(m, "referer"),
+		Latency:       0,
+		Protocol:      popString(m, "protocol"),
+	}
+
+	var err error
+	if s := popString(m, "requestSize"); s != "" {
+		if req.RequestSize, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return nil, fmt.Errorf("failed to parse httpRequest.requestSize: %w", err)
+		}
+	}
+	if s := popString(m, "responseSize"); s != "" {
+		if req.ResponseSize, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return nil, fmt.Errorf("failed to parse httpRequest.responseSize: %w", err)
+		}
+	}
+	if n, ok := m["status"].(json.Number); ok {
+		status, err := n.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse httpRequest.status: %w", err)
+		}
+		req.Status = int(status)
+	}
+	if s := popString(m, "latency"); s != "" {
+		if req.Latency, err = time.ParseDuration(s); err != nil {
+			return nil, fmt.Errorf("failed to parse httpRequest.latency: %w", err)
+		}
+	}
+	return req, nil
+}
+
+// popString は m から文字列の値を取り出し、キーを削除します。
+func popString(m map[string]any, key string) string {
+	s, ok := m[key].(string)
+	if !ok {
+		return ""
+	}
+	delete(m, key)
+	return s
+}
+
+// popObject は m からオブジェクトの値を取り出し、キーを削除します。
+func popObject(m map[string]any, key string) map[string]any {
+	obj, ok := m[key].(map[string]any)
+	if !ok {
+		return nil
+	}
+	delete(m, key)
+	return obj
+}
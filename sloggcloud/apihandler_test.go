@@ -0,0 +1,224 @@
+package sloggcloud_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeEntryLogger は受け取った Entry を記録する EntryLogger です。
+type fakeEntryLogger struct {
+	entries []sloggcloud.Entry
+	flushed int
+}
+
+func (l *fakeEntryLogger) Log(entry sloggcloud.Entry) {
+	l.entries = append(l.entries, entry)
+}
+
+func (l *fakeEntryLogger) Flush() error {
+	l.flushed++
+	return nil
+}
+
+func TestNewAPIHandler(t *testing.T) {
+	recordTime := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	tests := []struct {
+		name       string
+		level      slog.Level
+		attrs      []slog.Attr
+		opts       []sloggcloud.Option
+		setupCtx   func() context.Context
+		want       sloggcloud.Entry
+		wantSource bool
+	}{
+		{
+			name:  "severityとペイロードを変換",
+			level: slog.LevelWarn,
+			attrs: []slog.Attr{
+				slog.String("user", "alice"),
+				slog.Int64("id", 9007199254740993),
+			},
+			opts: []sloggcloud.Option{sloggcloud.WithSource(false)},
+			setupCtx: func() context.Context {
+				return context.Background()
+			},
+			want: sloggcloud.Entry{
+				Timestamp: recordTime,
+				Severity:  "WARNING",
+				Payload: map[string]any{
+					"message": "api message",
+					"user":    "alice",
+					"id":      json.Number("9007199254740993"),
+				},
+			},
+		},
+		{
+			name:  "ラベル・トレース・insertIdを変換",
+			level: slog.LevelInfo,
+			attrs: []slog.Attr{},
+			opts: []sloggcloud.Option{
+				sloggcloud.WithProjectID("test-project"),
+				sloggcloud.WithLabels(map[string]string{"env": "prod"}),
+				sloggcloud.WithInsertIDFunc(func(context.Context, slog.Record) string {
+					return "insert-id"
+				}),
+				sloggcloud.WithSource(false),
+			},
+			setupCtx: func() context.Context {
+				traceID, _ := trace.TraceIDFromHex("01020304050607080102030405060708")
+				spanID, _ := trace.SpanIDFromHex("0102030405060708")
+				return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID:    traceID,
+					SpanID:     spanID,
+					TraceFlags: trace.FlagsSampled,
+				}))
+			},
+			want: sloggcloud.Entry{
+				Timestamp:    recordTime,
+				Severity:     "INFO",
+				Payload:      map[string]any{"message": "api message"},
+				Labels:       map[string]string{"env": "prod"},
+				InsertID:     "insert-id",
+				Trace:        "projects/test-project/traces/01020304050607080102030405060708",
+				SpanID:       "0102030405060708",
+				TraceSampled: true,
+			},
+		},
+		{
+			name:  "httpRequestとoperationを変換",
+			level: slog.LevelInfo,
+			attrs: []slog.Attr{
+				sloggcloud.HTTPRequestAttr(&sloggcloud.HTTPRequest{
+					RequestMethod: "GET",
+					RequestURL:    "/users",
+					Status:        200,
+					ResponseSize:  1024,
+					Latency:       123 * time.Millisecond,
+				}),
+			},
+			opts: []sloggcloud.Option{sloggcloud.WithSource(false)},
+			setupCtx: func() context.Context {
+				return sloggcloud.ContextWithOperation(context.Background(), sloggcloud.Operation{
+					ID:       "op-1",
+					Producer: "test",
+					First:    true,
+				})
+			},
+			want: sloggcloud.Entry{
+				Timestamp: recordTime,
+				Severity:  "INFO",
+				Payload:   map[string]any{"message": "api message"},
+				HTTPRequest: &sloggcloud.HTTPRequest{
+					RequestMethod: "GET",
+					RequestURL:    "/users",
+					Status:        200,
+					ResponseSize:  1024,
+					Latency:       123 * time.Millisecond,
+				},
+				Operation: &sloggcloud.Operation{
+					ID:       "op-1",
+					Producer: "test",
+					First:    true,
+				},
+			},
+		},
+		{
+			name:  "ソースコードの位置情報を変換",
+			level: slog.LevelInfo,
+			attrs: []slog.Attr{},
+			opts:  []sloggcloud.Option{},
+			setupCtx: func() context.Context {
+				return context.Background()
+			},
+			want: sloggcloud.Entry{
+				Timestamp: recordTime,
+				Severity:  "INFO",
+				Payload:   map[string]any{"message": "api message"},
+			},
+			wantSource: true,
+		},
+		{
+			name:  "WithSourceKeyで指定したキーの位置情報を変換",
+			level: slog.LevelInfo,
+			attrs: []slog.Attr{},
+			opts:  []sloggcloud.Option{sloggcloud.WithSourceKey("caller")},
+			setupCtx: func() context.Context {
+				return context.Background()
+			},
+			want: sloggcloud.Entry{
+				Timestamp: recordTime,
+				Severity:  "INFO",
+				Payload:   map[string]any{"message": "api message"},
+			},
+			wantSource: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &fakeEntryLogger{}
+			handler := sloggcloud.NewAPIHandler(logger, tt.opts...)
+
+			var pc uintptr
+			if tt.wantSource {
+				pc = callerPC()
+			}
+			r := slog.NewRecord(recordTime, tt.level, "api message", pc)
+			r.AddAttrs(tt.attrs...)
+			if err := handler.Handle(tt.setupCtx(), r); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			if len(logger.entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(logger.entries))
+			}
+			got := logger.entries[0]
+
+			if tt.wantSource {
+				if got.SourceLocation == nil || !strings.HasSuffix(got.SourceLocation.File, "apihandler_test.go") ||
+					got.SourceLocation.Line == 0 || !strings.Contains(got.SourceLocation.Function, "callerPC") {
+					t.Errorf("unexpected source location: %+v", got.SourceLocation)
+				}
+				got.SourceLocation = nil
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("entry mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewAPIHandler_Close(t *testing.T) {
+	logger := &fakeEntryLogger{}
+	handler := sloggcloud.NewAPIHandler(logger, sloggcloud.WithSource(false))
+
+	slog.New(handler).Info("message before close")
+	if err := handler.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if len(logger.entries) != 1 {
+		t.Errorf("got %d entries, want 1", len(logger.entries))
+	}
+	if logger.flushed != 1 {
+		t.Errorf("Flush() called %d times, want 1", logger.flushed)
+	}
+}
+
+// callerPC は呼び出し箇所のプログラムカウンタを返します。
+func callerPC() uintptr {
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	return pcs[0]
+}
@@ -0,0 +1,168 @@
+package sloggcloud
+
+import (
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// redactedValue は WithRedactKeys で指定したキーの値を置き換える文字列です。
+	redactedValue = "[REDACTED]"
+	// scrubbedValue は WithValueScrubber で指定したパターンに一致した部分を置き換える文字列です。
+	scrubbedValue = "[SCRUBBED]"
+)
+
+// resolveAttr は属性の値が slog.LogValuer の場合に、グループの中も含めて値を解決します。
+// グループに包み直した後でも LogValuer の結果が確実に出力されるよう、Handle で属性を扱う前に解決しておきます。
+func resolveAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		return a
+	}
+
+	group := a.Value.Group()
+	resolved := make([]slog.Attr, 0, len(group))
+	for _, ga := range group {
+		ga = resolveAttr(ga)
+		if isInlineGroup(ga) {
+			resolved = append(resolved, ga.Value.Group()...)
+			continue
+		}
+		resolved = append(resolved, ga)
+	}
+	a.Value = slog.GroupValue(resolved...)
+	return a
+}
+
+// isInlineGroup は a が名前のないグループかどうかを返します。
+// slog の規約では名前のないグループの属性は親に展開されるため、キーの判定や入れ子の深さの計算の前に展開しておきます。
+func isInlineGroup(a slog.Attr) bool {
+	return a.Key == "" && a.Value.Kind() == slog.KindGroup
+}
+
+// redactAttr はキーが redactKeys に含まれる属性の値を、グループの中も含めて redactedValue に置き換えます。
+// redactKeys のキーは小文字で保持されており、大文字と小文字を区別せずに比較します。
+func redactAttr(a slog.Attr, redactKeys map[string]struct{}) slog.Attr {
+	if _, ok := redactKeys[strings.ToLower(a.Key)]; ok {
+		return slog.String(a.Key, redactedValue)
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return a
+	}
+
+	group := a.Value.Group()
+	redacted := make([]slog.Attr, len(group))
+	for i, ga := range group {
+		redacted[i] = redactAttr(ga, redactKeys)
+	}
+	a.Value = slog.GroupValue(redacted...)
+	return a
+}
+
+// scrubAttr は文字列の属性の値のうち patterns に一致した部分を、グループの中も含めて scrubbedValue に置き換えます。
+func scrubAttr(a slog.Attr, patterns []*regexp.Regexp) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(scrubString(a.Value.String(), patterns))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		scrubbed := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			scrubbed[i] = scrubAttr(ga, patterns)
+		}
+		a.Value = slog.GroupValue(scrubbed...)
+	case slog.KindAny, slog.KindBool, slog.KindDuration, slog.KindFloat64, slog.KindInt64, slog.KindLogValuer, slog.KindTime, slog.KindUint64:
+	}
+	return a
+}
+
+// scrubString は s のうち patterns のいずれかに一致した部分を scrubbedValue に置き換えます。
+func scrubString(s string, patterns []*regexp.Regexp) string {
+	for _, p := range patterns {
+		s = p.ReplaceAllLiteralString(s, scrubbedValue)
+	}
+	return s
+}
+
+// maxSafeInteger は float64 で誤差なく表せる最大の整数 (2^53 - 1) です。
+const maxSafeInteger = 1<<53 - 1
+
+// largeIntAttr は float64 で誤差なく表せない整数の属性を、グループの中も含めて 10 進数の文字列に置き換えます。
+// slog.JSONHandler は整数を正確な JSON の数値として出力しますが、Cloud Logging の jsonPayload や JavaScript など
+// 数値を float64 として扱う読み手では値が変わってしまうため、文字列として出力します。
+func largeIntAttr(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindInt64:
+		if v := a.Value.Int64(); v > maxSafeInteger || v < -maxSafeInteger {
+			return slog.String(a.Key, strconv.FormatInt(v, 10))
+		}
+	case slog.KindUint64:
+		if v := a.Value.Uint64(); v > maxSafeInteger {
+			return slog.String(a.Key, strconv.FormatUint(v, 10))
+		}
+	case slog.KindGroup:
+		group := a.Value.Group()
+		converted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			converted[i] = largeIntAttr(ga)
+		}
+		a.Value = slog.GroupValue(converted...)
+	case slog.KindAny, slog.KindBool, slog.KindDuration, slog.KindFloat64, slog.KindString, slog.KindTime, slog.KindLogValuer:
+	}
+	return a
+}
+
+// errorAttr は値が error の属性を、グループの中も含めて Error() の文字列に置き換えます。
+// error の多くは公開フィールドを持たず JSON にすると {} になってしまうため、メッセージを確実に出力します。
+// unwrap が true の場合は、message と原因となったエラーのメッセージの配列 causes を持つオブジェクトに置き換えます。
+func errorAttr(a slog.Attr, unwrap bool) slog.Attr {
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		converted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			converted[i] = errorAttr(ga, unwrap)
+		}
+		a.Value = slog.GroupValue(converted...)
+		return a
+	}
+
+	if a.Value.Kind() != slog.KindAny {
+		return a
+	}
+	err, ok := a.Value.Any().(error)
+	if !ok {
+		return a
+	}
+	if !unwrap {
+		return slog.String(a.Key, err.Error())
+	}
+
+	attrs := []slog.Attr{slog.String("message", err.Error())}
+	if causes := errorCauses(err); len(causes) > 0 {
+		attrs = append(attrs, slog.Any("causes", causes))
+	}
+	return slog.Attr{Key: a.Key, Value: slog.GroupValue(attrs...)}
+}
+
+// errorCauses は err がラップしているエラーのメッセージを、外側から順に深さ優先で返します。
+// errors.Join などで複数のエラーをラップしている場合は、すべてのエラーを辿ります。
+func errorCauses(err error) []string {
+	var wrapped []error
+	switch e := err.(type) { //nolint:errorlint // ラップしているエラーを辿るため、err 自身の型のみを判定する
+	case interface{ Unwrap() error }:
+		if inner := e.Unwrap(); inner != nil {
+			wrapped = []error{inner}
+		}
+	case interface{ Unwrap() []error }:
+		wrapped = e.Unwrap()
+	}
+
+	causes := make([]string, 0, len(wrapped))
+	for _, inner := range wrapped {
+		causes = append(causes, inner.Error())
+		causes = append(causes, errorCauses(inner)...)
+	}
+	return causes
+}
@@ -0,0 +1,459 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithRedactKeys(t *testing.T) {
+	tests := []struct {
+		name        string
+		keys        []string
+		setupGroups func(h *sloggcloud.Handler) slog.Handler
+		args        []slog.Attr
+		want        map[string]interface{}
+	}{
+		{
+			name: "トップレベルの属性を秘匿",
+			keys: []string{"password", "token"},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h
+			},
+			args: []slog.Attr{
+				slog.String("user", "alice"),
+				slog.String("password", "p@ssw0rd"),
+				slog.String("token", "secret-token"),
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message with secrets",
+				"user":     "alice",
+				"password": "[REDACTED]",
+				"token":    "[REDACTED]",
+			},
+		},
+		{
+			name: "大文字と小文字を区別せずに秘匿",
+			keys: []string{"authorization"},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h
+			},
+			args: []slog.Attr{
+				slog.String("Authorization", "Bearer xxx"),
+			},
+			want: map[string]interface{}{
+				"severity":      "INFO",
+				"message":       "message with secrets",
+				"Authorization": "[REDACTED]",
+			},
+		},
+		{
+			name: "グループの中の属性を秘匿",
+			keys: []string{"password"},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h.WithGroup("request")
+			},
+			args: []slog.Attr{
+				slog.Group("user",
+					slog.String("name", "alice"),
+					slog.String("password", "p@ssw0rd"),
+				),
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message with secrets",
+				"request": map[string]interface{}{
+					"user": map[string]interface{}{
+						"name":     "alice",
+						"password": "[REDACTED]",
+					},
+				},
+			},
+		},
+		{
+			name: "グループのキーが一致した場合はグループ全体を秘匿",
+			keys: []string{"credentials"},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h
+			},
+			args: []slog.Attr{
+				slog.Group("credentials",
+					slog.String("user", "alice"),
+					slog.String("password", "p@ssw0rd"),
+				),
+			},
+			want: map[string]interface{}{
+				"severity":    "INFO",
+				"message":     "message with secrets",
+				"credentials": "[REDACTED]",
+			},
+		},
+		{
+			name: "一致しないキーはそのまま出力",
+			keys: []string{"password"},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h
+			},
+			args: []slog.Attr{
+				slog.String("passwordHint", "favorite color"),
+				slog.Int("count", 1),
+			},
+			want: map[string]interface{}{
+				"severity":     "INFO",
+				"message":      "message with secrets",
+				"passwordHint": "favorite color",
+				"count":        float64(1),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := sloggcloud.New(&buf,
+				sloggcloud.WithRedactKeys(tt.keys...),
+				sloggcloud.WithSource(false),
+			)
+			logger := slog.New(tt.setupGroups(handler))
+
+			logger.LogAttrs(t.Context(), slog.LevelInfo, "message with secrets", tt.args...)
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// opaqueError は公開フィールドを持たず、JSON にすると {} になる error です。
+type opaqueError struct {
+	msg string
+}
+
+func (e *opaqueError) Error() string {
+	return e.msg
+}
+
+func TestWithErrorUnwrap(t *testing.T) {
+	base := &opaqueError{msg: "connection refused"}
+	wrapped := fmt.Errorf("query users: %w", fmt.Errorf("dial db: %w", base))
+	joined := errors.Join(errors.New("first"), fmt.Errorf("second: %w", base))
+
+	tests := []struct {
+		name string
+		opts []sloggcloud.Option
+		err  error
+		want interface{}
+	}{
+		{
+			name: "デフォルトではErrorの文字列を出力",
+			opts: []sloggcloud.Option{},
+			err:  wrapped,
+			want: "query users: dial db: connection refused",
+		},
+		{
+			name: "公開フィールドを持たないerrorもErrorの文字列を出力",
+			opts: []sloggcloud.Option{},
+			err:  base,
+			want: "connection refused",
+		},
+		{
+			name: "ラップしたエラーをcausesとして出力",
+			opts: []sloggcloud.Option{sloggcloud.WithErrorUnwrap(true)},
+			err:  wrapped,
+			want: map[string]interface{}{
+				"message": "query users: dial db: connection refused",
+				"causes":  []interface{}{"dial db: connection refused", "connection refused"},
+			},
+		},
+		{
+			name: "ラップしていないエラーはmessageのみ出力",
+			opts: []sloggcloud.Option{sloggcloud.WithErrorUnwrap(true)},
+			err:  base,
+			want: map[string]interface{}{
+				"message": "connection refused",
+			},
+		},
+		{
+			name: "errors.Joinしたエラーをすべて辿る",
+			opts: []sloggcloud.Option{sloggcloud.WithErrorUnwrap(true)},
+			err:  joined,
+			want: map[string]interface{}{
+				"message": "first\nsecond: connection refused",
+				"causes":  []interface{}{"first", "second: connection refused", "connection refused"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...))
+
+			logger.Error("failed", slog.Any("err", tt.err), slog.Group("nested", slog.Any("err", tt.err)))
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, got["err"]); diff != "" {
+				t.Errorf("err mismatch (-want +got):\n%s", diff)
+			}
+			nested, _ := got["nested"].(map[string]interface{})
+			if diff := cmp.Diff(tt.want, nested["err"]); diff != "" {
+				t.Errorf("nested err mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithLargeIntAsString(t *testing.T) {
+	args := []any{
+		slog.Int64("id", 9007199254740993),
+		slog.Int64("negative", -9007199254740993),
+		slog.Uint64("max", math.MaxUint64),
+		slog.Int("small", 500),
+		slog.Group("req", slog.Int64("id", 9007199254740993)),
+	}
+
+	tests := []struct {
+		name string
+		opts []sloggcloud.Option
+		want map[string]interface{}
+	}{
+		{
+			name: "デフォルトでは正確なJSONの数値として出力",
+			opts: nil,
+			want: map[string]interface{}{
+				"id":       json.Number("9007199254740993"),
+				"negative": json.Number("-9007199254740993"),
+				"max":      json.Number("18446744073709551615"),
+				"small":    json.Number("500"),
+				"req":      map[string]interface{}{"id": json.Number("9007199254740993")},
+			},
+		},
+		{
+			name: "有効な場合は2^53以上の整数を文字列で出力",
+			opts: []sloggcloud.Option{sloggcloud.WithLargeIntAsString(true)},
+			want: map[string]interface{}{
+				"id":       "9007199254740993",
+				"negative": "-9007199254740993",
+				"max":      "18446744073709551615",
+				"small":    json.Number("500"),
+				"req":      map[string]interface{}{"id": "9007199254740993"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...))
+
+			logger.Info("large int", args...)
+
+			// float64 に変換せずに元の表現のまま比較する
+			dec := json.NewDecoder(&buf)
+			dec.UseNumber()
+			var got map[string]interface{}
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+			delete(got, "severity")
+			delete(got, "message")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithFieldAllowlist(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []sloggcloud.Option
+		logger func(*slog.Logger) *slog.Logger
+		args   []any
+		want   map[string]interface{}
+	}{
+		{
+			name:   "許可したキーの属性のみ出力",
+			opts:   []sloggcloud.Option{sloggcloud.WithFieldAllowlist("user_id")},
+			logger: func(l *slog.Logger) *slog.Logger { return l },
+			args:   []any{"user_id", "u-1", "email", "alice@example.com", "query", "SELECT 1"},
+			want:   map[string]interface{}{"user_id": "u-1"},
+		},
+		{
+			name:   "WithAttrsとslog.Groupの属性も判定",
+			opts:   []sloggcloud.Option{sloggcloud.WithFieldAllowlist("user_id", "req")},
+			logger: func(l *slog.Logger) *slog.Logger { return l.With("email", "alice@example.com", "user_id", "u-1") },
+			args:   []any{slog.Group("req", slog.String("path", "/")), slog.Group("db", slog.String("query", "SELECT 1"))},
+			want:   map[string]interface{}{"user_id": "u-1", "req": map[string]interface{}{"path": "/"}},
+		},
+		{
+			name:   "WithGroupの場合はグループの中の属性のキーで判定",
+			opts:   []sloggcloud.Option{sloggcloud.WithFieldAllowlist("user_id")},
+			logger: func(l *slog.Logger) *slog.Logger { return l.WithGroup("app") },
+			args:   []any{"user_id", "u-1", "email", "alice@example.com"},
+			want:   map[string]interface{}{"app": map[string]interface{}{"user_id": "u-1"}},
+		},
+		{
+			name:   "ハンドラが出力するフィールドは常に出力",
+			opts:   []sloggcloud.Option{sloggcloud.WithFieldAllowlist("user_id"), sloggcloud.WithLabels(map[string]string{"env": "prod"})},
+			logger: func(l *slog.Logger) *slog.Logger { return l },
+			args: []any{
+				"email", "alice@example.com",
+				sloggcloud.HTTPRequestAttr(&sloggcloud.HTTPRequest{RequestMethod: "GET", Status: 200}),
+			},
+			want: map[string]interface{}{
+				"logging.googleapis.com/labels": map[string]interface{}{"env": "prod"},
+				"httpRequest":                   map[string]interface{}{"requestMethod": "GET", "status": float64(200)},
+			},
+		},
+		{
+			name:   "指定しない場合は全ての属性を出力",
+			opts:   nil,
+			logger: func(l *slog.Logger) *slog.Logger { return l },
+			args:   []any{"user_id", "u-1", "email", "alice@example.com"},
+			want:   map[string]interface{}{"user_id": "u-1", "email": "alice@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := tt.logger(slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...)))
+
+			logger.Info("hello", tt.args...)
+
+			want := map[string]interface{}{"severity": "INFO", "message": "hello"}
+			for k, v := range tt.want {
+				want[k] = v
+			}
+			if diff := cmp.Diff([]map[string]interface{}{want}, decodeLines(t, &buf)); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithValueScrubber(t *testing.T) {
+	email := regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	token := regexp.MustCompile(`sk-[A-Za-z0-9]+`)
+
+	tests := []struct {
+		name    string
+		opts    []sloggcloud.Option
+		message string
+		args    []any
+		want    map[string]interface{}
+	}{
+		{
+			name:    "属性のメールアドレスを前後の文字列を残して置き換え",
+			opts:    []sloggcloud.Option{sloggcloud.WithValueScrubber(email)},
+			message: "hello",
+			args:    []any{"note", "contact alice@example.com for details", "user_id", "u-1"},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "hello",
+				"note":     "contact [SCRUBBED] for details",
+				"user_id":  "u-1",
+			},
+		},
+		{
+			name:    "メッセージとグループの中の属性も置き換え",
+			opts:    []sloggcloud.Option{sloggcloud.WithValueScrubber(email)},
+			message: "signup from bob@example.com",
+			args:    []any{slog.Group("user", "email", "bob@example.com", "age", 20)},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "signup from [SCRUBBED]",
+				"user":     map[string]interface{}{"email": "[SCRUBBED]", "age": float64(20)},
+			},
+		},
+		{
+			name:    "複数のパターンに一致した部分をすべて置き換え",
+			opts:    []sloggcloud.Option{sloggcloud.WithValueScrubber(email, token)},
+			message: "hello",
+			args:    []any{"note", "a@example.com uses sk-abc123 and b@example.com"},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "hello",
+				"note":     "[SCRUBBED] uses [SCRUBBED] and [SCRUBBED]",
+			},
+		},
+		{
+			name:    "指定しない場合は置き換えない",
+			opts:    nil,
+			message: "signup from bob@example.com",
+			args:    []any{"email", "bob@example.com"},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "signup from bob@example.com",
+				"email":    "bob@example.com",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...))
+
+			logger.Info(tt.message, tt.args...)
+
+			if diff := cmp.Diff([]map[string]interface{}{tt.want}, decodeLines(t, &buf)); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("トレース情報などハンドラが出力するフィールドは置き換えない", func(t *testing.T) {
+		hexToken := regexp.MustCompile(`[0-9a-f]{16,}`)
+		ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+			SpanID:     trace.SpanID{0, 0, 0, 0, 0, 0, 0, 0x01},
+			TraceFlags: trace.FlagsSampled,
+		}))
+
+		var buf bytes.Buffer
+		logger := slog.New(sloggcloud.New(&buf,
+			sloggcloud.WithProjectID("test-project"),
+			sloggcloud.WithSource(false),
+			sloggcloud.WithValueScrubber(hexToken),
+		))
+		logger.InfoContext(ctx, "token deadbeefdeadbeef leaked", "api_key", "0123456789abcdef0123")
+
+		want := []map[string]interface{}{{
+			"severity":                             "INFO",
+			"message":                              "token [SCRUBBED] leaked",
+			"api_key":                              "[SCRUBBED]",
+			"logging.googleapis.com/trace":         "projects/test-project/traces/0102030405060708090a0b0c0d0e0f10",
+			"logging.googleapis.com/spanId":        "0000000000000001",
+			"logging.googleapis.com/trace_sampled": true,
+		}}
+		if diff := cmp.Diff(want, decodeLines(t, &buf)); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package sloggcloud
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"log/slog"
+)
+
+// BinaryFormat は []byte の属性の出力形式です。
+type BinaryFormat string
+
+const (
+	// BinaryFormatBase64 は標準の base64 でエンコードした文字列で出力します。encoding/json の []byte の形式と同じです。
+	BinaryFormatBase64 BinaryFormat = "base64"
+	// BinaryFormatHex は 16 進数でエンコードした文字列で出力します。
+	BinaryFormatHex BinaryFormat = "hex"
+	// BinaryFormatString はバイト列をそのまま文字列として出力します。UTF-8 として不正なバイトは置換文字になります。
+	BinaryFormatString BinaryFormat = "string"
+)
+
+// binaryAttr は []byte の属性を、グループの中も含めて format に従った文字列に変換します。
+// 出力形式によって base64 の文字列や数値の配列になるのを避け、どの出力先でも同じ表現にそろえます。
+func binaryAttr(a slog.Attr, format BinaryFormat) slog.Attr {
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		converted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			converted[i] = binaryAttr(ga, format)
+		}
+		a.Value = slog.GroupValue(converted...)
+		return a
+	}
+
+	if a.Value.Kind() != slog.KindAny {
+		return a
+	}
+	// json.RawMessage などの名前付きの型は利用者が意図した表現を持つため変換しない
+	b, ok := a.Value.Any().([]byte)
+	if !ok {
+		return a
+	}
+	switch format {
+	case BinaryFormatHex:
+		return slog.String(a.Key, hex.EncodeToString(b))
+	case BinaryFormatString:
+		return slog.String(a.Key, string(b))
+	case BinaryFormatBase64:
+	}
+	return slog.String(a.Key, base64.StdEncoding.EncodeToString(b))
+}
@@ -0,0 +1,109 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithBinaryFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []sloggcloud.Option
+		want map[string]interface{}
+	}{
+		{
+			name: "デフォルトはbase64",
+			opts: []sloggcloud.Option{},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "binary",
+				"data":     "aGVsbG8=",
+				"raw":      map[string]interface{}{"id": float64(1)},
+				"req": map[string]interface{}{
+					"body": "aGVsbG8=",
+				},
+			},
+		},
+		{
+			name: "base64",
+			opts: []sloggcloud.Option{sloggcloud.WithBinaryFormat(sloggcloud.BinaryFormatBase64)},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "binary",
+				"data":     "aGVsbG8=",
+				"raw":      map[string]interface{}{"id": float64(1)},
+				"req": map[string]interface{}{
+					"body": "aGVsbG8=",
+				},
+			},
+		},
+		{
+			name: "hex",
+			opts: []sloggcloud.Option{sloggcloud.WithBinaryFormat(sloggcloud.BinaryFormatHex)},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "binary",
+				"data":     "68656c6c6f",
+				"raw":      map[string]interface{}{"id": float64(1)},
+				"req": map[string]interface{}{
+					"body": "68656c6c6f",
+				},
+			},
+		},
+		{
+			name: "string",
+			opts: []sloggcloud.Option{sloggcloud.WithBinaryFormat(sloggcloud.BinaryFormatString)},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "binary",
+				"data":     "hello",
+				"raw":      map[string]interface{}{"id": float64(1)},
+				"req": map[string]interface{}{
+					"body": "hello",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...))
+
+			logger.Info("binary",
+				slog.Any("data", []byte("hello")),
+				// json.RawMessage は []byte として扱わずにそのまま出力する
+				slog.Any("raw", json.RawMessage(`{"id":1}`)),
+				slog.Group("req", slog.Any("body", []byte("hello"))),
+			)
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithBinaryFormat_console(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(sloggcloud.New(&buf, sloggcloud.WithConsole(true), sloggcloud.WithSource(false)))
+
+	logger.Info("binary", slog.Any("data", []byte("hello")))
+
+	// コンソール出力でも数値の配列ではなく base64 の文字列で出力する
+	if got := buf.String(); !strings.Contains(got, `"aGVsbG8="`) {
+		t.Errorf("console output does not contain base64 data: %q", got)
+	}
+}
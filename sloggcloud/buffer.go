@@ -0,0 +1,121 @@
+package sloggcloud
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// bufferedWriter は書き込みをまとめてから書き込み先に渡す io.Writer です。
+// バッファが一杯になった時と flushInterval ごとに、バックグラウンドの goroutine で書き出します。
+// 1 行のログが複数回の書き込みに分割されないように、収まらない行はバッファを書き出してから追加します。
+type bufferedWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	buf  []byte
+	size int
+
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// newBufferedWriter は size バイトのバッファを持つ bufferedWriter を作成します。
+// flushInterval が正の場合は、その間隔で書き出す goroutine を起動します。
+func newBufferedWriter(w io.Writer, size int, flushInterval time.Duration) *bufferedWriter {
+	bw := &bufferedWriter{
+		mu:        sync.Mutex{},
+		w:         w,
+		buf:       make([]byte, 0, size),
+		size:      size,
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+		closeOnce: sync.Once{},
+	}
+	if flushInterval > 0 {
+		go bw.flushLoop(flushInterval)
+	} else {
+		close(bw.stopped)
+	}
+	return bw
+}
+
+// flushLoop は Close が呼ばれるまで flushInterval ごとにバッファを書き出します。
+func (w *bufferedWriter) flushLoop(flushInterval time.Duration) {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			// 定期的な書き出しの失敗は返す先がないため、次の書き込みか Flush で改めて書き出す
+			_ = w.flushBuffer()
+			w.mu.Unlock()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Write は p をバッファに追加します。書き込み先への書き込みに失敗した場合のみエラーを返します。
+func (w *bufferedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf)+len(p) > w.size {
+		if err := w.flushBuffer(); err != nil {
+			return 0, err
+		}
+	}
+	// バッファより大きい行はバッファを経由せずにそのまま書き込む
+	if len(p) > w.size {
+		if _, err := w.w.Write(p); err != nil {
+			return 0, fmt.Errorf("failed to write buffered log: %w", err)
+		}
+		return len(p), nil
+	}
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+// Flush はバッファを書き出し、書き込み先が Flush() error を実装している場合はそれも呼び出します。
+func (w *bufferedWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.flushBuffer(); err != nil {
+		return err
+	}
+	if f, ok := w.w.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			return fmt.Errorf("failed to flush writer: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close は書き出しの goroutine を止めてバッファを書き出します。
+// 書き込み先は Handler が作成した gzipWriter であっても閉じず、閉じるのは Handler.Close が行います。
+func (w *bufferedWriter) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+	<-w.stopped
+
+	return w.Flush()
+}
+
+// flushBuffer はバッファの内容を書き込み先に書き出します。呼び出し側で mu をロックしてください。
+func (w *bufferedWriter) flushBuffer() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	if _, err := w.w.Write(w.buf); err != nil {
+		return fmt.Errorf("failed to write buffered log: %w", err)
+	}
+	w.buf = w.buf[:0]
+	return nil
+}
@@ -0,0 +1,150 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+// syncBuffer は書き出しの goroutine と並行に読み出せる bytes.Buffer です。
+type syncBuffer struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes int
+	closed bool
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.writes++
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWithBuffer(t *testing.T) {
+	t.Run("flushIntervalの経過後に書き出す", func(t *testing.T) {
+		var buf syncBuffer
+		handler := sloggcloud.New(&buf, sloggcloud.WithBuffer(4096, 10*time.Millisecond))
+		t.Cleanup(func() { _ = handler.Close() })
+
+		slog.New(handler).Info("buffered")
+		if got := buf.String(); got != "" {
+			t.Errorf("log was written before flush interval: %s", got)
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for !strings.Contains(buf.String(), `"message":"buffered"`) {
+			if time.Now().After(deadline) {
+				t.Fatal("log was not written after flush interval")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+
+	t.Run("Closeで書き出し、呼び出し側の書き込み先は閉じない", func(t *testing.T) {
+		var buf syncBuffer
+		handler := sloggcloud.New(&buf, sloggcloud.WithBuffer(4096, time.Hour))
+
+		logger := slog.New(handler)
+		logger.Info("first")
+		logger.Info("second")
+		if got := buf.String(); got != "" {
+			t.Errorf("log was written before Close: %s", got)
+		}
+
+		if err := handler.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if got := strings.Count(buf.String(), "\n"); got != 2 {
+			t.Errorf("written lines = %d, want 2: %s", got, buf.String())
+		}
+		if buf.writes != 1 {
+			t.Errorf("writes = %d, want 1", buf.writes)
+		}
+		if buf.closed {
+			t.Error("caller's writer was closed")
+		}
+	})
+
+	t.Run("バッファが一杯になると行を分割せずに書き出す", func(t *testing.T) {
+		var buf syncBuffer
+		handler := sloggcloud.New(&buf, sloggcloud.WithBuffer(200, time.Hour), sloggcloud.WithSource(false))
+
+		logger := slog.New(handler)
+		for range 10 {
+			logger.Info("message that fills the buffer")
+		}
+		if err := handler.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+
+		if got := strings.Count(buf.String(), "\n"); got != 10 {
+			t.Errorf("written lines = %d, want 10", got)
+		}
+		if buf.writes <= 1 {
+			t.Errorf("writes = %d, want more than 1", buf.writes)
+		}
+	})
+
+	t.Run("バッファより大きい行はそのまま書き込む", func(t *testing.T) {
+		var buf syncBuffer
+		handler := sloggcloud.New(&buf, sloggcloud.WithBuffer(16, time.Hour))
+
+		slog.New(handler).Info("message larger than the buffer")
+		if !strings.Contains(buf.String(), "message larger than the buffer") {
+			t.Errorf("large log was not written: %s", buf.String())
+		}
+	})
+}
+
+func BenchmarkWithBuffer(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		opts []sloggcloud.Option
+	}{
+		{name: "unbuffered", opts: []sloggcloud.Option{}},
+		{name: "buffered", opts: []sloggcloud.Option{sloggcloud.WithBuffer(64*1024, time.Second)}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			f, err := os.Create(filepath.Join(b.TempDir(), "log"))
+			if err != nil {
+				b.Fatalf("failed to create file: %v", err)
+			}
+			handler := sloggcloud.New(f, append(bm.opts, sloggcloud.WithSource(false))...)
+			logger := slog.New(handler)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				logger.Info("benchmark message", slog.String("key", "value"), slog.Int("code", 200))
+			}
+			b.StopTimer()
+
+			if err := handler.Close(); err != nil {
+				b.Fatalf("Close() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Package cloudlogging は sloggcloud のログを cloud.google.com/go/logging のクライアントで Cloud Logging API に書き込みます。
+// sloggcloud 本体が Cloud Logging のクライアントライブラリとその依存関係を持たないように、別のモジュールに分けています。
+package cloudlogging
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"cloud.google.com/go/logging"
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+// NewAPIHandler は client の logID のロガーを通して Cloud Logging API にログを書き込む Handler を作成します。
+// エントリは *logging.Logger が非同期にバッファリングし、まとめて送信します。
+// プロセスの終了前に Handler の Close を呼び出すと、Flush でバッファリングされたエントリを送信します。
+// client は呼び出し側が所有しているため Close では閉じません。Handler の Close の後に client.Close を呼び出してください。
+func NewAPIHandler(client *logging.Client, logID string, opts ...sloggcloud.Option) *sloggcloud.Handler {
+	return sloggcloud.NewAPIHandler(&loggerAdapter{logger: client.Logger(logID)}, opts...)
+}
+
+// loggerAdapter は *logging.Logger を sloggcloud.EntryLogger として使えるようにします。
+type loggerAdapter struct {
+	logger *logging.Logger
+}
+
+func (a *loggerAdapter) Log(e sloggcloud.Entry) {
+	a.logger.Log(toLoggingEntry(e))
+}
+
+func (a *loggerAdapter) Flush() error {
+	if err := a.logger.Flush(); err != nil {
+		return fmt.Errorf("failed to flush cloud logging entries: %w", err)
+	}
+	return nil
+}
+
+// toLoggingEntry は sloggcloud.Entry を logging.Entry に変換します。
+func toLoggingEntry(e sloggcloud.Entry) logging.Entry {
+	//nolint:exhaustruct // LogName や Resource はロガーの設定をそのまま使う
+	entry := logging.Entry{
+		Timestamp:    e.Timestamp,
+		Severity:     logging.ParseSeverity(e.Severity),
+		Payload:      e.Payload,
+		Labels:       e.Labels,
+		InsertID:     e.InsertID,
+		Trace:        e.Trace,
+		SpanID:       e.SpanID,
+		TraceSampled: e.TraceSampled,
+	}
+	if e.HTTPRequest != nil {
+		entry.HTTPRequest = toHTTPRequest(e.HTTPRequest)
+	}
+	if e.Operation != nil {
+		entry.Operation = &loggingpb.LogEntryOperation{
+			Id:       e.Operation.ID,
+			Producer: e.Operation.Producer,
+			First:    e.Operation.First,
+			Last:     e.Operation.Last,
+		}
+	}
+	if e.SourceLocation != nil {
+		entry.SourceLocation = &loggingpb.LogEntrySourceLocation{
+			File:     e.SourceLocation.File,
+			Line:     e.SourceLocation.Line,
+			Function: e.SourceLocation.Function,
+		}
+	}
+	return entry
+}
+
+// toHTTPRequest は sloggcloud.HTTPRequest を logging.HTTPRequest に変換します。
+// logging.HTTPRequest はメソッドや URL を *http.Request から取得するため、取り出した値から組み立て直します。
+func toHTTPRequest(r *sloggcloud.HTTPRequest) *logging.HTTPRequest {
+	// クライアントは URL を必ず参照するため、解析できない場合も空の URL を設定する
+	u, err := url.Parse(r.RequestURL)
+	if err != nil {
+		u = &url.URL{}
+	}
+	header := http.Header{}
+	if r.UserAgent != "" {
+		header.Set("User-Agent", r.UserAgent)
+	}
+	if r.Referer != "" {
+		header.Set("Referer", r.Referer)
+	}
+
+	//nolint:exhaustruct // キャッシュの情報は sloggcloud.HTTPRequest に含まれない
+	return &logging.HTTPRequest{
+		//nolint:exhaustruct // ログに出力する項目だけを設定する
+		Request: &http.Request{
+			Method: r.RequestMethod,
+			URL:    u,
+			Proto:  r.Protocol,
+			Header: header,
+		},
+		RequestSize:  r.RequestSize,
+		Status:       r.Status,
+		ResponseSize: r.ResponseSize,
+		Latency:      r.Latency,
+		LocalIP:      r.ServerIP,
+		RemoteIP:     r.RemoteIP,
+	}
+}
@@ -0,0 +1,212 @@
+package cloudlogging_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+	"github.com/p1ass/go-pkg/sloggcloud/cloudlogging"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+// request は http.Request のうち、logging.HTTPRequest がログに出力する項目です。
+// http.Request は非公開のフィールドを持ち cmp で直接比較できないため、この構造体に変換して比較します。
+type request struct {
+	Method string
+	URL    *url.URL
+	Proto  string
+	Header http.Header
+}
+
+func TestToLoggingEntry(t *testing.T) {
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	tests := []struct {
+		name  string
+		entry sloggcloud.Entry
+		want  logging.Entry
+	}{
+		{
+			name: "severityとペイロードを変換",
+			entry: sloggcloud.Entry{
+				Timestamp: timestamp,
+				Severity:  "WARNING",
+				Payload:   map[string]any{"message": "hello", "user": "alice"},
+			},
+			want: logging.Entry{
+				Timestamp: timestamp,
+				Severity:  logging.Warning,
+				Payload:   map[string]any{"message": "hello", "user": "alice"},
+			},
+		},
+		{
+			name: "ラベル・トレース・insertIdを変換",
+			entry: sloggcloud.Entry{
+				Timestamp:    timestamp,
+				Severity:     "INFO",
+				Payload:      map[string]any{"message": "hello"},
+				Labels:       map[string]string{"env": "prod"},
+				InsertID:     "insert-id",
+				Trace:        "projects/test-project/traces/01020304050607080102030405060708",
+				SpanID:       "0102030405060708",
+				TraceSampled: true,
+			},
+			want: logging.Entry{
+				Timestamp:    timestamp,
+				Severity:     logging.Info,
+				Payload:      map[string]any{"message": "hello"},
+				Labels:       map[string]string{"env": "prod"},
+				InsertID:     "insert-id",
+				Trace:        "projects/test-project/traces/01020304050607080102030405060708",
+				SpanID:       "0102030405060708",
+				TraceSampled: true,
+			},
+		},
+		{
+			name: "operationとソースコードの位置情報を変換",
+			entry: sloggcloud.Entry{
+				Timestamp: timestamp,
+				Severity:  "ERROR",
+				Payload:   map[string]any{"message": "hello"},
+				Operation: &sloggcloud.Operation{ID: "op-1", Producer: "test", First: true, Last: false},
+				SourceLocation: &sloggcloud.SourceLocation{
+					File:     "main.go",
+					Line:     42,
+					Function: "main.main",
+				},
+			},
+			want: logging.Entry{
+				Timestamp: timestamp,
+				Severity:  logging.Error,
+				Payload:   map[string]any{"message": "hello"},
+				Operation: &loggingpb.LogEntryOperation{Id: "op-1", Producer: "test", First: true, Last: false},
+				SourceLocation: &loggingpb.LogEntrySourceLocation{
+					File:     "main.go",
+					Line:     42,
+					Function: "main.main",
+				},
+			},
+		},
+		{
+			name: "未知のseverityはDefaultに変換",
+			entry: sloggcloud.Entry{
+				Timestamp: timestamp,
+				Severity:  "UNKNOWN",
+				Payload:   map[string]any{"message": "hello"},
+			},
+			want: logging.Entry{
+				Timestamp: timestamp,
+				Severity:  logging.Default,
+				Payload:   map[string]any{"message": "hello"},
+			},
+		},
+		{
+			name: "httpRequestがnilの場合は設定しない",
+			entry: sloggcloud.Entry{
+				Timestamp:   timestamp,
+				Severity:    "INFO",
+				Payload:     map[string]any{"message": "hello"},
+				HTTPRequest: nil,
+			},
+			want: logging.Entry{
+				Timestamp:   timestamp,
+				Severity:    logging.Info,
+				Payload:     map[string]any{"message": "hello"},
+				HTTPRequest: nil,
+			},
+		},
+		{
+			name: "httpRequestのサイズとレイテンシを変換",
+			entry: sloggcloud.Entry{
+				Timestamp: timestamp,
+				Severity:  "INFO",
+				Payload:   map[string]any{"message": "hello"},
+				HTTPRequest: &sloggcloud.HTTPRequest{
+					RequestMethod: "POST",
+					RequestURL:    "https://example.com/users?id=1",
+					RequestSize:   128,
+					Status:        201,
+					ResponseSize:  2048,
+					UserAgent:     "test-agent",
+					RemoteIP:      "192.0.2.1",
+					ServerIP:      "192.0.2.2",
+					Referer:       "https://example.com/",
+					Latency:       1500 * time.Millisecond,
+					Protocol:      "HTTP/1.1",
+				},
+			},
+			want: logging.Entry{
+				Timestamp: timestamp,
+				Severity:  logging.Info,
+				Payload:   map[string]any{"message": "hello"},
+				HTTPRequest: &logging.HTTPRequest{
+					Request: &http.Request{
+						Method: "POST",
+						URL:    &url.URL{Scheme: "https", Host: "example.com", Path: "/users", RawQuery: "id=1"},
+						Proto:  "HTTP/1.1",
+						Header: http.Header{
+							"User-Agent": []string{"test-agent"},
+							"Referer":    []string{"https://example.com/"},
+						},
+					},
+					RequestSize:  128,
+					Status:       201,
+					ResponseSize: 2048,
+					Latency:      1500 * time.Millisecond,
+					LocalIP:      "192.0.2.2",
+					RemoteIP:     "192.0.2.1",
+				},
+			},
+		},
+		{
+			name: "解析できないURLは空のURLに変換",
+			entry: sloggcloud.Entry{
+				Timestamp: timestamp,
+				Severity:  "INFO",
+				Payload:   map[string]any{"message": "hello"},
+				HTTPRequest: &sloggcloud.HTTPRequest{
+					RequestMethod: "GET",
+					RequestURL:    "http://[::1",
+					Status:        400,
+				},
+			},
+			want: logging.Entry{
+				Timestamp: timestamp,
+				Severity:  logging.Info,
+				Payload:   map[string]any{"message": "hello"},
+				HTTPRequest: &logging.HTTPRequest{
+					Request: &http.Request{
+						Method: "GET",
+						URL:    &url.URL{},
+						Header: http.Header{},
+					},
+					Status: 400,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cloudlogging.ToLoggingEntry(tt.entry)
+
+			opts := cmp.Options{
+				protocmp.Transform(),
+				cmp.Transformer("request", func(r *http.Request) *request {
+					if r == nil {
+						return nil
+					}
+					return &request{Method: r.Method, URL: r.URL, Proto: r.Proto, Header: r.Header}
+				}),
+			}
+			if diff := cmp.Diff(tt.want, got, opts); diff != "" {
+				t.Errorf("entry mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
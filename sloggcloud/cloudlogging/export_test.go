@@ -0,0 +1,4 @@
+package cloudlogging
+
+// ToLoggingEntry はテストから toLoggingEntry を呼び出すために公開します。
+var ToLoggingEntry = toLoggingEntry
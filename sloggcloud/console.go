@@ -0,0 +1,159 @@
+package sloggcloud
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// consoleTimeFormat はコンソール形式で出力する時刻のフォーマットです。
+const consoleTimeFormat = "2006-01-02 15:04:05.000"
+
+// severityWidth は severity の表示幅で、最も長い EMERGENCY に合わせています。
+const severityWidth = len("EMERGENCY")
+
+// isTerminal は書き込み先が端末かどうかを判定します。テストで差し替えられるように変数にしています。
+var isTerminal = func(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// NewAuto は実行環境に応じて出力形式を自動で選択する Handler を作成します。
+// 書き込み先が端末の場合は WithConsole を有効にしたコンソール形式で、それ以外の場合は JSON 形式で出力します。
+// GKE や Compute Engine、Cloud Run ジョブなどは実行環境を環境変数から判別できないため、端末でなければ Cloud Logging が解析できる JSON 形式にします。
+// opts で WithConsole を指定した場合はそちらが優先されます。
+func NewAuto(w io.Writer, opts ...Option) *Handler {
+	// New と同じく nil の場合は os.Stderr とみなし、端末かどうかを正しく判定する
+	if w == nil {
+		w = os.Stderr
+	}
+	opts = append([]Option{WithConsole(shouldUseConsole(w))}, opts...)
+	return New(w, opts...)
+}
+
+// shouldUseConsole はコンソール形式で出力すべきかどうかを判定します。
+// エスケープシーケンスを含むコンソール形式は Cloud Logging が解析できないため、人が直接読む端末の場合のみ選択する。
+func shouldUseConsole(w io.Writer) bool {
+	return isTerminal(w)
+}
+
+// consoleHandler はローカル開発向けに、人が読みやすい形式でレコードを出力します。
+// "time severity message key=value ..." の形式で 1 レコードを 1 行に出力し、severity は色付けされます。
+type consoleHandler struct {
+	w    io.Writer
+	opts *options
+}
+
+func newConsoleHandler(w io.Writer, o *options) *consoleHandler {
+	return &consoleHandler{w: w, opts: o}
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	if !r.Time.IsZero() {
+		b.WriteString(r.Time.Format(consoleTimeFormat))
+		b.WriteByte(' ')
+	}
+
+	severity := h.opts.severityMapper(r.Level)
+	b.WriteString(severityColor(severity))
+	b.WriteString(severity)
+	b.WriteString(colorReset)
+	b.WriteString(strings.Repeat(" ", max(severityWidth-len(severity), 0)))
+	b.WriteByte(' ')
+	b.WriteString(r.Message)
+
+	r.Attrs(func(a slog.Attr) bool {
+		writeConsoleAttr(&b, "", a)
+		return true
+	})
+	b.WriteByte('\n')
+
+	_, err := io.WriteString(h.w, b.String())
+	return err //nolint:wrapcheck // Handler.Handle でラップする
+}
+
+// writeConsoleAttr は属性を key=value の形式で書き込みます。グループはキーを "." でつなげて展開します。
+func writeConsoleAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	key := a.Key
+	if prefix != "" && key != "" {
+		key = prefix + "." + key
+	} else if key == "" {
+		key = prefix
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			writeConsoleAttr(b, key, ga)
+		}
+		return
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(colorFaint)
+	b.WriteString(key)
+	b.WriteByte('=')
+	b.WriteString(colorReset)
+	b.WriteString(quoteIfNeeded(a.Value.String()))
+}
+
+// quoteIfNeeded は空白や制御文字を含む文字列を 1 行で読めるようにクォートします。
+func quoteIfNeeded(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) || r == '"' || r == '=' {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// ANSI エスケープシーケンスによる色の指定です。
+const (
+	colorReset   = "\x1b[0m"
+	colorFaint   = "\x1b[2m"
+	colorGray    = "\x1b[90m"
+	colorCyan    = "\x1b[36m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorRed     = "\x1b[31m"
+	colorBoldRed = "\x1b[1;31m"
+)
+
+// severityColor は severity に対応する色を返します。
+func severityColor(severity string) string {
+	switch severity {
+	case "DEBUG":
+		return colorGray
+	case "INFO":
+		return colorCyan
+	case "NOTICE":
+		return colorGreen
+	case "WARNING":
+		return colorYellow
+	case "ERROR":
+		return colorRed
+	case "CRITICAL", "ALERT", "EMERGENCY":
+		return colorBoldRed
+	default:
+		return colorReset
+	}
+}
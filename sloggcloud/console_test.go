@@ -0,0 +1,164 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithConsole(t *testing.T) {
+	recordTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		level        slog.Level
+		attrs        []slog.Attr
+		setupGroups  func(h *sloggcloud.Handler) slog.Handler
+		wantContains []string
+	}{
+		{
+			name:  "INFOレベルのログ",
+			level: slog.LevelInfo,
+			attrs: []slog.Attr{slog.String("user", "alice")},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h
+			},
+			wantContains: []string{"2024-01-02 03:04:05.000", "INFO", "hello console", "user=", "alice"},
+		},
+		{
+			name:  "ERRORレベルのログは赤色で出力",
+			level: slog.LevelError,
+			attrs: []slog.Attr{slog.Int("code", 500)},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h
+			},
+			wantContains: []string{"\x1b[31mERROR", "hello console", "code=", "500"},
+		},
+		{
+			name:  "グループはキーをドットでつなげて出力",
+			level: slog.LevelInfo,
+			attrs: []slog.Attr{slog.String("host", "example.com")},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h.WithGroup("server")
+			},
+			wantContains: []string{"server.host=", "example.com"},
+		},
+		{
+			name:  "空白を含む値はクォートして出力",
+			level: slog.LevelInfo,
+			attrs: []slog.Attr{slog.String("query", "SELECT 1")},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h
+			},
+			wantContains: []string{`"SELECT 1"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := sloggcloud.New(&buf,
+				sloggcloud.WithConsole(true),
+				sloggcloud.WithSource(false),
+			)
+
+			r := slog.NewRecord(recordTime, tt.level, "hello console", 0)
+			r.AddAttrs(tt.attrs...)
+			if err := tt.setupGroups(handler).Handle(context.Background(), r); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			got := buf.String()
+			if json.Valid(buf.Bytes()) {
+				t.Errorf("console output should not be JSON: %s", got)
+			}
+			if strings.Count(got, "\n") != 1 || !strings.HasSuffix(got, "\n") {
+				t.Errorf("console output should be a single line: %q", got)
+			}
+			for _, want := range tt.wantContains {
+				if !strings.Contains(got, want) {
+					t.Errorf("console output %q does not contain %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewAuto(t *testing.T) {
+	tests := []struct {
+		name        string
+		env         map[string]string
+		isTerminal  bool
+		opts        []sloggcloud.Option
+		wantConsole bool
+	}{
+		{
+			name:        "端末に出力する場合はコンソール形式",
+			env:         map[string]string{"K_SERVICE": "", "GAE_SERVICE": ""},
+			isTerminal:  true,
+			opts:        []sloggcloud.Option{},
+			wantConsole: true,
+		},
+		{
+			name:        "Cloud Runの環境変数があっても端末に出力する場合はコンソール形式",
+			env:         map[string]string{"K_SERVICE": "my-service", "GAE_SERVICE": ""},
+			isTerminal:  true,
+			opts:        []sloggcloud.Option{},
+			wantConsole: true,
+		},
+		{
+			name:        "Cloud Runの環境変数がある場合はJSON形式",
+			env:         map[string]string{"K_SERVICE": "my-service", "GAE_SERVICE": ""},
+			isTerminal:  false,
+			opts:        []sloggcloud.Option{},
+			wantConsole: false,
+		},
+		{
+			name:        "App Engineの環境変数がある場合はJSON形式",
+			env:         map[string]string{"K_SERVICE": "", "GAE_SERVICE": "default"},
+			isTerminal:  false,
+			opts:        []sloggcloud.Option{},
+			wantConsole: false,
+		},
+		{
+			name:        "GKEなどGoogle Cloudの環境変数がない環境でも端末でない場合はJSON形式",
+			env:         map[string]string{"K_SERVICE": "", "GAE_SERVICE": ""},
+			isTerminal:  false,
+			opts:        []sloggcloud.Option{},
+			wantConsole: false,
+		},
+		{
+			name:        "WithConsoleを指定した場合はそちらを優先",
+			env:         map[string]string{"K_SERVICE": "", "GAE_SERVICE": ""},
+			isTerminal:  true,
+			opts:        []sloggcloud.Option{sloggcloud.WithConsole(false)},
+			wantConsole: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			sloggcloud.SetIsTerminal(t, func(io.Writer) bool {
+				return tt.isTerminal
+			})
+
+			var buf bytes.Buffer
+			opts := append([]sloggcloud.Option{sloggcloud.WithSource(false)}, tt.opts...)
+			slog.New(sloggcloud.NewAuto(&buf, opts...)).Info("hello auto")
+
+			if gotConsole := !json.Valid(buf.Bytes()); gotConsole != tt.wantConsole {
+				t.Errorf("console format = %v, want %v: %s", gotConsole, tt.wantConsole, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package sloggcloud
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+type attrsKey struct{}
+
+// ContextWithAttrs は属性を追加したコンテキストを返します。
+// このコンテキストを渡して出力したログには、WithAttrs で追加した属性と同じように attrs が付与されます。
+// 既にコンテキストに属性がある場合は置き換えずに追加します。
+func ContextWithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	// 親のコンテキストと底の配列を共有しないように容量を切り詰めてから追加する
+	return context.WithValue(ctx, attrsKey{}, append(slices.Clip(AttrsFromContext(ctx)), attrs...))
+}
+
+// AttrsFromContext は ContextWithAttrs でコンテキストに追加した属性を返します。
+func AttrsFromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+type levelKey struct{}
+
+// ContextWithLevel はログの最小レベルを level に置き換えたコンテキストを返します。
+// サービス全体は INFO で動かしたまま、特定のリクエストだけ DEBUG のログを出力する場合などに利用します。
+// このコンテキストを渡したログでは、WithLevel や WithGroupLevel で指定したレベルの代わりに level を使用します。
+func ContextWithLevel(ctx context.Context, level slog.Level) context.Context {
+	return context.WithValue(ctx, levelKey{}, level)
+}
+
+// levelFromContext は ContextWithLevel でコンテキストに設定したレベルを返します。
+func levelFromContext(ctx context.Context) (slog.Level, bool) {
+	// slog.Handler の Enabled は nil のコンテキストで呼び出される可能性がある
+	if ctx == nil {
+		return 0, false
+	}
+	level, ok := ctx.Value(levelKey{}).(slog.Level)
+	return level, ok
+}
@@ -0,0 +1,201 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+// logFromContext はコンテキストのみを受け取ってログを出力する関数です。
+func logFromContext(ctx context.Context, logger *slog.Logger) {
+	logger.InfoContext(ctx, "context attrs", "key", "value")
+}
+
+func TestContextWithAttrs(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(ctx context.Context) context.Context
+		groups []string
+		want   map[string]interface{}
+	}{
+		{
+			name: "コンテキストの属性を出力",
+			setup: func(ctx context.Context) context.Context {
+				return sloggcloud.ContextWithAttrs(ctx, slog.String("request_id", "r-1"), slog.String("user", "alice"))
+			},
+			want: map[string]interface{}{
+				"severity":   "INFO",
+				"message":    "context attrs",
+				"request_id": "r-1",
+				"user":       "alice",
+				"key":        "value",
+			},
+		},
+		{
+			name: "ネストした呼び出しでは属性を追加",
+			setup: func(ctx context.Context) context.Context {
+				ctx = sloggcloud.ContextWithAttrs(ctx, slog.String("request_id", "r-1"))
+				return sloggcloud.ContextWithAttrs(ctx, slog.String("user", "alice"))
+			},
+			want: map[string]interface{}{
+				"severity":   "INFO",
+				"message":    "context attrs",
+				"request_id": "r-1",
+				"user":       "alice",
+				"key":        "value",
+			},
+		},
+		{
+			name: "グループを指定した場合はグループの中に出力",
+			setup: func(ctx context.Context) context.Context {
+				return sloggcloud.ContextWithAttrs(ctx, slog.String("request_id", "r-1"))
+			},
+			groups: []string{"api"},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "context attrs",
+				"api": map[string]interface{}{
+					"request_id": "r-1",
+					"key":        "value",
+				},
+			},
+		},
+		{
+			name: "属性を指定しない場合は何も追加しない",
+			setup: func(ctx context.Context) context.Context {
+				return sloggcloud.ContextWithAttrs(ctx)
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "context attrs",
+				"key":      "value",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, sloggcloud.WithSource(false)))
+			for _, group := range tt.groups {
+				logger = logger.WithGroup(group)
+			}
+
+			logFromContext(tt.setup(context.Background()), logger)
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAttrsFromContext(t *testing.T) {
+	parent := sloggcloud.ContextWithAttrs(context.Background(), slog.String("request_id", "r-1"))
+	child1 := sloggcloud.ContextWithAttrs(parent, slog.String("user", "alice"))
+	child2 := sloggcloud.ContextWithAttrs(parent, slog.String("user", "bob"))
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want []slog.Attr
+	}{
+		{name: "属性がない場合はnil", ctx: context.Background(), want: nil},
+		{name: "親のコンテキストの属性", ctx: parent, want: []slog.Attr{slog.String("request_id", "r-1")}},
+		{
+			name: "子のコンテキストは親の属性を引き継ぐ",
+			ctx:  child1,
+			want: []slog.Attr{slog.String("request_id", "r-1"), slog.String("user", "alice")},
+		},
+		{
+			name: "兄弟のコンテキストの属性は互いに影響しない",
+			ctx:  child2,
+			want: []slog.Attr{slog.String("request_id", "r-1"), slog.String("user", "bob")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sloggcloud.AttrsFromContext(tt.ctx)
+			if diff := cmp.Diff(tt.want, got, cmp.Comparer(func(a, b slog.Attr) bool { return a.Equal(b) })); diff != "" {
+				t.Errorf("AttrsFromContext() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestContextWithLevel(t *testing.T) {
+	handler := sloggcloud.New(io.Discard, sloggcloud.WithLevel(slog.LevelInfo), sloggcloud.WithGroupLevel("db", slog.LevelWarn))
+	debugCtx := sloggcloud.ContextWithLevel(context.Background(), slog.LevelDebug)
+
+	tests := []struct {
+		name    string
+		handler slog.Handler
+		ctx     context.Context
+		level   slog.Level
+		want    bool
+	}{
+		{
+			name:    "コンテキストにレベルがない場合はDEBUGを出力しない",
+			handler: handler,
+			ctx:     context.Background(),
+			level:   slog.LevelDebug,
+			want:    false,
+		},
+		{
+			name:    "コンテキストでDEBUGを指定した場合はDEBUGを出力",
+			handler: handler,
+			ctx:     debugCtx,
+			level:   slog.LevelDebug,
+			want:    true,
+		},
+		{
+			name:    "WithGroupLevelのレベルよりも優先",
+			handler: handler.WithGroup("db"),
+			ctx:     debugCtx,
+			level:   slog.LevelDebug,
+			want:    true,
+		},
+		{
+			name:    "コンテキストで高いレベルを指定した場合は出力しない",
+			handler: handler,
+			ctx:     sloggcloud.ContextWithLevel(context.Background(), slog.LevelError),
+			level:   slog.LevelWarn,
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.handler.Enabled(tt.ctx, tt.level); got != tt.want {
+				t.Errorf("Enabled(%s) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("コンテキストを渡したリクエストのみDEBUGのログを出力", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(sloggcloud.New(&buf, sloggcloud.WithSource(false)))
+
+		logger.DebugContext(context.Background(), "skipped")
+		logger.DebugContext(debugCtx, "traced")
+
+		got := decodeLines(t, &buf)
+		want := []map[string]interface{}{{"severity": "DEBUG", "message": "traced"}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
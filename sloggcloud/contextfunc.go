@@ -0,0 +1,33 @@
+package sloggcloud
+
+import (
+	"context"
+	"time"
+)
+
+// callContextFunc は WithLabelsFromContext などで設定した利用者の関数 f を、WithContextFuncTimeout の時間を上限に呼び出します。
+// 時間内に終わらなかった場合は f の結果を使わずにゼロ値と false を返し、ログの出力を止めないようにします。
+// f は別の goroutine で実行し続けるため、f はコンテキストの期限を確認して早めに処理を返すことが望ましいです。
+func callContextFunc[T any](ctx context.Context, timeout time.Duration, f func(context.Context) T) (T, bool) {
+	if timeout <= 0 {
+		return f(ctx), true
+	}
+
+	// リクエストの終了などで呼び出し元のコンテキストがキャンセルされていても、f の結果は使えるようにする
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	// 時間切れで受け取らなくなっても f の goroutine が終了できるように、バッファを持たせる
+	done := make(chan T, 1)
+	go func() {
+		done <- f(ctx)
+	}()
+
+	select {
+	case v := <-done:
+		return v, true
+	case <-ctx.Done():
+		var zero T
+		return zero, false
+	}
+}
@@ -0,0 +1,124 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithContextFuncTimeout(t *testing.T) {
+	// 遅い関数はテストが終わるまで処理を返さない
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	slow := func() { <-release }
+
+	labelsFunc := func(slowFunc bool) sloggcloud.Option {
+		return sloggcloud.WithLabelsFromContext(func(context.Context) map[string]string {
+			if slowFunc {
+				slow()
+			}
+			return map[string]string{"tenant": "t-1"}
+		})
+	}
+	attrsFunc := func(slowFunc bool) sloggcloud.Option {
+		return sloggcloud.WithDefaultAttrsFunc(func(context.Context) []slog.Attr {
+			if slowFunc {
+				slow()
+			}
+			return []slog.Attr{slog.String("request_id", "r-1")}
+		})
+	}
+	traceFunc := func(slowFunc bool) sloggcloud.Option {
+		return sloggcloud.WithTraceIDFunc(func(context.Context) (string, string, bool) {
+			if slowFunc {
+				slow()
+			}
+			return "custom-trace-id", "", false
+		})
+	}
+
+	tests := []struct {
+		name string
+		opts []sloggcloud.Option
+		want map[string]interface{}
+	}{
+		{
+			name: "時間内に終わった関数の結果は出力",
+			opts: []sloggcloud.Option{labelsFunc(false), attrsFunc(false), traceFunc(false)},
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "hello",
+				"request_id":                           "r-1",
+				"logging.googleapis.com/labels":        map[string]interface{}{"tenant": "t-1"},
+				"logging.googleapis.com/trace":         "projects/test-project/traces/custom-trace-id",
+				"logging.googleapis.com/trace_sampled": false,
+			},
+		},
+		{
+			name: "時間内に終わらなかった関数の結果は出力しない",
+			opts: []sloggcloud.Option{labelsFunc(true), attrsFunc(true), traceFunc(true)},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "hello",
+			},
+		},
+		{
+			name: "遅い関数の結果のみ出力しない",
+			opts: []sloggcloud.Option{labelsFunc(false), attrsFunc(true), traceFunc(false)},
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "hello",
+				"logging.googleapis.com/labels":        map[string]interface{}{"tenant": "t-1"},
+				"logging.googleapis.com/trace":         "projects/test-project/traces/custom-trace-id",
+				"logging.googleapis.com/trace_sampled": false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts,
+				sloggcloud.WithProjectID("test-project"),
+				sloggcloud.WithSource(false),
+				sloggcloud.WithContextFuncTimeout(50*time.Millisecond),
+			)...))
+
+			start := time.Now()
+			logger.Info("hello")
+			// 3 つの関数がそれぞれ時間切れになっても、ログの出力はすぐに終わる
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("logging took %v, want less than 1s", elapsed)
+			}
+
+			got := decodeLines(t, &buf)
+			if diff := cmp.Diff([]map[string]interface{}{tt.want}, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("呼び出し元のコンテキストがキャンセルされていても関数の結果を出力", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(sloggcloud.New(&buf,
+			sloggcloud.WithSource(false),
+			attrsFunc(false),
+			sloggcloud.WithContextFuncTimeout(50*time.Millisecond),
+		))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		logger.InfoContext(ctx, "hello")
+
+		got := decodeLines(t, &buf)
+		want := []map[string]interface{}{{"severity": "INFO", "message": "hello", "request_id": "r-1"}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
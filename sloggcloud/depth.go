@@ -0,0 +1,41 @@
+package sloggcloud
+
+import (
+	"log/slog"
+)
+
+// truncatedKey は WithMaxAttrDepth で入れ子を切り詰めたグループに出力するキーです。
+const truncatedKey = "_truncated"
+
+// truncateAttrDepth は maxDepth より深いグループを取り除き、取り除いたグループの親に "_truncated": true を出力します。
+// トップレベルのグループの深さを 1 として数えます。
+func truncateAttrDepth(attrs []slog.Attr, maxDepth int) []slog.Attr {
+	return truncateGroupAttrs(attrs, 1, maxDepth)
+}
+
+// truncateGroupAttrs は深さ depth に出力する属性のうち、maxDepth を超えて入れ子になるグループを取り除きます。
+func truncateGroupAttrs(attrs []slog.Attr, depth, maxDepth int) []slog.Attr {
+	truncated := false
+	result := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		if attr.Value.Kind() != slog.KindGroup {
+			result = append(result, attr)
+			continue
+		}
+		// 空のキーのグループは親に展開されるため、深さを増やさない
+		if attr.Key == "" {
+			result = append(result, truncateGroupAttrs(attr.Value.Group(), depth, maxDepth)...)
+			continue
+		}
+		if depth > maxDepth {
+			truncated = true
+			continue
+		}
+		attr.Value = slog.GroupValue(truncateGroupAttrs(attr.Value.Group(), depth+1, maxDepth)...)
+		result = append(result, attr)
+	}
+	if truncated {
+		result = append(result, slog.Bool(truncatedKey, true))
+	}
+	return result
+}
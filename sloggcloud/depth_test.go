@@ -0,0 +1,107 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithMaxAttrDepth(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []sloggcloud.Option
+		groups int
+		args   []any
+		want   map[string]interface{}
+	}{
+		{
+			name:   "10段のWithGroupを3段に切り詰め",
+			opts:   []sloggcloud.Option{sloggcloud.WithMaxAttrDepth(3)},
+			groups: 10,
+			args:   []any{"key", "value"},
+			want: map[string]interface{}{
+				"g1": map[string]interface{}{
+					"g2": map[string]interface{}{
+						"g3": map[string]interface{}{
+							"_truncated": true,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:   "WithGroupと属性のグループを合わせて数える",
+			opts:   []sloggcloud.Option{sloggcloud.WithMaxAttrDepth(2)},
+			groups: 1,
+			args:   []any{"key", "value", slog.Group("nested", "key", "value", slog.Group("deep", "key", "value"))},
+			want: map[string]interface{}{
+				"g1": map[string]interface{}{
+					"key": "value",
+					"nested": map[string]interface{}{
+						"key":        "value",
+						"_truncated": true,
+					},
+				},
+			},
+		},
+		{
+			name:   "上限以内の場合は切り詰めない",
+			opts:   []sloggcloud.Option{sloggcloud.WithMaxAttrDepth(3)},
+			groups: 2,
+			args:   []any{"key", "value"},
+			want: map[string]interface{}{
+				"g1": map[string]interface{}{
+					"g2": map[string]interface{}{
+						"key": "value",
+					},
+				},
+			},
+		},
+		{
+			name:   "デフォルトでは制限しない",
+			opts:   nil,
+			groups: 4,
+			args:   []any{"key", "value"},
+			want: map[string]interface{}{
+				"g1": map[string]interface{}{
+					"g2": map[string]interface{}{
+						"g3": map[string]interface{}{
+							"g4": map[string]interface{}{
+								"key": "value",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...))
+			for i := range tt.groups {
+				logger = logger.WithGroup(fmt.Sprintf("g%d", i+1))
+			}
+
+			logger.Info("nested", tt.args...)
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			for _, key := range []string{"time", "severity", "message"} {
+				delete(got, key)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package sloggcloud
+
+import (
+	"log/slog"
+)
+
+// DurationFormat は slog.KindDuration の属性の出力形式です。
+type DurationFormat string
+
+const (
+	// DurationFormatString は "1.5s" のような Google Cloud の Duration 形式の文字列で出力します。
+	DurationFormatString DurationFormat = "string"
+	// DurationFormatSeconds は 1.5 のような秒単位の数値で出力します。
+	DurationFormatSeconds DurationFormat = "seconds"
+	// DurationFormatNanos は 1500000000 のようなナノ秒単位の整数で出力します。slog.JSONHandler の標準の形式です。
+	DurationFormatNanos DurationFormat = "nanos"
+)
+
+// formatDurationAttr は slog.KindDuration の属性を format に従って変換します。
+func formatDurationAttr(a slog.Attr, format DurationFormat) slog.Attr {
+	d := a.Value.Duration()
+	switch format {
+	case DurationFormatString:
+		return slog.String(a.Key, formatDuration(d))
+	case DurationFormatSeconds:
+		return slog.Float64(a.Key, d.Seconds())
+	case DurationFormatNanos:
+	}
+	return a
+}
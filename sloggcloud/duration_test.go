@@ -0,0 +1,91 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithDurationFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []sloggcloud.Option
+		want map[string]interface{}
+	}{
+		{
+			name: "デフォルトはGoogle Cloudの文字列形式",
+			opts: []sloggcloud.Option{},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "duration",
+				"elapsed":  "1.5s",
+				"db": map[string]interface{}{
+					"latency": "1.5s",
+				},
+			},
+		},
+		{
+			name: "string",
+			opts: []sloggcloud.Option{sloggcloud.WithDurationFormat(sloggcloud.DurationFormatString)},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "duration",
+				"elapsed":  "1.5s",
+				"db": map[string]interface{}{
+					"latency": "1.5s",
+				},
+			},
+		},
+		{
+			name: "seconds",
+			opts: []sloggcloud.Option{sloggcloud.WithDurationFormat(sloggcloud.DurationFormatSeconds)},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "duration",
+				"elapsed":  1.5,
+				"db": map[string]interface{}{
+					"latency": 1.5,
+				},
+			},
+		},
+		{
+			name: "nanos",
+			opts: []sloggcloud.Option{sloggcloud.WithDurationFormat(sloggcloud.DurationFormatNanos)},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "duration",
+				"elapsed":  float64(1500000000),
+				"db": map[string]interface{}{
+					"latency": float64(1500000000),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...))
+
+			logger.Info("duration",
+				slog.Duration("elapsed", 1500*time.Millisecond),
+				slog.Group("db", slog.Duration("latency", 1500*time.Millisecond)),
+			)
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package sloggcloud
+
+import (
+	"log/slog"
+)
+
+// errorReportingType は Error Reporting にエラーとして認識させるための @type の値です。
+const errorReportingType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// serviceContext は Error Reporting がエラーをグループ化するためのサービス情報です。
+type serviceContext struct {
+	service string
+	version string
+}
+
+// attr は serviceContext を Error Reporting の serviceContext の形式の slog.Attr に変換します。
+func (s *serviceContext) attr() slog.Attr {
+	attrs := []slog.Attr{slog.String("service", s.service)}
+	if s.version != "" {
+		attrs = append(attrs, slog.String("version", s.version))
+	}
+	return slog.Attr{Key: "serviceContext", Value: slog.GroupValue(attrs...)}
+}
+
+// errorReportingAttrs は ERROR 以上のレコードを Error Reporting に送るための属性を返します。
+// Error Reporting に必要な stack_trace は stackTraceAttr で出力されます。
+// WithServiceContext が指定されている場合は、その serviceContext が優先されます。
+func (h *Handler) errorReportingAttrs(r slog.Record) []slog.Attr {
+	if r.Level < slog.LevelError {
+		return nil
+	}
+
+	attrs := make([]slog.Attr, 0, 2)
+	if h.opts.errorReporting != nil {
+		attrs = append(attrs, slog.String("@type", errorReportingType))
+	}
+	switch {
+	case h.opts.serviceContext != nil:
+		attrs = append(attrs, h.opts.serviceContext.attr())
+	case h.opts.errorReporting != nil:
+		sc := *h.opts.errorReporting
+		if sc.version == "" {
+			sc.version = h.opts.version
+		}
+		attrs = append(attrs, sc.attr())
+	}
+	return attrs
+}
@@ -0,0 +1,188 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithErrorReporting(t *testing.T) {
+	tests := []struct {
+		name               string
+		level              slog.Level
+		want               map[string]interface{}
+		wantErrorReporting bool
+	}{
+		{
+			name:  "INFOレベルのログには付与しない",
+			level: slog.LevelInfo,
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "something happened",
+			},
+			wantErrorReporting: false,
+		},
+		{
+			name:  "WARNレベルのログには付与しない",
+			level: slog.LevelWarn,
+			want: map[string]interface{}{
+				"severity": "WARNING",
+				"message":  "something happened",
+			},
+			wantErrorReporting: false,
+		},
+		{
+			name:  "ERRORレベルのログに付与する",
+			level: slog.LevelError,
+			want: map[string]interface{}{
+				"severity": "ERROR",
+				"message":  "something happened",
+				"@type":    "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent",
+				"serviceContext": map[string]interface{}{
+					"service": "test-service",
+					"version": "v1.0.0",
+				},
+			},
+			wantErrorReporting: true,
+		},
+		{
+			name:  "CRITICALレベルのログに付与する",
+			level: sloggcloud.LevelCritical,
+			want: map[string]interface{}{
+				"severity": "CRITICAL",
+				"message":  "something happened",
+				"@type":    "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent",
+				"serviceContext": map[string]interface{}{
+					"service": "test-service",
+					"version": "v1.0.0",
+				},
+			},
+			wantErrorReporting: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf,
+				sloggcloud.WithErrorReporting("test-service", "v1.0.0"),
+				sloggcloud.WithSource(false),
+			))
+
+			logger.Log(t.Context(), tt.level, "something happened")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			stackTrace, ok := got["stack_trace"].(string)
+			if ok != tt.wantErrorReporting {
+				t.Fatalf("stack_trace presence = %v, want %v", ok, tt.wantErrorReporting)
+			}
+			if ok {
+				if !strings.HasPrefix(stackTrace, "something happened\n\ngoroutine 1 [running]:\n") {
+					t.Errorf("stack_trace does not start with message and goroutine header: %s", stackTrace)
+				}
+				if !strings.Contains(stackTrace, "TestWithErrorReporting") {
+					t.Errorf("stack_trace does not contain caller function: %s", stackTrace)
+				}
+				if strings.Contains(stackTrace, "log/slog.") {
+					t.Errorf("stack_trace should not contain slog internal frames: %s", stackTrace)
+				}
+				delete(got, "stack_trace")
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithServiceContext(t *testing.T) {
+	tests := []struct {
+		name  string
+		level slog.Level
+		opts  []sloggcloud.Option
+		want  map[string]interface{}
+	}{
+		{
+			name:  "INFOレベルのログには付与しない",
+			level: slog.LevelInfo,
+			opts:  []sloggcloud.Option{sloggcloud.WithServiceContext("test-service", "v1.0.0")},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "something happened",
+			},
+		},
+		{
+			name:  "ERRORレベルのログに付与する",
+			level: slog.LevelError,
+			opts:  []sloggcloud.Option{sloggcloud.WithServiceContext("test-service", "v1.0.0")},
+			want: map[string]interface{}{
+				"severity": "ERROR",
+				"message":  "something happened",
+				"serviceContext": map[string]interface{}{
+					"service": "test-service",
+					"version": "v1.0.0",
+				},
+			},
+		},
+		{
+			name:  "versionが空の場合はserviceのみ付与する",
+			level: slog.LevelError,
+			opts:  []sloggcloud.Option{sloggcloud.WithServiceContext("test-service", "")},
+			want: map[string]interface{}{
+				"severity": "ERROR",
+				"message":  "something happened",
+				"serviceContext": map[string]interface{}{
+					"service": "test-service",
+				},
+			},
+		},
+		{
+			name:  "WithErrorReportingよりWithServiceContextを優先",
+			level: slog.LevelError,
+			opts: []sloggcloud.Option{
+				sloggcloud.WithErrorReporting("reporting-service", "v0.0.1"),
+				sloggcloud.WithServiceContext("test-service", "v1.0.0"),
+			},
+			want: map[string]interface{}{
+				"severity": "ERROR",
+				"message":  "something happened",
+				"@type":    "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent",
+				"serviceContext": map[string]interface{}{
+					"service": "test-service",
+					"version": "v1.0.0",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...))
+
+			logger.Log(t.Context(), tt.level, "something happened")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+			delete(got, "stack_trace")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package sloggcloud
+
+import (
+	"context"
+	"io"
+	"os"
+	"runtime/debug"
+	"testing"
+)
+
+var LevelToSeverity = levelToSeverity
+
+// SetIsTerminal はテストの間だけ端末の判定を差し替えます。
+func SetIsTerminal(t *testing.T, fn func(io.Writer) bool) {
+	t.Helper()
+	orig := isTerminal
+	isTerminal = fn
+	t.Cleanup(func() {
+		isTerminal = orig
+	})
+}
+
+// SetWarnOutput はテストの間だけ警告の出力先を差し替えます。
+func SetWarnOutput(t *testing.T, w io.Writer) {
+	t.Helper()
+	orig := warnOutput
+	warnOutput = w
+	t.Cleanup(func() {
+		warnOutput = orig
+	})
+}
+
+// SetReadBuildInfo はテストの間だけビルド情報の取得を差し替えます。
+func SetReadBuildInfo(t *testing.T, fn func() (*debug.BuildInfo, bool)) {
+	t.Helper()
+	orig := readBuildInfo
+	readBuildInfo = fn
+	t.Cleanup(func() {
+		readBuildInfo = orig
+	})
+}
+
+var CloneRecord = cloneRecord
+
+// NewLineWriter は 1 回の書き込みを 1 行として w に書き込む io.Writer を作成します。
+func NewLineWriter(w io.Writer) io.Writer {
+	return newLineWriter(w)
+}
+
+// FlushOnSignal は実際のシグナルの代わりに sigCh からシグナルを受け取る HandleSignals です。
+func FlushOnSignal(ctx context.Context, h *Handler, sigCh <-chan os.Signal) (context.Context, func()) {
+	return h.flushOnSignal(ctx, sigCh, func() {})
+}
@@ -0,0 +1,30 @@
+package sloggcloud
+
+import (
+	"log/slog"
+)
+
+// flattenGroups はグループを入れ子にせず、グループ名を separator でつないだキーの属性に展開します。
+// 空のキーのグループは slog の規約どおり親に展開するため、キーに名前を加えません。
+func flattenGroups(attrs []slog.Attr, separator string) []slog.Attr {
+	return appendFlattenedAttrs(make([]slog.Attr, 0, len(attrs)), "", attrs, separator)
+}
+
+// appendFlattenedAttrs は attrs のキーの前に prefix を付け、グループの中の属性も含めて dst に追加します。
+func appendFlattenedAttrs(dst []slog.Attr, prefix string, attrs []slog.Attr, separator string) []slog.Attr {
+	for _, attr := range attrs {
+		key := attr.Key
+		switch {
+		case key == "":
+			key = prefix
+		case prefix != "":
+			key = prefix + separator + key
+		}
+		if attr.Value.Kind() == slog.KindGroup {
+			dst = appendFlattenedAttrs(dst, key, attr.Value.Group(), separator)
+			continue
+		}
+		dst = append(dst, slog.Attr{Key: key, Value: attr.Value})
+	}
+	return dst
+}
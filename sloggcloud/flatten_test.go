@@ -0,0 +1,91 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithFlattenGroups(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []sloggcloud.Option
+		groups int
+		args   []any
+		want   map[string]interface{}
+	}{
+		{
+			name:   "2段の属性のグループをドットでつないだキーで出力",
+			opts:   []sloggcloud.Option{sloggcloud.WithFlattenGroups(".")},
+			groups: 0,
+			args:   []any{slog.Group("db", slog.Group("query", slog.Duration("duration", 1500*time.Millisecond), "table", "users")), "key", "value"},
+			want: map[string]interface{}{
+				"db.query.duration": "1.5s",
+				"db.query.table":    "users",
+				"key":               "value",
+			},
+		},
+		{
+			name:   "WithGroupと属性のグループをつなぐ",
+			opts:   []sloggcloud.Option{sloggcloud.WithFlattenGroups(".")},
+			groups: 2,
+			args:   []any{"key", "value", slog.Group("nested", "key", "value")},
+			want: map[string]interface{}{
+				"g1.g2.key":        "value",
+				"g1.g2.nested.key": "value",
+			},
+		},
+		{
+			name:   "名前のないグループはキーに加えない",
+			opts:   []sloggcloud.Option{sloggcloud.WithFlattenGroups("_")},
+			groups: 1,
+			args:   []any{slog.Group("", "key", "value")},
+			want: map[string]interface{}{
+				"g1_key": "value",
+			},
+		},
+		{
+			name:   "デフォルトでは入れ子にする",
+			opts:   nil,
+			groups: 1,
+			args:   []any{slog.Group("nested", "key", "value")},
+			want: map[string]interface{}{
+				"g1": map[string]interface{}{
+					"nested": map[string]interface{}{
+						"key": "value",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...))
+			for i := range tt.groups {
+				logger = logger.WithGroup(fmt.Sprintf("g%d", i+1))
+			}
+
+			logger.Info("flatten", tt.args...)
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			for _, key := range []string{"time", "severity", "message"} {
+				delete(got, key)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
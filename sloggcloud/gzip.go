@@ -0,0 +1,64 @@
+package sloggcloud
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// gzipWriter は書き込みを gzip で圧縮する io.Writer です。
+// 圧縮済みのログファイルを読み込むエージェントが途中までのログも読めるように、書き込みのたびに圧縮したデータを書き出します。
+// WithBuffer と併用した場合はバッファを書き出すたびに書き出します。
+type gzipWriter struct {
+	w  io.Writer
+	gz *gzip.Writer
+}
+
+// newGzipWriter は level の圧縮レベルで w に書き込む gzipWriter を作成します。
+// level は WithGzip で検証済みのため、gzip.NewWriterLevel は失敗しません。
+func newGzipWriter(w io.Writer, level int) *gzipWriter {
+	gz, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		gz = gzip.NewWriter(w)
+	}
+	return &gzipWriter{w: w, gz: gz}
+}
+
+// Write は p を圧縮して書き込み先に書き出します。
+func (w *gzipWriter) Write(p []byte) (int, error) {
+	n, err := w.gz.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("failed to compress log: %w", err)
+	}
+	if err := w.gz.Flush(); err != nil {
+		return n, fmt.Errorf("failed to flush compressed log: %w", err)
+	}
+	return n, nil
+}
+
+// Flush は圧縮中のデータを書き出し、書き込み先が Flush() error を実装している場合はそれも呼び出します。
+func (w *gzipWriter) Flush() error {
+	if err := w.gz.Flush(); err != nil {
+		return fmt.Errorf("failed to flush compressed log: %w", err)
+	}
+	if f, ok := w.w.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			return fmt.Errorf("failed to flush writer: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close は gzip のトレーラーを書き出し、書き込み先が Flush() error を実装している場合はそれも呼び出します。
+// 書き込み先は呼び出し側が所有しているため閉じません。
+func (w *gzipWriter) Close() error {
+	if err := w.gz.Close(); err != nil {
+		return fmt.Errorf("failed to close gzip writer: %w", err)
+	}
+	if f, ok := w.w.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			return fmt.Errorf("failed to flush writer: %w", err)
+		}
+	}
+	return nil
+}
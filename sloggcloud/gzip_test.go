@@ -0,0 +1,84 @@
+package sloggcloud_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithGzip(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []sloggcloud.Option
+		wantWarn bool
+	}{
+		{
+			name:     "書き込みごとに圧縮",
+			opts:     []sloggcloud.Option{sloggcloud.WithGzip(gzip.BestSpeed)},
+			wantWarn: false,
+		},
+		{
+			name:     "バッファを書き出すごとに圧縮",
+			opts:     []sloggcloud.Option{sloggcloud.WithGzip(gzip.DefaultCompression), sloggcloud.WithBuffer(1024, 0)},
+			wantWarn: false,
+		},
+		{
+			name:     "不正な圧縮レベルの場合は警告してデフォルトの圧縮レベルを使用",
+			opts:     []sloggcloud.Option{sloggcloud.WithGzip(100)},
+			wantWarn: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warn bytes.Buffer
+			sloggcloud.SetWarnOutput(t, &warn)
+
+			var buf bytes.Buffer
+			handler := sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...)
+			logger := slog.New(handler)
+
+			messages := []string{"first", "second", "third"}
+			for _, msg := range messages {
+				logger.Info(msg, "key", "value")
+			}
+			if err := handler.Close(); err != nil {
+				t.Fatalf("failed to close handler: %v", err)
+			}
+
+			gr, err := gzip.NewReader(&buf)
+			if err != nil {
+				t.Fatalf("failed to create gzip reader: %v", err)
+			}
+			// トレーラーまで読み込めない場合は ReadAll がエラーを返す
+			data, err := io.ReadAll(gr)
+			if err != nil {
+				t.Fatalf("failed to decompress: %v", err)
+			}
+
+			var got []string
+			scanner := bufio.NewScanner(bytes.NewReader(data))
+			for scanner.Scan() {
+				var entry map[string]interface{}
+				if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+					t.Fatalf("line is not valid JSON: %v: %s", err, scanner.Text())
+				}
+				msg, _ := entry["message"].(string)
+				got = append(got, msg)
+			}
+			if diff := cmp.Diff(messages, got); diff != "" {
+				t.Errorf("messages mismatch (-want +got):\n%s", diff)
+			}
+			if gotWarn := warn.Len() > 0; gotWarn != tt.wantWarn {
+				t.Errorf("warned = %v, want %v: %s", gotWarn, tt.wantWarn, warn.String())
+			}
+		})
+	}
+}
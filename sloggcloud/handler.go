@@ -6,116 +6,504 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"runtime"
-
-	"go.opentelemetry.io/otel/trace"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
+// sourceLocationKey はソースコードの位置情報を出力するデフォルトのキーです。
+const sourceLocationKey = "logging.googleapis.com/sourceLocation"
+
+// severityKey は severity を出力するデフォルトのキーです。
+const severityKey = "severity"
+
 // Handler は Google Cloud Logging 用の slog.Handler 実装です。
 // Google Cloud Logging と互換性のある構造化フォーマットでログを出力します。
 // また、利用可能な場合は OpenTelemetry のトレース ID とスパン ID も含みます。
+// 同じ New から派生したハンドラは書き込みを排他制御するため、複数の goroutine から安全に利用できます。
 type Handler struct {
 	opts   *options
 	attrs  []slog.Attr
 	groups []string
-	w      io.Writer
+	// nestGroups は WithGroup で作成され、属性を groups の入れ子のグループで包む
+	nestGroups func([]slog.Attr) slog.Attr
+	w          io.Writer
+	// inner は実際にレコードを書き出すハンドラで、派生したハンドラ間で共有される
+	inner recordHandler
+	// errInner は WithErrorWriter を指定した場合に ERROR 以上のレコードを書き出すハンドラ
+	errInner recordHandler
+	// mu は派生したハンドラ間で共有され、w への書き込みを排他制御する
+	mu *sync.Mutex
+	// attrPool はレコードごとに属性を組み立てるスライスのプールで、派生したハンドラ間で共有される
+	attrPool *attrPool
+	// closers は WithBuffer や WithGzip で Handler 自身が作成した書き込み先で、Close で閉じる順に並ぶ
+	closers []io.Closer
 }
 
 var _ slog.Handler = (*Handler)(nil)
 
+// recordHandler は Handler が組み立てたレコードを書き込み先の形式に変換して出力します。
+type recordHandler interface {
+	Handle(ctx context.Context, r slog.Record) error
+}
+
 // New は Google Cloud Logging 用の新しい Handler を作成します。
+// WithProjectID を指定しない場合、Project ID は環境変数 GOOGLE_CLOUD_PROJECT、GCLOUD_PROJECT の順に取得されます。
+// w が nil の場合は os.Stderr に出力します。
+// WithStrict(true) を指定した場合、設定に誤りがあると panic します。
 func New(w io.Writer, opts ...Option) *Handler {
+	// 設定ミスで nil が渡されても、最初のログ出力時に panic させずにログを残せるようにする
+	if w == nil {
+		w = os.Stderr
+	}
+
 	o := defaultOptions()
 	for _, opt := range opts {
 		opt(o)
 	}
 
+	// WithSeverityMapper を後に指定しても個別の置き換えが失われないように、全てのオプションを適用してから組み合わせる
+	if len(o.levelSeverities) > 0 {
+		o.severityMapper = overrideSeverity(o.severityMapper, o.levelSeverities)
+	}
+
+	if o.projectID == "" {
+		o.projectID = projectIDFromEnv()
+	}
+	if o.projectID == "" && o.projectIDFromMetadata {
+		// ローカル環境などでは取得に失敗するため、エラーは無視して Project ID なしで動作させる
+		if projectID, err := projectIDFromMetadata(context.Background()); err == nil {
+			o.projectID = projectID
+		}
+	}
+	// 明示的に指定したリソースを優先し、判定にはここまでで決まった Project ID を使用する
+	if o.resource == nil && o.autoResource {
+		o.resource = detectResource(context.Background(), o.projectID)
+	}
+	// Project ID を環境から取得した後に確認する
+	o.checkConfig()
+
+	// バッファを書き出す単位で圧縮するため、gzip はバッファの内側に置く
+	// Close では外側から順に閉じ、バッファの内容を gzip に書き出してからトレーラーを書き出す
+	var closers []io.Closer
+	if o.gzip {
+		gw := newGzipWriter(w, o.gzipLevel)
+		w = gw
+		closers = append(closers, gw)
+	}
+	if o.bufferSize > 0 {
+		bw := newBufferedWriter(w, o.bufferSize, o.bufferFlushInterval)
+		w = bw
+		closers = append([]io.Closer{bw}, closers...)
+	}
+
+	// 容量を指定した場合は、他の Handler のスライスと混ざらないように専用のプールを使う
+	pool := attrBufPool
+	if o.attrPoolCapacity > 0 {
+		pool = newAttrPool(o.attrPoolCapacity)
+	}
+
+	var errInner recordHandler
+	if o.errorWriter != nil {
+		errInner = newRecordHandler(o.errorWriter, o)
+	}
+
 	return &Handler{
-		opts: o,
-		w:    w,
+		opts:     o,
+		attrs:    o.initialAttrs,
+		w:        w,
+		inner:    newRecordHandler(w, o),
+		errInner: errInner,
+		mu:       &sync.Mutex{},
+		attrPool: pool,
+		closers:  closers,
+	}
+}
+
+// newRecordHandler はオプションに応じてコンソール形式または JSON 形式で出力するハンドラを作成します。
+func newRecordHandler(w io.Writer, o *options) recordHandler {
+	if o.console {
+		return newConsoleHandler(w, o)
+	}
+	return newJSONHandler(w, o)
+}
+
+// newJSONHandler は Google Cloud Logging の形式で出力する slog.JSONHandler を作成します。
+func newJSONHandler(w io.Writer, o *options) *slog.JSONHandler {
+	replaceAttr := cloudLoggingReplaceAttr(o)
+	if o.replaceAttr != nil {
+		internal := replaceAttr
+		// 利用者の関数には Cloud Logging の形式に変換した後の属性を渡す
+		replaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			return o.replaceAttr(groups, internal(groups, a))
+		}
+	}
+	if o.maxMessageBytes > 0 || o.maxEntryBytes > 0 || o.entrySizeHook != nil {
+		w = newTruncatingWriter(w, o)
+	}
+	// 1 つのレコードが必ず 1 行の JSON として書き込まれるように、最も外側で行に整える
+	w = newLineWriter(w)
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: o.leveler(),
+		// 利用者の関数が返した値も JSON に変換できない可能性があるため、最後に置き換える
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			return serializableAttr(replaceAttr(groups, a))
+		},
+	})
+}
+
+// cloudLoggingReplaceAttr は slog.JSONHandler が出力する属性を Cloud Logging の形式に変換する関数を返します。
+func cloudLoggingReplaceAttr(o *options) func([]string, slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		// slog.JSONHandler の組み込みのフィールドはトップレベルにのみ出力されるため、グループの中の同じキーの属性は変換しない
+		// トップレベルのユーザーの属性は payloadAttrs でリネームされ、ここには組み込みのフィールドだけが渡される
+		key := a.Key
+		if len(groups) > 0 {
+			key = ""
+		}
+		switch key {
+		// levelをseverityに変換
+		case slog.LevelKey:
+			if level, ok := a.Value.Any().(slog.Level); ok {
+				return slog.String(o.severityKey, o.severityMapper(level))
+			}
+		// Cloud Logging は message キーをログの表示テキストとして扱う
+		case slog.MessageKey:
+			return slog.Attr{Key: o.messageKey, Value: a.Value}
+		case slog.TimeKey:
+			if a.Value.Kind() == slog.KindTime && (o.timeKey != slog.TimeKey || o.timeFormat != time.RFC3339Nano) {
+				return slog.String(o.timeKey, a.Value.Time().Format(o.timeFormat))
+			}
+		}
+		// 属性の時刻もエントリの時刻と同じ形式にそろえる。slog.JSONHandler は time.RFC3339Nano で出力するため、それ以外の場合のみ変換する
+		if a.Value.Kind() == slog.KindTime && o.timeFormat != time.RFC3339Nano {
+			return slog.String(a.Key, a.Value.Time().Format(o.timeFormat))
+		}
+		// slog.JSONHandler はナノ秒の整数で出力し Logs Explorer で読みにくいため、Google Cloud の形式に揃える
+		if a.Value.Kind() == slog.KindDuration {
+			return formatDurationAttr(a, o.durationFormat)
+		}
+		return a
 	}
 }
 
 // Enabled は指定されたレベルのレコードをハンドラが処理するかどうかを報告します。
-func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
-	return level >= h.opts.level
+// コンテキストに ContextWithLevel でレベルが設定されている場合はそのレベルで、
+// 最初のグループに WithGroupLevel でレベルが設定されている場合はそのレベルで判定します。
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	if ctxLevel, ok := levelFromContext(ctx); ok {
+		return level >= ctxLevel
+	}
+	if len(h.groups) > 0 {
+		if groupLevel, ok := h.opts.groupLevels[h.groups[0]]; ok {
+			return level >= groupLevel
+		}
+	}
+	return level >= h.opts.leveler().Level()
 }
 
 // Handle はレコードを処理します。
 func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
-	logger := slog.New(slog.NewJSONHandler(h.w, &slog.HandlerOptions{
-		Level: h.opts.level,
-		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			// levelをseverityに変換
-			if a.Key == slog.LevelKey {
-				return slog.String("severity", levelToSeverity(r.Level))
-			}
-			return a
-		},
-	}))
+	// 流量制限も出力する時刻を基準にするため、最初に置き換える
+	r.Time = h.recordTime(r.Time)
 
-	attrs := make([]slog.Attr, 0)
+	if h.opts.sampler != nil && !h.opts.sampler(ctx, r) {
+		return nil
+	}
 
-	if h.opts.addSource {
-		var frame runtime.Frame
-		pc := r.PC
-		if pc != 0 {
-			frames := runtime.CallersFrames([]uintptr{pc})
-			frame, _ = frames.Next()
-			attrs = append(attrs,
-				slog.Group("logging.googleapis.com/sourceLocation",
-					slog.String("file", frame.File),
-					slog.Int("line", frame.Line),
-					slog.String("function", frame.Function),
-				),
-			)
+	if h.opts.rateLimiter != nil {
+		allowed, suppressed := h.opts.rateLimiter.allow(r.Time)
+		if suppressed > 0 {
+			if err := h.handleSuppressed(ctx, r.Time, suppressed); err != nil {
+				return err
+			}
 		}
+		if !allowed {
+			return nil
+		}
+	}
+
+	return h.handle(ctx, r)
+}
+
+// handle はサンプリングや流量制限を行わずにレコードを出力します。
+// 属性は WithAttrs (WithInitialAttrs を含む)、コンテキスト、WithDefaultAttrsFunc、スパン、レコードの順に出力し、
+// その後に Cloud Logging が特別に扱うフィールドを出力します。
+// ハンドラの派生のさせ方や高速な経路を通るかどうかに関わらず同じ順序になるため、出力をそのまま比較できます。
+func (h *Handler) handle(ctx context.Context, r slog.Record) error {
+	if h.opts.spanEventOnError && r.Level >= slog.LevelError {
+		h.recordSpanEvent(ctx, r)
+	}
+	if h.canUseFastPath(ctx, r) {
+		return h.handleFast(ctx, r)
 	}
 
-	span := trace.SpanFromContext(ctx)
-	if span.SpanContext().IsValid() {
-		traceID := span.SpanContext().TraceID()
-		spanID := span.SpanContext().SpanID()
+	// 属性を集めるスライスはプールから借り、書き込みが終わってから戻す
+	buf := h.attrPool.get(len(h.attrs) + r.NumAttrs())
+	attrs := *buf
 
-		// Google Cloud Logging の要件に従ってトレース ID をフォーマット
-		var traceIDStr string
-		if h.opts.projectID != "" {
-			traceIDStr = fmt.Sprintf("projects/%s/traces/%s", h.opts.projectID, traceID.String())
-		} else {
-			traceIDStr = traceID.String()
+	// httpRequest は Cloud Logging が特別に扱うフィールドのため、他の属性とは分けて出力する
+	var httpReq *HTTPRequest
+	var panicStackTrace *slog.Attr
+	var appendAttr func(attr slog.Attr)
+	appendAttr = func(attr slog.Attr) {
+		// Recover が付与したスタックトレースは LogValuer を解決する前に取り出し、トップレベルに出力する
+		if stackTrace, ok := panicStackAttr(attr); ok {
+			panicStackTrace = &stackTrace
+			return
+		}
+		attr = resolveAttr(attr)
+		// 名前のないグループの属性は 1 つずつ追加し、予約済みのキーのリネームや httpRequest の判定の対象にする
+		if isInlineGroup(attr) {
+			for _, ga := range attr.Value.Group() {
+				appendAttr(ga)
+			}
+			return
+		}
+		attr = errorAttr(attr, h.opts.errorUnwrap)
+		attr = binaryAttr(attr, h.opts.binaryFormat)
+		if h.opts.largeIntAsString {
+			attr = largeIntAttr(attr)
+		}
+		if len(h.opts.redactKeys) > 0 {
+			attr = redactAttr(attr, h.opts.redactKeys)
+		}
+		if req, ok := httpRequestFromAttr(attr); ok {
+			httpReq = req
+			return
+		}
+		// トレース ID などを置き換えると Cloud Logging で関連付けられなくなるため、予約済みのフィールドは対象にしない
+		if len(h.opts.valueScrubbers) > 0 && !strings.HasPrefix(attr.Key, reservedKeyNamespace) {
+			attr = scrubAttr(attr, h.opts.valueScrubbers)
+		}
+		if h.opts.fieldAllowlist != nil {
+			if _, ok := h.opts.fieldAllowlist[attr.Key]; !ok {
+				return
+			}
+		}
+		attrs = append(attrs, attr)
+	}
+	for _, attr := range h.attrs {
+		appendAttr(attr)
+	}
+	for _, attr := range AttrsFromContext(ctx) {
+		appendAttr(attr)
+	}
+	if h.opts.defaultAttrsFunc != nil {
+		defaultAttrs, _ := callContextFunc(ctx, h.opts.contextFuncTimeout, h.opts.defaultAttrsFunc)
+		for _, attr := range defaultAttrs {
+			appendAttr(attr)
+		}
+	}
+	if len(h.opts.spanAttributeKeys) > 0 {
+		for _, attr := range h.spanAttrs(ctx) {
+			appendAttr(attr)
 		}
+	}
+	r.Attrs(func(attr slog.Attr) bool {
+		appendAttr(attr)
+		return true
+	})
+	// 容量が足りずに確保し直した場合も、大きくなったスライスをプールに戻す
+	*buf = attrs
+	defer h.attrPool.put(buf)
 
-		attrs = append(attrs,
-			slog.String("logging.googleapis.com/trace", traceIDStr),
-			slog.String("logging.googleapis.com/spanId", spanID.String()),
-		)
+	// グループで入れ子にするのはユーザーの属性のみ
+	if h.nestGroups != nil {
+		attrs = []slog.Attr{h.nestGroups(attrs)}
 	}
+	if h.opts.maxAttrDepth > 0 {
+		attrs = truncateAttrDepth(attrs, h.opts.maxAttrDepth)
+	}
+	if h.opts.flattenSeparator != "" {
+		attrs = flattenGroups(attrs, h.opts.flattenSeparator)
+	}
+	attrs = h.payloadAttrs(attrs)
+
+	topLevel := h.topLevelAttrs(ctx, r, attrs, httpReq, panicStackTrace)
+
+	message := r.Message
+	if len(h.opts.valueScrubbers) > 0 {
+		message = scrubString(message, h.opts.valueScrubbers)
+	}
+	record := slog.NewRecord(r.Time, r.Level, message, r.PC)
+	record.AddAttrs(attrs...)
+	record.AddAttrs(topLevel...)
+	return h.write(ctx, record)
+}
 
-	if len(h.attrs) > 0 {
-		attrs = append(attrs, h.attrs...)
+// canUseFastPath はレコードの属性を変換せずにそのまま出力できるかどうかを返します。
+// グループや WithAttrs、コンテキストの属性がなく、レコードの属性がすべて解決や変換の不要な値で予約済みのキーとも衝突しない場合が対象です。
+func (h *Handler) canUseFastPath(ctx context.Context, r slog.Record) bool {
+	if len(h.groups) > 0 || len(h.attrs) > 0 || len(AttrsFromContext(ctx)) > 0 || h.opts.defaultAttrsFunc != nil ||
+		len(h.opts.spanAttributeKeys) > 0 || len(h.opts.redactKeys) > 0 ||
+		h.opts.payloadMode != PayloadModeFlat || h.opts.contentHashInsertID || h.opts.largeIntAsString ||
+		h.opts.fieldAllowlist != nil || len(h.opts.valueScrubbers) > 0 {
+		return false
 	}
 
+	fast := true
 	r.Attrs(func(attr slog.Attr) bool {
-		attrs = append(attrs, attr)
+		switch attr.Value.Kind() {
+		case slog.KindAny, slog.KindLogValuer, slog.KindGroup:
+			fast = false
+		case slog.KindBool, slog.KindDuration, slog.KindFloat64, slog.KindInt64, slog.KindString, slog.KindTime, slog.KindUint64:
+			fast = !h.isReservedKey(attr.Key)
+		}
+		return fast
+	})
+	return fast
+}
+
+// handleFast は canUseFastPath を満たすレコードを、属性を組み立て直さずに出力します。
+// トップレベルに出力するフィールドがない場合はレコードをそのまま書き込むため、追加のメモリ確保が発生しません。
+func (h *Handler) handleFast(ctx context.Context, r slog.Record) error {
+	topLevel := h.topLevelAttrs(ctx, r, nil, nil, nil)
+	if len(topLevel) == 0 {
+		return h.write(ctx, r)
+	}
+
+	record := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(attr slog.Attr) bool {
+		record.AddAttrs(attr)
 		return true
 	})
+	record.AddAttrs(topLevel...)
+	return h.write(ctx, record)
+}
 
-	if len(h.groups) > 0 {
-		var values []any
-		for _, attr := range attrs {
-			values = append(values, attr)
+// topLevelAttrs は Cloud Logging が特別に扱うフィールドを返します。
+// Cloud Logging はトップレベルのフィールドしか認識しないため、これらはグループの外に出力します。
+func (h *Handler) topLevelAttrs(ctx context.Context, r slog.Record, attrs []slog.Attr, httpReq *HTTPRequest, panicStackTrace *slog.Attr) []slog.Attr {
+	var topLevel []slog.Attr
+	topLevel = append(topLevel, h.sourceAttrs(r)...)
+	// 属性でトレース情報を明示的に指定した場合は、キーが重複しないようにスパンから取得しない
+	if h.opts.addTraceInfo && !hasTraceAttr(attrs) {
+		topLevel = append(topLevel, h.traceAttrs(ctx)...)
+	}
+	if labels := h.labels(ctx); len(labels) > 0 {
+		topLevel = append(topLevel, h.labelsAttrs(labels)...)
+	}
+	if h.opts.resource != nil {
+		topLevel = append(topLevel, h.opts.resource.attr())
+	}
+	if h.opts.logName != "" {
+		topLevel = append(topLevel, slog.String(logNameKey, h.opts.logName))
+	}
+	if h.opts.insertIDFunc != nil {
+		if insertID := h.opts.insertIDFunc(ctx, r); insertID != "" {
+			topLevel = append(topLevel, slog.String(insertIDKey, insertID))
+		}
+	} else if h.opts.contentHashInsertID {
+		topLevel = append(topLevel, slog.String(insertIDKey, contentHashInsertID(h.opts.severityMapper(r.Level), r.Message, attrs)))
+	}
+	if httpReq != nil {
+		topLevel = append(topLevel, httpReq.attr())
+	}
+	if op, ok := operationFromContext(ctx); ok {
+		topLevel = append(topLevel, op.attr())
+	}
+	topLevel = append(topLevel, h.errorReportingAttrs(r)...)
+	if panicStackTrace != nil {
+		topLevel = append(topLevel, *panicStackTrace)
+	} else if stackTrace, ok := h.stackTraceAttr(r); ok {
+		topLevel = append(topLevel, stackTrace)
+	}
+	return topLevel
+}
+
+// write はレコードのレベルに応じた書き込み先にレコードを出力します。
+// WithOnError と WithMetricsHook の関数は、排他制御を解除してから呼び出します。
+func (h *Handler) write(ctx context.Context, r slog.Record) error {
+	if err := h.writeRecord(ctx, r); err != nil {
+		if h.opts.onError != nil {
+			h.opts.onError(err)
 		}
-		groupedAttrs := values
-		for i := len(h.groups) - 1; i >= 0; i-- {
-			groupedAttrs = []any{slog.Group(h.groups[i], groupedAttrs...)}
+		return err
+	}
+	if h.opts.metricsHook != nil {
+		h.opts.metricsHook(h.opts.severityMapper(r.Level))
+	}
+	return nil
+}
+
+func (h *Handler) writeRecord(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	inner := h.inner
+	if h.errInner != nil && r.Level >= slog.LevelError {
+		inner = h.errInner
+	}
+	// slog は Handler の返すエラーを呼び出し元に伝えないが、Handler を直接利用する場合に書き込みの失敗を検知できるようにする
+	if err := inner.Handle(ctx, r); err != nil {
+		return fmt.Errorf("failed to write log: %w", err)
+	}
+	return nil
+}
+
+// flusher はバッファリングされたデータを書き出す Flush メソッドを持つ io.Writer です。
+type flusher interface {
+	Flush() error
+}
+
+// Flush は書き込み先が Flush() error を実装している場合に、バッファリングされたログを書き出します。
+// WithErrorWriter を指定した場合は、その書き込み先も対象になります。
+// WithRateLimit を指定した場合は、書き出す前にまだ出力していない破棄したレコードの件数を出力します。
+func (h *Handler) Flush() error {
+	// 集計の出力は書き込み先のロックを取得するため、ロックを取得する前に行う
+	if err := h.flushSuppressed(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.flush()
+}
+
+// Close はバッファリングされたログを書き出した後、WithBuffer や WithGzip で Handler が作成した書き込み先を閉じます。
+// New に渡した書き込み先と WithErrorWriter の書き込み先は Flush() error を呼び出すだけで閉じないため、ファイルなどは呼び出し側で閉じてください。
+// os.Stderr などを閉じて以降のログや panic の出力が失われることはないため、プロセスの終了前に defer で呼び出してください。
+// 同じ New から派生したハンドラは書き込み先を共有するため、Close はいずれか 1 つに対して呼び出せば十分です。
+func (h *Handler) Close() error {
+	if err := h.flushSuppressed(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.flush(); err != nil {
+		return err
+	}
+	for _, c := range h.closers {
+		if err := c.Close(); err != nil {
+			return fmt.Errorf("failed to close writer: %w", err)
 		}
-		attrs = []slog.Attr{groupedAttrs[0].(slog.Attr)}
 	}
+	return nil
+}
 
-	logger.LogAttrs(ctx, r.Level, r.Message, attrs...)
+func (h *Handler) flush() error {
+	for _, w := range h.writers() {
+		if f, ok := w.(flusher); ok {
+			if err := f.Flush(); err != nil {
+				return fmt.Errorf("failed to flush writer: %w", err)
+			}
+		}
+	}
 	return nil
 }
 
+// writers はハンドラの書き込み先の一覧を返します。
+func (h *Handler) writers() []io.Writer {
+	if h.opts.errorWriter == nil {
+		return []io.Writer{h.w}
+	}
+	return []io.Writer{h.w, h.opts.errorWriter}
+}
+
 // WithAttrs は指定された属性を持つ新しい Handler を返します。
 func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	if len(attrs) == 0 {
@@ -123,7 +511,8 @@ func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	}
 
 	h2 := *h
-	h2.attrs = append(h2.attrs, attrs...)
+	// 親ハンドラと底の配列を共有しないように容量を切り詰めてから追加する
+	h2.attrs = append(slices.Clip(h.attrs), attrs...)
 	return &h2
 }
 
@@ -134,19 +523,24 @@ func (h *Handler) WithGroup(name string) slog.Handler {
 	}
 
 	h2 := *h
-	h2.groups = append(h2.groups, name)
+	h2.groups = append(slices.Clip(h.groups), name)
+	h2.nestGroups = newGroupNester(h2.groups)
 	return &h2
 }
 
-func levelToSeverity(level slog.Level) string {
-	switch {
-	case level >= slog.LevelError:
-		return "ERROR"
-	case level >= slog.LevelWarn && level < slog.LevelError:
-		return "WARNING"
-	case level >= slog.LevelInfo && level < slog.LevelWarn:
-		return "INFO"
-	default:
-		return "DEBUG"
+// newGroupNester は属性を groups の入れ子のグループで包む関数を返します。
+// グループの連なりは WithGroup の時点で決まるため、外側から内側への順序を反転した一覧をここで作っておき、
+// Handle ではレコードごとの属性を包むだけで済むようにします。
+func newGroupNester(groups []string) func([]slog.Attr) slog.Attr {
+	inner := groups[len(groups)-1]
+	outers := slices.Clone(groups[:len(groups)-1])
+	slices.Reverse(outers)
+
+	return func(attrs []slog.Attr) slog.Attr {
+		grouped := slog.Attr{Key: inner, Value: slog.GroupValue(attrs...)}
+		for _, name := range outers {
+			grouped = slog.Attr{Key: name, Value: slog.GroupValue(grouped)}
+		}
+		return grouped
 	}
 }
@@ -1,17 +1,257 @@
 package sloggcloud_test
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/p1ass/go-pkg/sloggcloud"
 	"go.opentelemetry.io/otel/trace"
 )
 
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		opts      []sloggcloud.Option
+		wantTrace string
+	}{
+		{
+			name:      "GOOGLE_CLOUD_PROJECTからProject IDを取得",
+			env:       map[string]string{"GOOGLE_CLOUD_PROJECT": "env-project", "GCLOUD_PROJECT": ""},
+			opts:      []sloggcloud.Option{},
+			wantTrace: "projects/env-project/traces/01020304050607080102030405060708",
+		},
+		{
+			name:      "GCLOUD_PROJECTからProject IDを取得",
+			env:       map[string]string{"GOOGLE_CLOUD_PROJECT": "", "GCLOUD_PROJECT": "gcloud-project"},
+			opts:      []sloggcloud.Option{},
+			wantTrace: "projects/gcloud-project/traces/01020304050607080102030405060708",
+		},
+		{
+			name:      "GOOGLE_CLOUD_PROJECTをGCLOUD_PROJECTより優先",
+			env:       map[string]string{"GOOGLE_CLOUD_PROJECT": "env-project", "GCLOUD_PROJECT": "gcloud-project"},
+			opts:      []sloggcloud.Option{},
+			wantTrace: "projects/env-project/traces/01020304050607080102030405060708",
+		},
+		{
+			name:      "WithProjectIDを環境変数より優先",
+			env:       map[string]string{"GOOGLE_CLOUD_PROJECT": "env-project", "GCLOUD_PROJECT": "gcloud-project"},
+			opts:      []sloggcloud.Option{sloggcloud.WithProjectID("explicit-project")},
+			wantTrace: "projects/explicit-project/traces/01020304050607080102030405060708",
+		},
+		{
+			name:      "環境変数がない場合はProject IDなし",
+			env:       map[string]string{"GOOGLE_CLOUD_PROJECT": "", "GCLOUD_PROJECT": ""},
+			opts:      []sloggcloud.Option{},
+			wantTrace: "01020304050607080102030405060708",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			var buf bytes.Buffer
+			opts := append([]sloggcloud.Option{sloggcloud.WithSource(false)}, tt.opts...)
+			logger := slog.New(sloggcloud.New(&buf, opts...))
+
+			traceID, _ := trace.TraceIDFromHex("01020304050607080102030405060708")
+			spanID, _ := trace.SpanIDFromHex("0102030405060708")
+			ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID: traceID,
+				SpanID:  spanID,
+			}))
+			logger.InfoContext(ctx, "message with project id")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if got["logging.googleapis.com/trace"] != tt.wantTrace {
+				t.Errorf("trace = %v, want %v", got["logging.googleapis.com/trace"], tt.wantTrace)
+			}
+		})
+	}
+}
+
+func TestNew_nilWriter(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	t.Cleanup(func() { os.Stderr = stderr })
+
+	logger := slog.New(sloggcloud.New(nil, sloggcloud.WithSource(false)))
+	logger.Info("message with nil writer")
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close pipe: %v", err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to read stderr: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if got["message"] != "message with nil writer" {
+		t.Errorf("message = %v, want %v", got["message"], "message with nil writer")
+	}
+}
+
+type tenantKey struct{}
+
+func TestHandler_Enabled(t *testing.T) {
+	levelVar := &slog.LevelVar{}
+	levelVar.Set(slog.LevelInfo)
+
+	tests := []struct {
+		name  string
+		opts  []sloggcloud.Option
+		setup func()
+		level slog.Level
+		want  bool
+	}{
+		{
+			name:  "最小レベル以上のログを処理",
+			opts:  []sloggcloud.Option{},
+			setup: func() {},
+			level: slog.LevelInfo,
+			want:  true,
+		},
+		{
+			name:  "最小レベル未満のログを処理しない",
+			opts:  []sloggcloud.Option{},
+			setup: func() {},
+			level: slog.LevelDebug,
+			want:  false,
+		},
+		{
+			name:  "LevelVarがINFOの場合はDEBUGを処理しない",
+			opts:  []sloggcloud.Option{sloggcloud.WithLevelVar(levelVar)},
+			setup: func() { levelVar.Set(slog.LevelInfo) },
+			level: slog.LevelDebug,
+			want:  false,
+		},
+		{
+			name:  "LevelVarをDEBUGに変更するとDEBUGを処理",
+			opts:  []sloggcloud.Option{sloggcloud.WithLevelVar(levelVar)},
+			setup: func() { levelVar.Set(slog.LevelDebug) },
+			level: slog.LevelDebug,
+			want:  true,
+		},
+		{
+			name:  "WithLevelよりLevelVarを優先",
+			opts:  []sloggcloud.Option{sloggcloud.WithLevel(slog.LevelError), sloggcloud.WithLevelVar(levelVar)},
+			setup: func() { levelVar.Set(slog.LevelDebug) },
+			level: slog.LevelInfo,
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := sloggcloud.New(io.Discard, tt.opts...)
+			tt.setup()
+
+			if got := handler.Enabled(context.Background(), tt.level); got != tt.want {
+				t.Errorf("Enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("実行中にLevelVarを変更すると同じハンドラの判定が変わる", func(t *testing.T) {
+		levelVar := &slog.LevelVar{}
+		handler := sloggcloud.New(io.Discard, sloggcloud.WithLevelVar(levelVar))
+
+		if handler.Enabled(context.Background(), slog.LevelDebug) {
+			t.Error("Enabled(DEBUG) = true before changing LevelVar, want false")
+		}
+		levelVar.Set(slog.LevelDebug)
+		if !handler.Enabled(context.Background(), slog.LevelDebug) {
+			t.Error("Enabled(DEBUG) = false after changing LevelVar, want true")
+		}
+	})
+}
+
+func TestWithGroupLevel(t *testing.T) {
+	var buf bytes.Buffer
+	handler := sloggcloud.New(&buf,
+		sloggcloud.WithGroupLevel("db", slog.LevelDebug),
+		sloggcloud.WithGroupLevel("noisy", slog.LevelWarn),
+		sloggcloud.WithSource(false),
+	)
+	root := slog.New(handler)
+
+	tests := []struct {
+		name   string
+		logger *slog.Logger
+		level  slog.Level
+		want   bool
+	}{
+		{name: "ルートはINFOのまま", logger: root, level: slog.LevelDebug, want: false},
+		{name: "ルートのINFOは出力", logger: root, level: slog.LevelInfo, want: true},
+		{name: "dbグループはDEBUGを出力", logger: root.WithGroup("db"), level: slog.LevelDebug, want: true},
+		{name: "dbから始まるグループにも適用", logger: root.WithGroup("db").WithGroup("query"), level: slog.LevelDebug, want: true},
+		{name: "dbで始まらないグループには適用しない", logger: root.WithGroup("api").WithGroup("db"), level: slog.LevelDebug, want: false},
+		{name: "レベルを上げたグループはINFOを出力しない", logger: root.WithGroup("noisy"), level: slog.LevelInfo, want: false},
+		{name: "属性を追加してもグループのレベルを維持", logger: root.WithGroup("db").With("key", "value"), level: slog.LevelDebug, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.logger.Enabled(context.Background(), tt.level); got != tt.want {
+				t.Errorf("Enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("dbグループのDEBUGログのみ出力", func(t *testing.T) {
+		buf.Reset()
+		root.Debug("root debug")
+		root.WithGroup("db").Debug("db debug", "query", "SELECT 1")
+
+		var got map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		delete(got, "time")
+
+		want := map[string]interface{}{
+			"severity": "DEBUG",
+			"message":  "db debug",
+			"db": map[string]interface{}{
+				"query": "SELECT 1",
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
 func TestHandler_Handle(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -34,7 +274,7 @@ func TestHandler_Handle(t *testing.T) {
 			},
 			want: map[string]interface{}{
 				"severity": "INFO",
-				"msg":      "test message",
+				"message":  "test message",
 				"key":      "value",
 			},
 			wantSourceLocation: false,
@@ -50,7 +290,7 @@ func TestHandler_Handle(t *testing.T) {
 			},
 			want: map[string]interface{}{
 				"severity": "INFO",
-				"msg":      "message with source",
+				"message":  "message with source",
 				"code":     float64(500),
 			},
 			wantSourceLocation: true,
@@ -75,10 +315,210 @@ func TestHandler_Handle(t *testing.T) {
 				return trace.ContextWithSpanContext(context.Background(), spanCtx)
 			},
 			want: map[string]interface{}{
-				"severity":                      "INFO",
-				"msg":                           "message with project ID",
-				"logging.googleapis.com/trace":  "projects/test-project/traces/01020304050607080102030405060708",
-				"logging.googleapis.com/spanId": "0102030405060708",
+				"severity":                             "INFO",
+				"message":                              "message with project ID",
+				"logging.googleapis.com/trace":         "projects/test-project/traces/01020304050607080102030405060708",
+				"logging.googleapis.com/spanId":        "0102030405060708",
+				"logging.googleapis.com/trace_sampled": true,
+			},
+			wantSourceLocation: false,
+		},
+		{
+			name:    "サンプリングされていないトレース情報付きのログ",
+			level:   slog.LevelInfo,
+			message: "message with unsampled trace",
+			args:    []slog.Attr{},
+			opts: []sloggcloud.Option{
+				sloggcloud.WithProjectID("test-project"),
+				sloggcloud.WithSource(false),
+			},
+			setupTrace: func() context.Context {
+				traceID, _ := trace.TraceIDFromHex("01020304050607080102030405060708")
+				spanID, _ := trace.SpanIDFromHex("0102030405060708")
+				spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID: traceID,
+					SpanID:  spanID,
+				})
+				return trace.ContextWithSpanContext(context.Background(), spanCtx)
+			},
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "message with unsampled trace",
+				"logging.googleapis.com/trace":         "projects/test-project/traces/01020304050607080102030405060708",
+				"logging.googleapis.com/spanId":        "0102030405060708",
+				"logging.googleapis.com/trace_sampled": false,
+			},
+			wantSourceLocation: false,
+		},
+		{
+			name:    "トレース情報の出力を無効化したログ",
+			level:   slog.LevelInfo,
+			message: "message without trace info",
+			args:    []slog.Attr{},
+			opts: []sloggcloud.Option{
+				sloggcloud.WithProjectID("test-project"),
+				sloggcloud.WithTraceInfo(false),
+				sloggcloud.WithSource(false),
+			},
+			setupTrace: func() context.Context {
+				traceID, _ := trace.TraceIDFromHex("01020304050607080102030405060708")
+				spanID, _ := trace.SpanIDFromHex("0102030405060708")
+				spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID:    traceID,
+					SpanID:     spanID,
+					TraceFlags: trace.FlagsSampled,
+				})
+				return trace.ContextWithSpanContext(context.Background(), spanCtx)
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message without trace info",
+			},
+			wantSourceLocation: false,
+		},
+		{
+			name:    "メッセージがmessageキーに出力される",
+			level:   slog.LevelInfo,
+			message: "hello",
+			args:    []slog.Attr{},
+			opts:    []sloggcloud.Option{sloggcloud.WithSource(false)},
+			setupTrace: func() context.Context {
+				return context.Background()
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "hello",
+			},
+			wantSourceLocation: false,
+		},
+		{
+			name:    "メッセージキーを変更したログ",
+			level:   slog.LevelInfo,
+			message: "hello",
+			args:    []slog.Attr{},
+			opts: []sloggcloud.Option{
+				sloggcloud.WithMessageKey("msg"),
+				sloggcloud.WithSource(false),
+			},
+			setupTrace: func() context.Context {
+				return context.Background()
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"msg":      "hello",
+			},
+			wantSourceLocation: false,
+		},
+		{
+			name:    "プログラム名付きのログ",
+			level:   slog.LevelInfo,
+			message: "message with program",
+			args:    []slog.Attr{},
+			opts: []sloggcloud.Option{
+				sloggcloud.WithProgram("test-program"),
+				sloggcloud.WithSource(false),
+			},
+			setupTrace: func() context.Context {
+				return context.Background()
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message with program",
+				"logging.googleapis.com/labels": map[string]interface{}{
+					"program": "test-program",
+				},
+			},
+			wantSourceLocation: false,
+		},
+		{
+			name:    "プログラム名が空の場合はラベルを出力しない",
+			level:   slog.LevelInfo,
+			message: "message without program",
+			args:    []slog.Attr{},
+			opts: []sloggcloud.Option{
+				sloggcloud.WithProgram(""),
+				sloggcloud.WithSource(false),
+			},
+			setupTrace: func() context.Context {
+				return context.Background()
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message without program",
+			},
+			wantSourceLocation: false,
+		},
+		{
+			name:    "ラベル付きのログ",
+			level:   slog.LevelInfo,
+			message: "message with labels",
+			args:    []slog.Attr{},
+			opts: []sloggcloud.Option{
+				sloggcloud.WithLabels(map[string]string{"env": "prod", "team": "backend"}),
+				sloggcloud.WithSource(false),
+			},
+			setupTrace: func() context.Context {
+				return context.Background()
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message with labels",
+				"logging.googleapis.com/labels": map[string]interface{}{
+					"env":  "prod",
+					"team": "backend",
+				},
+			},
+			wantSourceLocation: false,
+		},
+		{
+			name:    "ラベルとプログラム名を併用したログ",
+			level:   slog.LevelInfo,
+			message: "message with labels and program",
+			args:    []slog.Attr{},
+			opts: []sloggcloud.Option{
+				sloggcloud.WithLabels(map[string]string{"env": "prod"}),
+				sloggcloud.WithProgram("test-program"),
+				sloggcloud.WithSource(false),
+			},
+			setupTrace: func() context.Context {
+				return context.Background()
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message with labels and program",
+				"logging.googleapis.com/labels": map[string]interface{}{
+					"env":     "prod",
+					"program": "test-program",
+				},
+			},
+			wantSourceLocation: false,
+		},
+		{
+			name:    "コンテキストから取得したラベル付きのログ",
+			level:   slog.LevelInfo,
+			message: "message with context labels",
+			args:    []slog.Attr{},
+			opts: []sloggcloud.Option{
+				sloggcloud.WithLabels(map[string]string{"env": "prod", "tenant": "default"}),
+				sloggcloud.WithLabelsFromContext(func(ctx context.Context) map[string]string {
+					tenant, ok := ctx.Value(tenantKey{}).(string)
+					if !ok {
+						return nil
+					}
+					return map[string]string{"tenant": tenant}
+				}),
+				sloggcloud.WithSource(false),
+			},
+			setupTrace: func() context.Context {
+				return context.WithValue(context.Background(), tenantKey{}, "tenant-a")
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message with context labels",
+				"logging.googleapis.com/labels": map[string]interface{}{
+					"env":    "prod",
+					"tenant": "tenant-a",
+				},
 			},
 			wantSourceLocation: false,
 		},
@@ -97,7 +537,7 @@ func TestHandler_Handle(t *testing.T) {
 			},
 			want: map[string]interface{}{
 				"severity": "DEBUG",
-				"msg":      "debug message",
+				"message":  "debug message",
 				"key":      "value",
 			},
 			wantSourceLocation: false,
@@ -113,7 +553,7 @@ func TestHandler_Handle(t *testing.T) {
 			},
 			want: map[string]interface{}{
 				"severity": "WARNING",
-				"msg":      "warning message",
+				"message":  "warning message",
 				"key":      "value",
 			},
 			wantSourceLocation: false,
@@ -129,58 +569,825 @@ func TestHandler_Handle(t *testing.T) {
 			},
 			want: map[string]interface{}{
 				"severity": "ERROR",
-				"msg":      "error message",
+				"message":  "error message",
 				"key":      "value",
 			},
 			wantSourceLocation: false,
 		},
+		{
+			name:    "CRITICALレベルのログ",
+			level:   sloggcloud.LevelCritical,
+			message: "db down",
+			args:    []slog.Attr{},
+			opts:    []sloggcloud.Option{sloggcloud.WithSource(false)},
+			setupTrace: func() context.Context {
+				return context.Background()
+			},
+			want: map[string]interface{}{
+				"severity": "CRITICAL",
+				"message":  "db down",
+			},
+			wantSourceLocation: false,
+		},
+		{
+			name:    "独自のseverity変換を設定したログ",
+			level:   slog.Level(42),
+			message: "custom level message",
+			args:    []slog.Attr{},
+			opts: []sloggcloud.Option{
+				sloggcloud.WithSeverityMapper(func(level slog.Level) string {
+					if level == slog.Level(42) {
+						return "ALERT"
+					}
+					return "DEFAULT"
+				}),
+				sloggcloud.WithSource(false),
+			},
+			setupTrace: func() context.Context {
+				return context.Background()
+			},
+			want: map[string]interface{}{
+				"severity": "ALERT",
+				"message":  "custom level message",
+			},
+			wantSourceLocation: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := sloggcloud.New(&buf, tt.opts...)
+			logger := slog.New(handler)
+
+			ctx := tt.setupTrace()
+			logger.LogAttrs(ctx, tt.level, tt.message, tt.args...)
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+
+			// time フィールドの検証
+			if _, ok := got["time"].(string); !ok {
+				t.Error("time field is not a string")
+			}
+			delete(got, "time")
+
+			// ソース位置情報の検証
+			if sourceLocation, ok := got["logging.googleapis.com/sourceLocation"].(map[string]interface{}); ok {
+				if !tt.wantSourceLocation {
+					t.Error("unexpected source location information is included")
+				}
+				t.Log(sourceLocation)
+				if _, ok := sourceLocation["file"].(string); !ok {
+					t.Error("sourceLocation.file is not a string")
+				}
+				if _, ok := sourceLocation["line"].(float64); !ok {
+					t.Error("sourceLocation.line is not a number")
+				}
+				if _, ok := sourceLocation["function"].(string); !ok {
+					t.Error("sourceLocation.function is not a string")
+				}
+				delete(got, "logging.googleapis.com/sourceLocation")
+			} else if tt.wantSourceLocation {
+				t.Error("source location information is missing")
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_output(t *testing.T) {
+	var buf bytes.Buffer
+	handler := sloggcloud.New(&buf, sloggcloud.WithSource(false))
+
+	r := slog.NewRecord(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), slog.LevelInfo, "hello", 0)
+	r.AddAttrs(slog.String("key", "value"), slog.Int("code", 200))
+	for range 2 {
+		if err := handler.Handle(context.Background(), r); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+
+	line := `{"time":"2024-01-01T12:00:00Z","severity":"INFO","message":"hello","key":"value","code":200}` + "\n"
+	want := line + line
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHandler_Handle_fastPath(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("01020304050607080102030405060708")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	spanCtx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	pc, _, _, _ := runtime.Caller(0)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		opts []sloggcloud.Option
+		pc   uintptr
+	}{
+		{
+			name: "トップレベルのフィールドがない場合",
+			ctx:  context.Background(),
+			opts: []sloggcloud.Option{sloggcloud.WithSource(false)},
+			pc:   0,
+		},
+		{
+			name: "ソース情報とトレース情報がある場合",
+			ctx:  spanCtx,
+			opts: []sloggcloud.Option{sloggcloud.WithProjectID("test-project")},
+			pc:   pc,
+		},
+		{
+			name: "ラベルとError Reportingがある場合",
+			ctx:  context.Background(),
+			opts: []sloggcloud.Option{
+				sloggcloud.WithSource(false),
+				sloggcloud.WithLabels(map[string]string{"env": "prod"}),
+				sloggcloud.WithErrorReporting("test-service", "v1.0.0"),
+			},
+			pc: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := slog.NewRecord(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), slog.LevelError, "hello", tt.pc)
+			r.AddAttrs(
+				slog.String("key", "value"),
+				slog.Int("code", 200),
+				slog.Bool("ok", false),
+				slog.Duration("elapsed", 1500*time.Millisecond),
+			)
+
+			var fast, general bytes.Buffer
+			if err := sloggcloud.New(&fast, tt.opts...).Handle(tt.ctx, r); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			// 存在しないキーを WithRedactKeys に指定して、属性を組み立て直す通常の経路を通す
+			generalOpts := append(slices.Clip(tt.opts), sloggcloud.WithRedactKeys("password"))
+			if err := sloggcloud.New(&general, generalOpts...).Handle(tt.ctx, r); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			if diff := cmp.Diff(general.String(), fast.String()); diff != "" {
+				t.Errorf("fast path output mismatch (-general +fast):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithReplaceAttr(t *testing.T) {
+	tests := []struct {
+		name    string
+		replace func(groups []string, a slog.Attr) slog.Attr
+		want    map[string]interface{}
+	}{
+		{
+			name: "文字列の値を大文字に変換",
+			replace: func(_ []string, a slog.Attr) slog.Attr {
+				if a.Value.Kind() == slog.KindString {
+					return slog.String(a.Key, strings.ToUpper(a.Value.String()))
+				}
+				return a
+			},
+			want: map[string]interface{}{
+				"severity": "WARNING",
+				"message":  "HELLO",
+				"key":      "VALUE",
+				"code":     float64(200),
+				"group": map[string]interface{}{
+					"nested": "VALUE",
+				},
+			},
+		},
+		{
+			name: "severityへの変換後に呼び出す",
+			replace: func(_ []string, a slog.Attr) slog.Attr {
+				// slog の level のままであれば "WARN" になる
+				if a.Key == "severity" {
+					return slog.String("severity", "custom-"+a.Value.String())
+				}
+				return a
+			},
+			want: map[string]interface{}{
+				"severity": "custom-WARNING",
+				"message":  "hello",
+				"key":      "value",
+				"code":     float64(200),
+				"group": map[string]interface{}{
+					"nested": "value",
+				},
+			},
+		},
+		{
+			name: "空のキーを返した属性は出力しない",
+			replace: func(groups []string, a slog.Attr) slog.Attr {
+				if len(groups) > 0 || a.Key == "code" {
+					return slog.Attr{}
+				}
+				return a
+			},
+			want: map[string]interface{}{
+				"severity": "WARNING",
+				"message":  "hello",
+				"key":      "value",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf,
+				sloggcloud.WithReplaceAttr(tt.replace),
+				sloggcloud.WithSource(false),
+			))
+
+			logger.Warn("hello", "key", "value", "code", 200, slog.Group("group", "nested", "value"))
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if _, ok := got["time"].(string); !ok {
+				t.Errorf("time field is not a string: %v", got["time"])
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithSourceKey(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+
+	tests := []struct {
+		name     string
+		opts     []sloggcloud.Option
+		args     []any
+		wantKey  string
+		wantFile string
+		wantAttr map[string]interface{}
+	}{
+		{
+			name:     "デフォルトではCloud Loggingのキーにフルパスを出力",
+			opts:     nil,
+			wantKey:  "logging.googleapis.com/sourceLocation",
+			wantFile: file,
+			wantAttr: map[string]interface{}{},
+		},
+		{
+			name:     "キーを変更",
+			opts:     []sloggcloud.Option{sloggcloud.WithSourceKey("source")},
+			wantKey:  "source",
+			wantFile: file,
+			wantAttr: map[string]interface{}{},
+		},
+		{
+			name:     "空文字列の場合はデフォルトのキー",
+			opts:     []sloggcloud.Option{sloggcloud.WithSourceKey("")},
+			wantKey:  "logging.googleapis.com/sourceLocation",
+			wantFile: file,
+			wantAttr: map[string]interface{}{},
+		},
+		{
+			name:     "ファイル名のみに短縮",
+			opts:     []sloggcloud.Option{sloggcloud.WithSourceShortFile(true)},
+			wantKey:  "logging.googleapis.com/sourceLocation",
+			wantFile: "handler_test.go",
+			wantAttr: map[string]interface{}{},
+		},
+		{
+			name:     "キーの変更とファイル名の短縮",
+			opts:     []sloggcloud.Option{sloggcloud.WithSourceKey("source"), sloggcloud.WithSourceShortFile(true)},
+			wantKey:  "source",
+			wantFile: "handler_test.go",
+			wantAttr: map[string]interface{}{},
+		},
+		{
+			name:     "変更したキーと衝突する属性は名前を変えて出力",
+			opts:     []sloggcloud.Option{sloggcloud.WithSourceKey("source")},
+			args:     []any{"source", "user"},
+			wantKey:  "source",
+			wantFile: file,
+			wantAttr: map[string]interface{}{"attr_source": "user"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append([]sloggcloud.Option{sloggcloud.WithSource(true)}, tt.opts...)...))
+
+			logger.Info("source", tt.args...)
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			source, ok := got[tt.wantKey].(map[string]interface{})
+			if !ok {
+				t.Fatalf("%s is not an object: %v", tt.wantKey, got)
+			}
+			if diff := cmp.Diff(tt.wantFile, source["file"]); diff != "" {
+				t.Errorf("file mismatch (-want +got):\n%s", diff)
+			}
+
+			for _, key := range []string{tt.wantKey, "time", "severity", "message"} {
+				delete(got, key)
+			}
+			if diff := cmp.Diff(tt.wantAttr, got); diff != "" {
+				t.Errorf("attrs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_time(t *testing.T) {
+	recordTime := time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC)
+
+	tests := []struct {
+		name       string
+		opts       []sloggcloud.Option
+		wantKey    string
+		wantLayout string
+	}{
+		{
+			name:       "デフォルトはtimeキーにRFC3339Nanoで出力",
+			opts:       []sloggcloud.Option{},
+			wantKey:    "time",
+			wantLayout: time.RFC3339Nano,
+		},
+		{
+			name: "timestampキーにマイクロ秒精度で出力",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithTimeKey("timestamp"),
+				sloggcloud.WithTimeFormat("2006-01-02T15:04:05.000000Z07:00"),
+			},
+			wantKey:    "timestamp",
+			wantLayout: "2006-01-02T15:04:05.000000Z07:00",
+		},
+		{
+			name: "キーのみ変更",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithTimeKey("timestamp"),
+			},
+			wantKey:    "timestamp",
+			wantLayout: time.RFC3339Nano,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := append([]sloggcloud.Option{sloggcloud.WithSource(false)}, tt.opts...)
+			handler := sloggcloud.New(&buf, opts...)
+
+			r := slog.NewRecord(recordTime, slog.LevelInfo, "message with time", 0)
+			r.AddAttrs(slog.Time("deadline", recordTime), slog.Group("req", slog.Time("started_at", recordTime)))
+			if err := handler.Handle(context.Background(), r); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+
+			gotTime, ok := got[tt.wantKey].(string)
+			if !ok {
+				t.Fatalf("%s field is not a string: %v", tt.wantKey, got)
+			}
+			if want := recordTime.Format(tt.wantLayout); gotTime != want {
+				t.Errorf("%s = %v, want %v", tt.wantKey, gotTime, want)
+			}
+			if _, err := time.Parse(tt.wantLayout, gotTime); err != nil {
+				t.Errorf("failed to parse %s with layout %s: %v", gotTime, tt.wantLayout, err)
+			}
+			// 属性の時刻もエントリの時刻と同じ形式で出力する
+			if got["deadline"] != gotTime {
+				t.Errorf("deadline = %v, want %v", got["deadline"], gotTime)
+			}
+			if req, _ := got["req"].(map[string]interface{}); req["started_at"] != gotTime {
+				t.Errorf("req.started_at = %v, want %v", req["started_at"], gotTime)
+			}
+			if tt.wantKey != "time" {
+				if _, ok := got["time"]; ok {
+					t.Error("time field should be renamed")
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_errorWriter(t *testing.T) {
+	tests := []struct {
+		name       string
+		level      slog.Level
+		wantOut    bool
+		wantErrOut bool
+	}{
+		{name: "INFOは標準の書き込み先に出力", level: slog.LevelInfo, wantOut: true, wantErrOut: false},
+		{name: "WARNは標準の書き込み先に出力", level: slog.LevelWarn, wantOut: true, wantErrOut: false},
+		{name: "ERRORはエラー用の書き込み先に出力", level: slog.LevelError, wantOut: false, wantErrOut: true},
+		{name: "CRITICALはエラー用の書き込み先に出力", level: sloggcloud.LevelCritical, wantOut: false, wantErrOut: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			logger := slog.New(sloggcloud.New(&out,
+				sloggcloud.WithErrorWriter(&errOut),
+				sloggcloud.WithSource(false),
+			))
+
+			logger.Log(context.Background(), tt.level, "message")
+
+			if got := out.Len() > 0; got != tt.wantOut {
+				t.Errorf("output written = %v, want %v", got, tt.wantOut)
+			}
+			if got := errOut.Len() > 0; got != tt.wantErrOut {
+				t.Errorf("error output written = %v, want %v", got, tt.wantErrOut)
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_concurrent(t *testing.T) {
+	const (
+		goroutines = 50
+		messages   = 100
+	)
+
+	var buf bytes.Buffer
+	handler := sloggcloud.New(&buf, sloggcloud.WithSource(false))
+
+	var wg sync.WaitGroup
+	for i := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 派生したハンドラからも同じ書き込み先へ安全に書き込めることを確認する
+			logger := slog.New(handler.WithAttrs([]slog.Attr{slog.Int("goroutine", i)}))
+			for j := range messages {
+				logger.Info("concurrent message", "index", j)
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != goroutines*messages {
+		t.Fatalf("got %d lines, want %d", len(lines), goroutines*messages)
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("invalid JSON line: %s", line)
+		}
+	}
+}
+
+// errWriter は常にエラーを返す io.Writer です。
+type errWriter struct {
+	err error
+}
+
+func (w errWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+func TestHandler_Handle_writeError(t *testing.T) {
+	errWrite := errors.New("broken pipe")
+
+	tests := []struct {
+		name    string
+		opts    []sloggcloud.Option
+		wantErr error
+	}{
+		{
+			name:    "JSON出力で書き込みに失敗した場合はエラーを返す",
+			opts:    []sloggcloud.Option{},
+			wantErr: errWrite,
+		},
+		{
+			name:    "コンソール出力で書き込みに失敗した場合はエラーを返す",
+			opts:    []sloggcloud.Option{sloggcloud.WithConsole(true)},
+			wantErr: errWrite,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := sloggcloud.New(errWriter{err: errWrite}, tt.opts...)
+			record := slog.NewRecord(time.Now(), slog.LevelInfo, "hello", 0)
+
+			err := handler.Handle(context.Background(), record)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Handle() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("書き込みに成功した場合はnilを返す", func(t *testing.T) {
+		handler := sloggcloud.New(io.Discard)
+		record := slog.NewRecord(time.Now(), slog.LevelInfo, "hello", 0)
+
+		if err := handler.Handle(context.Background(), record); err != nil {
+			t.Errorf("Handle() error = %v, want nil", err)
+		}
+	})
+}
+
+func TestWithOnError(t *testing.T) {
+	errWrite := errors.New("broken pipe")
+
+	tests := []struct {
+		name    string
+		w       io.Writer
+		wantErr error
+	}{
+		{
+			name:    "書き込みに失敗した場合はエラーを渡して呼び出す",
+			w:       errWriter{err: errWrite},
+			wantErr: errWrite,
+		},
+		{
+			name:    "書き込みに成功した場合は呼び出さない",
+			w:       io.Discard,
+			wantErr: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []error
+			logger := slog.New(sloggcloud.New(tt.w, sloggcloud.WithOnError(func(err error) {
+				got = append(got, err)
+			})))
+
+			logger.Info("hello")
+
+			if tt.wantErr == nil {
+				if len(got) != 0 {
+					t.Errorf("onError called with %v, want no call", got)
+				}
+				return
+			}
+			if len(got) != 1 || !errors.Is(got[0], tt.wantErr) {
+				t.Errorf("onError called with %v, want %v", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithMetricsHook(t *testing.T) {
+	var got []string
+	logger := slog.New(sloggcloud.New(io.Discard,
+		sloggcloud.WithLevel(slog.LevelInfo),
+		sloggcloud.WithSampler(func(_ context.Context, r slog.Record) bool {
+			return r.Message != "sampled out"
+		}),
+		sloggcloud.WithMetricsHook(func(severity string) {
+			got = append(got, severity)
+		}),
+	))
+
+	logger.Debug("filtered by level")
+	logger.Info("info")
+	logger.Warn("warn")
+	logger.Info("sampled out")
+	logger.Error("error")
+	logger.Log(context.Background(), slog.LevelError+4, "critical")
+
+	want := []string{"INFO", "WARNING", "ERROR", "CRITICAL"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("severities mismatch (-want +got):\n%s", diff)
+	}
+}
+
+type secret string
+
+func (s secret) LogValue() slog.Value {
+	return slog.StringValue("********")
+}
+
+type user struct {
+	name     string
+	password secret
+}
+
+func (u user) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", u.name),
+		slog.Any("password", u.password),
+	)
+}
+
+func TestHandler_Handle_logValuer(t *testing.T) {
+	var buf bytes.Buffer
+	handler := sloggcloud.New(&buf, sloggcloud.WithSource(false))
+	logger := slog.New(handler.WithAttrs([]slog.Attr{slog.Any("token", secret("handler-token"))}).WithGroup("request"))
+
+	logger.Info("message with log valuer",
+		slog.Any("user", user{name: "alice", password: "p@ssw0rd"}),
+		slog.Group("auth", slog.Any("token", secret("record-token"))),
+	)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	delete(got, "time")
+
+	want := map[string]interface{}{
+		"severity": "INFO",
+		"message":  "message with log valuer",
+		"request": map[string]interface{}{
+			"token": "********",
+			"user": map[string]interface{}{
+				"name":     "alice",
+				"password": "********",
+			},
+			"auth": map[string]interface{}{
+				"token": "********",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// recordingWriter は Flush と Close の呼び出しを記録する io.Writer です。
+type recordingWriter struct {
+	bytes.Buffer
+	flushed  bool
+	closed   bool
+	flushErr error
+	closeErr error
+}
+
+func (w *recordingWriter) Flush() error {
+	w.flushed = true
+	return w.flushErr
+}
+
+func (w *recordingWriter) Close() error {
+	w.closed = true
+	return w.closeErr
+}
+
+func TestHandler_Flush(t *testing.T) {
+	errFlush := errors.New("flush error")
+
+	tests := []struct {
+		name        string
+		w           *recordingWriter
+		wantFlushed bool
+		wantErr     error
+	}{
+		{
+			name:        "書き込み先のFlushを呼び出す",
+			w:           &recordingWriter{},
+			wantFlushed: true,
+			wantErr:     nil,
+		},
+		{
+			name:        "Flushのエラーを返す",
+			w:           &recordingWriter{flushErr: errFlush},
+			wantFlushed: true,
+			wantErr:     errFlush,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := sloggcloud.New(tt.w)
+
+			err := handler.Flush()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Flush() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.w.flushed != tt.wantFlushed {
+				t.Errorf("flushed = %v, want %v", tt.w.flushed, tt.wantFlushed)
+			}
+			if tt.w.closed {
+				t.Error("Flush() should not close the writer")
+			}
+		})
+	}
+
+	t.Run("bufio.Writerにバッファリングされたログを書き出す", func(t *testing.T) {
+		var buf bytes.Buffer
+		bw := bufio.NewWriter(&buf)
+		logger := slog.New(sloggcloud.New(bw, sloggcloud.WithSource(false)))
+
+		logger.Info("buffered message")
+		if buf.Len() != 0 {
+			t.Fatalf("log should be buffered before Flush: %s", buf.String())
+		}
+
+		if err := logger.Handler().(*sloggcloud.Handler).Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		if !strings.Contains(buf.String(), "buffered message") {
+			t.Errorf("log was not flushed: %s", buf.String())
+		}
+	})
+}
+
+func TestHandler_Close(t *testing.T) {
+	errFlush := errors.New("flush error")
+	errClose := errors.New("close error")
+
+	tests := []struct {
+		name        string
+		w           *recordingWriter
+		wantFlushed bool
+		wantClosed  bool
+		wantErr     error
+	}{
+		{
+			name:        "書き込み先をFlushするが閉じない",
+			w:           &recordingWriter{},
+			wantFlushed: true,
+			wantClosed:  false,
+			wantErr:     nil,
+		},
+		{
+			name:        "Flushに失敗した場合は閉じない",
+			w:           &recordingWriter{flushErr: errFlush},
+			wantFlushed: true,
+			wantClosed:  false,
+			wantErr:     errFlush,
+		},
+		{
+			name:        "書き込み先のCloseを呼び出さないためエラーにならない",
+			w:           &recordingWriter{closeErr: errClose},
+			wantFlushed: true,
+			wantClosed:  false,
+			wantErr:     nil,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var buf bytes.Buffer
-			handler := sloggcloud.New(&buf, tt.opts...)
-			logger := slog.New(handler)
-
-			ctx := tt.setupTrace()
-			logger.LogAttrs(ctx, tt.level, tt.message, tt.args...)
-
-			var got map[string]interface{}
-			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
-				t.Fatalf("failed to parse JSON: %v", err)
-			}
+			handler := sloggcloud.New(tt.w)
 
-			// time フィールドの検証
-			if _, ok := got["time"].(string); !ok {
-				t.Error("time field is not a string")
+			err := handler.Close()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Close() error = %v, want %v", err, tt.wantErr)
 			}
-			delete(got, "time")
-
-			// ソース位置情報の検証
-			if sourceLocation, ok := got["logging.googleapis.com/sourceLocation"].(map[string]interface{}); ok {
-				if !tt.wantSourceLocation {
-					t.Error("unexpected source location information is included")
-				}
-				t.Log(sourceLocation)
-				if _, ok := sourceLocation["file"].(string); !ok {
-					t.Error("sourceLocation.file is not a string")
-				}
-				if _, ok := sourceLocation["line"].(float64); !ok {
-					t.Error("sourceLocation.line is not a number")
-				}
-				if _, ok := sourceLocation["function"].(string); !ok {
-					t.Error("sourceLocation.function is not a string")
-				}
-				delete(got, "logging.googleapis.com/sourceLocation")
-			} else if tt.wantSourceLocation {
-				t.Error("source location information is missing")
+			if tt.w.flushed != tt.wantFlushed {
+				t.Errorf("flushed = %v, want %v", tt.w.flushed, tt.wantFlushed)
 			}
-
-			if diff := cmp.Diff(tt.want, got); diff != "" {
-				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			if tt.w.closed != tt.wantClosed {
+				t.Errorf("closed = %v, want %v", tt.w.closed, tt.wantClosed)
 			}
 		})
 	}
+
+	t.Run("Close後も呼び出し側のファイルに書き込める", func(t *testing.T) {
+		f, err := os.Create(filepath.Join(t.TempDir(), "log"))
+		if err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+		t.Cleanup(func() { _ = f.Close() })
+
+		// WithBuffer と WithGzip で作成した書き込み先だけを閉じる
+		handler := sloggcloud.New(f, sloggcloud.WithBuffer(4096, time.Hour), sloggcloud.WithGzip(gzip.DefaultCompression))
+		slog.New(handler).Info("before close")
+		if err := handler.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		if _, err := fmt.Fprintln(f, "after close"); err != nil {
+			t.Errorf("writer is not usable after Close: %v", err)
+		}
+	})
+
+	t.Run("nilを渡した場合もos.Stderrを閉じない", func(t *testing.T) {
+		if err := sloggcloud.New(nil).Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if _, err := os.Stderr.Stat(); err != nil {
+			t.Errorf("os.Stderr is not usable after Close: %v", err)
+		}
+	})
 }
 
 func TestHandler_WithAttrs(t *testing.T) {
@@ -207,7 +1414,7 @@ func TestHandler_WithAttrs(t *testing.T) {
 			},
 			want: map[string]interface{}{
 				"severity": "INFO",
-				"msg":      "message with attrs",
+				"message":  "message with attrs",
 				"service":  "test-service",
 			},
 			wantSourceLocation: false,
@@ -227,7 +1434,7 @@ func TestHandler_WithAttrs(t *testing.T) {
 			},
 			want: map[string]interface{}{
 				"severity":    "INFO",
-				"msg":         "message with multiple attrs",
+				"message":     "message with multiple attrs",
 				"service":     "test-service",
 				"version":     float64(1),
 				"environment": "test",
@@ -263,6 +1470,236 @@ func TestHandler_WithAttrs(t *testing.T) {
 	}
 }
 
+func TestHandler_WithAttrs_siblings(t *testing.T) {
+	var buf bytes.Buffer
+	// 属性を段階的に追加して、スライスに余剰の容量がある状態のハンドラを作る
+	base := sloggcloud.New(&buf, sloggcloud.WithSource(false)).
+		WithAttrs([]slog.Attr{slog.String("a", "1")}).
+		WithAttrs([]slog.Attr{slog.String("b", "2")}).
+		WithAttrs([]slog.Attr{slog.String("c", "3")})
+
+	first := slog.New(base.WithAttrs([]slog.Attr{slog.String("first", "x")}))
+	second := slog.New(base.WithAttrs([]slog.Attr{slog.String("second", "y")}))
+
+	first.Info("first message")
+	second.Info("second message")
+
+	want := []map[string]interface{}{
+		{
+			"severity": "INFO",
+			"message":  "first message",
+			"a":        "1",
+			"b":        "2",
+			"c":        "3",
+			"first":    "x",
+		},
+		{
+			"severity": "INFO",
+			"message":  "second message",
+			"a":        "1",
+			"b":        "2",
+			"c":        "3",
+			"second":   "y",
+		},
+	}
+
+	dec := json.NewDecoder(&buf)
+	for i, w := range want {
+		var got map[string]interface{}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("failed to parse JSON line %d: %v", i, err)
+		}
+		delete(got, "time")
+		if diff := cmp.Diff(w, got); diff != "" {
+			t.Errorf("output mismatch at line %d (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+func TestHandler_Handle_attrOrder(t *testing.T) {
+	ctxWithAttrs := sloggcloud.ContextWithAttrs(context.Background(), slog.String("a", "1"))
+
+	tests := []struct {
+		name string
+		opts []sloggcloud.Option
+		// deriveA と deriveB は異なる方法で属性 a, b, c を出力するハンドラとコンテキスト、レコードの属性を返します
+		deriveA func(h slog.Handler) (slog.Handler, context.Context, []slog.Attr)
+		deriveB func(h slog.Handler) (slog.Handler, context.Context, []slog.Attr)
+	}{
+		{
+			name: "WithAttrsを分けて呼び出した場合とまとめて呼び出した場合",
+			deriveA: func(h slog.Handler) (slog.Handler, context.Context, []slog.Attr) {
+				h = h.WithAttrs([]slog.Attr{slog.String("a", "1")})
+				h = h.WithAttrs([]slog.Attr{slog.String("b", "2")})
+				return h, context.Background(), []slog.Attr{slog.String("c", "3")}
+			},
+			deriveB: func(h slog.Handler) (slog.Handler, context.Context, []slog.Attr) {
+				h = h.WithAttrs([]slog.Attr{slog.String("a", "1"), slog.String("b", "2")})
+				return h, context.Background(), []slog.Attr{slog.String("c", "3")}
+			},
+		},
+		{
+			name: "高速な経路と通常の経路",
+			opts: []sloggcloud.Option{sloggcloud.WithLabels(map[string]string{"env": "test"})},
+			deriveA: func(h slog.Handler) (slog.Handler, context.Context, []slog.Attr) {
+				return h, context.Background(), []slog.Attr{slog.String("a", "1"), slog.String("b", "2"), slog.String("c", "3")}
+			},
+			deriveB: func(h slog.Handler) (slog.Handler, context.Context, []slog.Attr) {
+				h = h.WithAttrs([]slog.Attr{slog.String("a", "1")})
+				return h, context.Background(), []slog.Attr{slog.String("b", "2"), slog.String("c", "3")}
+			},
+		},
+		{
+			name: "コンテキストの属性とWithAttrsの属性",
+			opts: []sloggcloud.Option{sloggcloud.WithLabels(map[string]string{"env": "test"})},
+			deriveA: func(h slog.Handler) (slog.Handler, context.Context, []slog.Attr) {
+				return h, ctxWithAttrs, []slog.Attr{slog.String("b", "2"), slog.String("c", "3")}
+			},
+			deriveB: func(h slog.Handler) (slog.Handler, context.Context, []slog.Attr) {
+				h = h.WithAttrs([]slog.Attr{slog.String("a", "1")})
+				return h, context.Background(), []slog.Attr{slog.String("b", "2"), slog.String("c", "3")}
+			},
+		},
+	}
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	output := func(t *testing.T, opts []sloggcloud.Option, derive func(h slog.Handler) (slog.Handler, context.Context, []slog.Attr)) string {
+		t.Helper()
+		var buf bytes.Buffer
+		h, ctx, attrs := derive(sloggcloud.New(&buf, append([]sloggcloud.Option{sloggcloud.WithSource(false)}, opts...)...))
+		r := slog.NewRecord(now, slog.LevelInfo, "ordered", 0)
+		r.AddAttrs(attrs...)
+		if err := h.Handle(ctx, r); err != nil {
+			t.Fatalf("failed to handle record: %v", err)
+		}
+		return buf.String()
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotA := output(t, tt.opts, tt.deriveA)
+			gotB := output(t, tt.opts, tt.deriveB)
+			if diff := cmp.Diff(gotA, gotB); diff != "" {
+				t.Errorf("output mismatch (-A +B):\n%s", diff)
+			}
+
+			// ユーザーの属性は a, b, c の順に、Cloud Logging のフィールドはその後に出力される
+			keys := []string{`"a":`, `"b":`, `"c":`}
+			if strings.Contains(gotA, "logging.googleapis.com/labels") {
+				keys = append(keys, `"logging.googleapis.com/labels":`)
+			}
+			last := -1
+			for _, key := range keys {
+				i := strings.Index(gotA, key)
+				if i <= last {
+					t.Errorf("key %s is out of order in %s", key, gotA)
+				}
+				last = i
+			}
+		})
+	}
+}
+
+func TestWithInitialAttrs(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []sloggcloud.Option
+		setup func(h *sloggcloud.Handler) slog.Handler
+		want  map[string]interface{}
+	}{
+		{
+			name: "最初のログに初期属性を出力",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithInitialAttrs(slog.String("service", "api"), slog.String("version", "v1.0.0")),
+			},
+			setup: func(h *sloggcloud.Handler) slog.Handler { return h },
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "initial attrs",
+				"service":  "api",
+				"version":  "v1.0.0",
+				"key":      "value",
+			},
+		},
+		{
+			name: "複数回指定した場合は追加",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithInitialAttrs(slog.String("service", "api")),
+				sloggcloud.WithInitialAttrs(slog.Int("pid", 1)),
+			},
+			setup: func(h *sloggcloud.Handler) slog.Handler { return h },
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "initial attrs",
+				"service":  "api",
+				"pid":      float64(1),
+				"key":      "value",
+			},
+		},
+		{
+			name: "WithAttrsで追加した属性と同じように扱う",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithInitialAttrs(slog.String("service", "api")),
+			},
+			setup: func(h *sloggcloud.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String("request_id", "r-1")})
+			},
+			want: map[string]interface{}{
+				"severity":   "INFO",
+				"message":    "initial attrs",
+				"service":    "api",
+				"request_id": "r-1",
+				"key":        "value",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...)
+			logger := slog.New(tt.setup(handler))
+
+			logger.Info("initial attrs", "key", "value")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithDefaultAttrsFunc(t *testing.T) {
+	var buf bytes.Buffer
+	count := 0
+	logger := slog.New(sloggcloud.New(&buf,
+		sloggcloud.WithSource(false),
+		sloggcloud.WithDefaultAttrsFunc(func(context.Context) []slog.Attr {
+			count++
+			return []slog.Attr{slog.Int("count", count)}
+		}),
+	))
+
+	logger.Info("first")
+	logger.With("user", "u-1").Info("second")
+	logger.WithGroup("req").Info("third", "id", "r-1")
+
+	want := []map[string]interface{}{
+		{"severity": "INFO", "message": "first", "count": float64(1)},
+		{"severity": "INFO", "message": "second", "user": "u-1", "count": float64(2)},
+		{"severity": "INFO", "message": "third", "req": map[string]interface{}{"count": float64(3), "id": "r-1"}},
+	}
+	if diff := cmp.Diff(want, decodeLines(t, &buf)); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestHandler_WithGroup(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -287,7 +1724,33 @@ func TestHandler_WithGroup(t *testing.T) {
 			opts: []sloggcloud.Option{sloggcloud.WithSource(false)},
 			want: map[string]interface{}{
 				"severity": "INFO",
-				"msg":      "message with group",
+				"message":  "message with group",
+				"server": map[string]interface{}{
+					"host": "example.com",
+				},
+			},
+			wantSourceLocation: false,
+		},
+		{
+			name:    "グループを指定してもラベルはトップレベルに出力",
+			level:   slog.LevelInfo,
+			message: "message with group and labels",
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h.WithGroup("server")
+			},
+			args: []slog.Attr{
+				slog.String("host", "example.com"),
+			},
+			opts: []sloggcloud.Option{
+				sloggcloud.WithLabels(map[string]string{"env": "prod"}),
+				sloggcloud.WithSource(false),
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message with group and labels",
+				"logging.googleapis.com/labels": map[string]interface{}{
+					"env": "prod",
+				},
 				"server": map[string]interface{}{
 					"host": "example.com",
 				},
@@ -307,7 +1770,7 @@ func TestHandler_WithGroup(t *testing.T) {
 			opts: []sloggcloud.Option{sloggcloud.WithSource(false)},
 			want: map[string]interface{}{
 				"severity": "INFO",
-				"msg":      "message with empty group",
+				"message":  "message with empty group",
 				"key":      "value",
 			},
 			wantSourceLocation: false,
@@ -326,7 +1789,7 @@ func TestHandler_WithGroup(t *testing.T) {
 			opts: []sloggcloud.Option{sloggcloud.WithSource(false)},
 			want: map[string]interface{}{
 				"severity": "INFO",
-				"msg":      "message with nested groups",
+				"message":  "message with nested groups",
 				"server": map[string]interface{}{
 					"network": map[string]interface{}{
 						"ip":   "192.168.1.1",
@@ -363,3 +1826,247 @@ func TestHandler_WithGroup(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_WithGroup_output(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs []slog.Attr
+		want  string
+	}{
+		{
+			name:  "3階層のグループに属性を出力",
+			attrs: []slog.Attr{slog.String("key", "value"), slog.Int("code", 200)},
+			want:  `{"time":"2024-01-01T12:00:00Z","severity":"INFO","message":"hello","server":{"network":{"conn":{"key":"value","code":200}}}}` + "\n",
+		},
+		{
+			name:  "グループ内の空のグループは出力しない",
+			attrs: []slog.Attr{slog.String("key", "value"), slog.Group("empty")},
+			want:  `{"time":"2024-01-01T12:00:00Z","severity":"INFO","message":"hello","server":{"network":{"conn":{"key":"value"}}}}` + "\n",
+		},
+		{
+			name:  "属性がない場合はグループを出力しない",
+			attrs: []slog.Attr{},
+			want:  `{"time":"2024-01-01T12:00:00Z","severity":"INFO","message":"hello"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := sloggcloud.New(&buf, sloggcloud.WithSource(false)).
+				WithGroup("server").WithGroup("network").WithGroup("conn")
+
+			r := slog.NewRecord(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), slog.LevelInfo, "hello", 0)
+			r.AddAttrs(tt.attrs...)
+			// 同じハンドラで複数回出力しても結果が変わらないことを確認する
+			for range 2 {
+				buf.Reset()
+				if err := handler.Handle(context.Background(), r); err != nil {
+					t.Fatalf("Handle() error = %v", err)
+				}
+				if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+					t.Errorf("output mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_WithGroup_reservedKeys(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("01020304050607080102030405060708")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), spanCtx)
+	ctx = sloggcloud.ContextWithOperation(ctx, sloggcloud.Operation{ID: "op-1", Producer: "", First: false, Last: false})
+
+	var buf bytes.Buffer
+	handler := sloggcloud.New(&buf,
+		sloggcloud.WithProjectID("test-project"),
+		sloggcloud.WithLabels(map[string]string{"env": "prod"}),
+	)
+	logger := slog.New(handler.WithGroup("server").WithGroup("network"))
+
+	logger.InfoContext(ctx, "message with group and trace",
+		slog.String("ip", "192.168.1.1"),
+		sloggcloud.HTTPRequestAttr(&sloggcloud.HTTPRequest{RequestMethod: "GET", Status: 200}),
+	)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	if _, ok := got["logging.googleapis.com/sourceLocation"].(map[string]interface{}); !ok {
+		t.Errorf("sourceLocation is not at the root: %v", got)
+	}
+	delete(got, "logging.googleapis.com/sourceLocation")
+	delete(got, "time")
+
+	want := map[string]interface{}{
+		"severity":                             "INFO",
+		"message":                              "message with group and trace",
+		"logging.googleapis.com/trace":         "projects/test-project/traces/01020304050607080102030405060708",
+		"logging.googleapis.com/spanId":        "0102030405060708",
+		"logging.googleapis.com/trace_sampled": true,
+		"logging.googleapis.com/labels": map[string]interface{}{
+			"env": "prod",
+		},
+		"logging.googleapis.com/operation": map[string]interface{}{
+			"id": "op-1",
+		},
+		"httpRequest": map[string]interface{}{
+			"requestMethod": "GET",
+			"status":        float64(200),
+		},
+		"server": map[string]interface{}{
+			"network": map[string]interface{}{
+				"ip": "192.168.1.1",
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHandler_Handle_emptyGroup(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []sloggcloud.Option
+		logger func(*slog.Logger) *slog.Logger
+		args   []any
+		want   map[string]interface{}
+	}{
+		{
+			name:   "名前のないグループの属性はルートに展開",
+			opts:   nil,
+			logger: func(l *slog.Logger) *slog.Logger { return l },
+			args:   []any{slog.Group("", slog.String("a", "b"))},
+			want:   map[string]interface{}{"a": "b"},
+		},
+		{
+			name:   "WithAttrsで追加した名前のないグループも展開",
+			opts:   nil,
+			logger: func(l *slog.Logger) *slog.Logger { return l.With(slog.Group("", slog.String("a", "b"))) },
+			args:   nil,
+			want:   map[string]interface{}{"a": "b"},
+		},
+		{
+			name:   "WithGroupのグループの中に展開",
+			opts:   nil,
+			logger: func(l *slog.Logger) *slog.Logger { return l.WithGroup("g") },
+			args:   []any{slog.Group("", slog.String("a", "b"))},
+			want:   map[string]interface{}{"g": map[string]interface{}{"a": "b"}},
+		},
+		{
+			name:   "予約済みのキーはリネーム",
+			opts:   nil,
+			logger: func(l *slog.Logger) *slog.Logger { return l },
+			args:   []any{slog.Group("", slog.String("severity", "custom"))},
+			want:   map[string]interface{}{"attr_severity": "custom"},
+		},
+		{
+			name:   "HTTPリクエストはトップレベルに出力",
+			opts:   nil,
+			logger: func(l *slog.Logger) *slog.Logger { return l },
+			args: []any{slog.Group("",
+				sloggcloud.HTTPRequestAttr(&sloggcloud.HTTPRequest{RequestMethod: "GET", Status: 200}),
+			)},
+			want: map[string]interface{}{"httpRequest": map[string]interface{}{"requestMethod": "GET", "status": float64(200)}},
+		},
+		{
+			name:   "入れ子の深さに数えない",
+			opts:   []sloggcloud.Option{sloggcloud.WithMaxAttrDepth(2)},
+			logger: func(l *slog.Logger) *slog.Logger { return l },
+			args:   []any{slog.Group("x", slog.Group("", slog.Group("y", slog.String("a", "b"))))},
+			want:   map[string]interface{}{"x": map[string]interface{}{"y": map[string]interface{}{"a": "b"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := tt.logger(slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...)))
+
+			logger.Info("hello", tt.args...)
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+			want := map[string]interface{}{"severity": "INFO", "message": "hello"}
+			for k, v := range tt.want {
+				want[k] = v
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func BenchmarkHandler_Handle(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		opts []sloggcloud.Option
+	}{
+		{
+			name: "属性を変換しない場合",
+			opts: []sloggcloud.Option{sloggcloud.WithSource(false)},
+		},
+		{
+			// WithRedactKeys を指定すると属性を組み立て直す通常の経路を通る
+			name: "属性を変換する場合",
+			opts: []sloggcloud.Option{sloggcloud.WithSource(false), sloggcloud.WithRedactKeys("password")},
+		},
+		{
+			// 属性の多いレコードでも、属性を集めるスライスはプールから再利用する
+			name: "属性が多い場合",
+			opts: []sloggcloud.Option{sloggcloud.WithSource(false), sloggcloud.WithInitialAttrs(
+				slog.String("service", "api"),
+				slog.String("env", "prod"),
+				slog.String("region", "asia-northeast1"),
+				slog.String("zone", "asia-northeast1-a"),
+				slog.String("instance", "i-1"),
+				slog.String("revision", "r-1"),
+			)},
+		},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			handler := sloggcloud.New(io.Discard, bm.opts...)
+			logger := slog.New(handler)
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				logger.LogAttrs(ctx, slog.LevelInfo, "benchmark message",
+					slog.String("key", "value"),
+					slog.Int("code", 200),
+				)
+			}
+		})
+	}
+}
+
+func BenchmarkHandler_WithGroup(b *testing.B) {
+	handler := sloggcloud.New(io.Discard, sloggcloud.WithSource(false))
+	logger := slog.New(handler.WithGroup("server").WithGroup("network").WithGroup("conn"))
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		logger.LogAttrs(ctx, slog.LevelInfo, "benchmark message",
+			slog.String("key", "value"),
+			slog.Int("code", 200),
+		)
+	}
+}
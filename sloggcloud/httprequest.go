@@ -0,0 +1,86 @@
+package sloggcloud
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// httpRequestKey は Cloud Logging の httpRequest フィールドを出力するキーです。
+const httpRequestKey = "httpRequest"
+
+// HTTPRequest は Cloud Logging の httpRequest フィールドに出力する HTTP リクエストの情報です。
+// ゼロ値のフィールドは出力されません。
+type HTTPRequest struct {
+	RequestMethod string
+	RequestURL    string
+	RequestSize   int64
+	Status        int
+	ResponseSize  int64
+	UserAgent     string
+	RemoteIP      string
+	ServerIP      string
+	Referer       string
+	Latency       time.Duration
+	Protocol      string
+}
+
+// HTTPRequestAttr は HTTPRequest を Cloud Logging の httpRequest フィールドとして出力する slog.Attr を返します。
+// グループの指定に関わらず、httpRequest はトップレベルに出力されます。
+func HTTPRequestAttr(req *HTTPRequest) slog.Attr {
+	return slog.Any(httpRequestKey, req)
+}
+
+// httpRequestFromAttr は slog.Attr が HTTPRequestAttr で作成されたものであれば HTTPRequest を返します。
+func httpRequestFromAttr(a slog.Attr) (*HTTPRequest, bool) {
+	if a.Key != httpRequestKey || a.Value.Kind() != slog.KindAny {
+		return nil, false
+	}
+	req, ok := a.Value.Any().(*HTTPRequest)
+	return req, ok && req != nil
+}
+
+// attr は HTTPRequest を Cloud Logging の httpRequest の形式の slog.Attr に変換します。
+func (r *HTTPRequest) attr() slog.Attr {
+	attrs := make([]slog.Attr, 0)
+	if r.RequestMethod != "" {
+		attrs = append(attrs, slog.String("requestMethod", r.RequestMethod))
+	}
+	if r.RequestURL != "" {
+		attrs = append(attrs, slog.String("requestUrl", r.RequestURL))
+	}
+	// LogEntry の int64 フィールドは JSON では文字列として表現される
+	if r.RequestSize != 0 {
+		attrs = append(attrs, slog.String("requestSize", strconv.FormatInt(r.RequestSize, 10)))
+	}
+	if r.Status != 0 {
+		attrs = append(attrs, slog.Int("status", r.Status))
+	}
+	if r.ResponseSize != 0 {
+		attrs = append(attrs, slog.String("responseSize", strconv.FormatInt(r.ResponseSize, 10)))
+	}
+	if r.UserAgent != "" {
+		attrs = append(attrs, slog.String("userAgent", r.UserAgent))
+	}
+	if r.RemoteIP != "" {
+		attrs = append(attrs, slog.String("remoteIp", r.RemoteIP))
+	}
+	if r.ServerIP != "" {
+		attrs = append(attrs, slog.String("serverIp", r.ServerIP))
+	}
+	if r.Referer != "" {
+		attrs = append(attrs, slog.String("referer", r.Referer))
+	}
+	if r.Latency != 0 {
+		attrs = append(attrs, slog.String("latency", formatDuration(r.Latency)))
+	}
+	if r.Protocol != "" {
+		attrs = append(attrs, slog.String("protocol", r.Protocol))
+	}
+	return slog.Attr{Key: httpRequestKey, Value: slog.GroupValue(attrs...)}
+}
+
+// formatDuration は time.Duration を "1.5s" のような Google Cloud の Duration 形式の文字列に変換します。
+func formatDuration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
@@ -0,0 +1,123 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestHTTPRequestAttr(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         *sloggcloud.HTTPRequest
+		setupGroups func(h *sloggcloud.Handler) slog.Handler
+		want        map[string]interface{}
+	}{
+		{
+			name: "メソッド・URL・ステータス・レイテンシを出力",
+			req: &sloggcloud.HTTPRequest{
+				RequestMethod: "GET",
+				RequestURL:    "https://example.com/foo?bar=baz",
+				Status:        200,
+				Latency:       123 * time.Millisecond,
+			},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "request",
+				"httpRequest": map[string]interface{}{
+					"requestMethod": "GET",
+					"requestUrl":    "https://example.com/foo?bar=baz",
+					"status":        float64(200),
+					"latency":       "0.123s",
+				},
+			},
+		},
+		{
+			name: "全てのフィールドを出力",
+			req: &sloggcloud.HTTPRequest{
+				RequestMethod: "POST",
+				RequestURL:    "/users",
+				RequestSize:   512,
+				Status:        201,
+				ResponseSize:  1024,
+				UserAgent:     "test-agent",
+				RemoteIP:      "192.0.2.1",
+				ServerIP:      "192.0.2.2",
+				Referer:       "https://example.com/",
+				Latency:       1500 * time.Millisecond,
+				Protocol:      "HTTP/1.1",
+			},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "request",
+				"httpRequest": map[string]interface{}{
+					"requestMethod": "POST",
+					"requestUrl":    "/users",
+					"requestSize":   "512",
+					"status":        float64(201),
+					"responseSize":  "1024",
+					"userAgent":     "test-agent",
+					"remoteIp":      "192.0.2.1",
+					"serverIp":      "192.0.2.2",
+					"referer":       "https://example.com/",
+					"latency":       "1.5s",
+					"protocol":      "HTTP/1.1",
+				},
+			},
+		},
+		{
+			name: "グループを指定してもトップレベルに出力",
+			req: &sloggcloud.HTTPRequest{
+				RequestMethod: "GET",
+				RequestURL:    "/",
+				Status:        404,
+				Latency:       2 * time.Second,
+			},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h.WithGroup("server")
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "request",
+				"httpRequest": map[string]interface{}{
+					"requestMethod": "GET",
+					"requestUrl":    "/",
+					"status":        float64(404),
+					"latency":       "2s",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := sloggcloud.New(&buf, sloggcloud.WithSource(false))
+			logger := slog.New(tt.setupGroups(handler))
+
+			logger.LogAttrs(context.Background(), slog.LevelInfo, "request", sloggcloud.HTTPRequestAttr(tt.req))
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
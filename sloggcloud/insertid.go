@@ -0,0 +1,64 @@
+package sloggcloud
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync/atomic"
+)
+
+// insertIDKey は Cloud Logging が重複排除に利用する insertId を出力するキーです。
+const insertIDKey = "logging.googleapis.com/insertId"
+
+// NewInsertIDFunc は WithInsertIDFunc に渡すための insertId を生成する関数を返します。
+// 生成される ID は関数ごとにランダムな接頭辞と単調増加するカウンタから構成されるため、
+// 同じ関数から生成された ID は辞書順に並びます。
+func NewInsertIDFunc() func(context.Context, slog.Record) string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	prefix := hex.EncodeToString(b[:])
+
+	var counter atomic.Uint64
+	return func(context.Context, slog.Record) string {
+		return fmt.Sprintf("%s-%020d", prefix, counter.Add(1))
+	}
+}
+
+// contentHashInsertID は severity・メッセージ・属性から決定的な insertId を生成します。
+// 時刻は含めないため、同じ内容のレコードを再送しても同じ ID となり Cloud Logging で重複排除されます。
+func contentHashInsertID(severity, message string, attrs []slog.Attr) string {
+	h := sha256.New()
+	writeHashString(h, severity)
+	writeHashString(h, message)
+	writeHashAttrs(h, attrs)
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// writeHashAttrs は属性の順序に依存しないように、キーでソートしてからハッシュに書き込みます。
+func writeHashAttrs(h hash.Hash, attrs []slog.Attr) {
+	sorted := slices.SortedStableFunc(slices.Values(attrs), func(a, b slog.Attr) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	for _, attr := range sorted {
+		writeHashString(h, attr.Key)
+		value := attr.Value.Resolve()
+		writeHashString(h, value.Kind().String())
+		if value.Kind() == slog.KindGroup {
+			writeHashAttrs(h, value.Group())
+			continue
+		}
+		writeHashString(h, value.String())
+	}
+}
+
+// writeHashString は文字列の区切りが曖昧にならないように長さを付けてハッシュに書き込みます。
+func writeHashString(h hash.Hash, s string) {
+	_, _ = h.Write([]byte(strconv.Itoa(len(s)) + ":" + s))
+}
@@ -0,0 +1,202 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithInsertIDFunc(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(context.Context, slog.Record) string
+		want map[string]interface{}
+	}{
+		{
+			name: "関数が値を返す場合はinsertIdを出力",
+			fn: func(context.Context, slog.Record) string {
+				return "insert-id-1"
+			},
+			want: map[string]interface{}{
+				"severity":                        "INFO",
+				"message":                         "message with insert id",
+				"logging.googleapis.com/insertId": "insert-id-1",
+			},
+		},
+		{
+			name: "関数が空文字列を返す場合はinsertIdを出力しない",
+			fn: func(context.Context, slog.Record) string {
+				return ""
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message with insert id",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf,
+				sloggcloud.WithInsertIDFunc(tt.fn),
+				sloggcloud.WithSource(false),
+			))
+
+			logger.Info("message with insert id")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewInsertIDFunc(t *testing.T) {
+	fn := sloggcloud.NewInsertIDFunc()
+
+	const n = 100
+	seen := make(map[string]struct{}, n)
+	prev := ""
+	for range n {
+		id := fn(context.Background(), slog.Record{})
+		if id == "" {
+			t.Fatal("NewInsertIDFunc() returned empty id")
+		}
+		if _, ok := seen[id]; ok {
+			t.Fatalf("NewInsertIDFunc() returned duplicate id: %s", id)
+		}
+		if id <= prev {
+			t.Errorf("NewInsertIDFunc() id %s is not greater than previous id %s", id, prev)
+		}
+		seen[id] = struct{}{}
+		prev = id
+	}
+
+	if other := sloggcloud.NewInsertIDFunc()(context.Background(), slog.Record{}); other[:16] == prev[:16] {
+		t.Errorf("NewInsertIDFunc() prefix should differ between functions: %s, %s", other, prev)
+	}
+}
+
+func TestWithContentHashInsertID(t *testing.T) {
+	type logCall struct {
+		level   slog.Level
+		message string
+		args    []any
+	}
+
+	tests := []struct {
+		name     string
+		opts     []sloggcloud.Option
+		first    logCall
+		second   logCall
+		wantSame bool
+	}{
+		{
+			name:     "同じ内容のログは同じinsertId",
+			opts:     []sloggcloud.Option{},
+			first:    logCall{level: slog.LevelInfo, message: "hello", args: []any{"user_id", "x", "count", 1}},
+			second:   logCall{level: slog.LevelInfo, message: "hello", args: []any{"user_id", "x", "count", 1}},
+			wantSame: true,
+		},
+		{
+			name:     "属性の順序が異なっても同じinsertId",
+			opts:     []sloggcloud.Option{},
+			first:    logCall{level: slog.LevelInfo, message: "hello", args: []any{"user_id", "x", "count", 1}},
+			second:   logCall{level: slog.LevelInfo, message: "hello", args: []any{"count", 1, "user_id", "x"}},
+			wantSame: true,
+		},
+		{
+			name:     "メッセージが異なる場合は異なるinsertId",
+			opts:     []sloggcloud.Option{},
+			first:    logCall{level: slog.LevelInfo, message: "hello", args: []any{}},
+			second:   logCall{level: slog.LevelInfo, message: "goodbye", args: []any{}},
+			wantSame: false,
+		},
+		{
+			name:     "severityが異なる場合は異なるinsertId",
+			opts:     []sloggcloud.Option{},
+			first:    logCall{level: slog.LevelInfo, message: "hello", args: []any{}},
+			second:   logCall{level: slog.LevelWarn, message: "hello", args: []any{}},
+			wantSame: false,
+		},
+		{
+			name:     "属性の値が異なる場合は異なるinsertId",
+			opts:     []sloggcloud.Option{},
+			first:    logCall{level: slog.LevelInfo, message: "hello", args: []any{"user_id", "x"}},
+			second:   logCall{level: slog.LevelInfo, message: "hello", args: []any{"user_id", "y"}},
+			wantSame: false,
+		},
+		{
+			name:     "グループ内の属性が異なる場合は異なるinsertId",
+			opts:     []sloggcloud.Option{},
+			first:    logCall{level: slog.LevelInfo, message: "hello", args: []any{slog.Group("req", "id", 1)}},
+			second:   logCall{level: slog.LevelInfo, message: "hello", args: []any{slog.Group("req", "id", 2)}},
+			wantSame: false,
+		},
+		{
+			name: "WithInsertIDFuncを優先",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithInsertIDFunc(sloggcloud.NewInsertIDFunc()),
+			},
+			first:    logCall{level: slog.LevelInfo, message: "hello", args: []any{}},
+			second:   logCall{level: slog.LevelInfo, message: "hello", args: []any{}},
+			wantSame: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := append([]sloggcloud.Option{sloggcloud.WithContentHashInsertID(true), sloggcloud.WithSource(false)}, tt.opts...)
+			logger := slog.New(sloggcloud.New(&buf, opts...))
+
+			ctx := context.Background()
+			logger.Log(ctx, tt.first.level, tt.first.message, tt.first.args...)
+			logger.Log(ctx, tt.second.level, tt.second.message, tt.second.args...)
+
+			dec := json.NewDecoder(&buf)
+			ids := make([]string, 0, 2)
+			for range 2 {
+				var got map[string]interface{}
+				if err := dec.Decode(&got); err != nil {
+					t.Fatalf("failed to parse JSON: %v", err)
+				}
+				id, ok := got["logging.googleapis.com/insertId"].(string)
+				if !ok || id == "" {
+					t.Fatalf("insertId is missing: %v", got)
+				}
+				ids = append(ids, id)
+			}
+
+			if got := ids[0] == ids[1]; got != tt.wantSame {
+				t.Errorf("insertId equality = %v, want %v (ids: %v)", got, tt.wantSame, ids)
+			}
+		})
+	}
+
+	t.Run("無効の場合はinsertIdを出力しない", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(sloggcloud.New(&buf, sloggcloud.WithSource(false)))
+		logger.Info("hello")
+
+		var got map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		if _, ok := got["logging.googleapis.com/insertId"]; ok {
+			t.Errorf("insertId should not be emitted: %v", got)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package sloggcloud
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// labelsKey は Cloud Logging のラベルを出力するキーです。
+const labelsKey = "logging.googleapis.com/labels"
+
+// ctxLabelsKey は Middleware がリクエストごとのラベルをコンテキストに保存するキーです。
+type ctxLabelsKey struct{}
+
+// contextWithLabels は labels を追加したコンテキストを返します。既にコンテキストにラベルがある場合は同じキーの値を上書きします。
+func contextWithLabels(ctx context.Context, labels map[string]string) context.Context {
+	merged := maps.Clone(labelsFromContextValue(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(labels))
+	}
+	maps.Copy(merged, labels)
+	return context.WithValue(ctx, ctxLabelsKey{}, merged)
+}
+
+// labelsFromContextValue は contextWithLabels でコンテキストに保存したラベルを返します。
+func labelsFromContextValue(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(ctxLabelsKey{}).(map[string]string)
+	return labels
+}
+
+// labels はレコードに付与するラベルを返します。
+// キーが重複した場合は WithLabels、WithProgram、WithVersion、WithGroupAsLabel、baggage、Middleware がコンテキストに保存したラベル、
+// WithLabelsFromContext の順に後のものが優先されます。
+// WithLabelKeySanitize を指定した場合は、全てのキーを Cloud Logging のラベルの規則に合わせて変換します。
+func (h *Handler) labels(ctx context.Context) map[string]string {
+	// ラベルを設定していない場合に毎回 map を確保しないようにする
+	if len(h.opts.labels) == 0 && h.opts.program == "" && h.opts.version == "" && !h.opts.groupAsLabel && !h.opts.baggageLabels && h.opts.labelsFromContext == nil &&
+		len(labelsFromContextValue(ctx)) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(h.opts.labels)+1)
+	maps.Copy(labels, h.opts.labels)
+	if h.opts.program != "" {
+		labels["program"] = h.opts.program
+	}
+	if h.opts.version != "" {
+		labels["version"] = h.opts.version
+	}
+	if h.opts.groupAsLabel && len(h.groups) > 0 {
+		labels["component"] = h.groups[0]
+	}
+	if h.opts.baggageLabels {
+		for _, member := range baggage.FromContext(ctx).Members() {
+			labels[sanitizeLabelKey(member.Key())] = member.Value()
+		}
+	}
+	maps.Copy(labels, labelsFromContextValue(ctx))
+	if h.opts.labelsFromContext != nil {
+		ctxLabels, _ := callContextFunc(ctx, h.opts.contextFuncTimeout, h.opts.labelsFromContext)
+		maps.Copy(labels, ctxLabels)
+	}
+	if h.opts.labelKeySanitize {
+		labels = sanitizeLabelKeys(labels)
+	}
+	return labels
+}
+
+// sanitizeLabelKeys は全てのラベルのキーを [a-z][a-z0-9_]* の形式に変換します。
+// 変換後のキーが重複した場合に結果が実行ごとに変わらないよう、元のキーの辞書順で後のものを優先します。
+func sanitizeLabelKeys(labels map[string]string) map[string]string {
+	sanitized := make(map[string]string, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		sanitized[strings.ReplaceAll(sanitizeLabelKey(key), "-", "_")] = labels[key]
+	}
+	return sanitized
+}
+
+// labelsOverflowKey は WithMaxLabels の上限を超えたラベルを出力するキーです。
+const labelsOverflowKey = "labels_overflow"
+
+// labelsAttrs はラベルを出力順が一定になるようにキーでソートした slog.Attr に変換します。
+// WithMaxLabels の上限を超えた場合は、キーの辞書順で先頭から上限までをラベルとして出力し、
+// 残りはラベルではなく jsonPayload の labels_overflow に出力します。
+func (h *Handler) labelsAttrs(labels map[string]string) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		attrs = append(attrs, slog.String(key, labels[key]))
+	}
+	if h.opts.maxLabels <= 0 || len(attrs) <= h.opts.maxLabels {
+		return []slog.Attr{{Key: labelsKey, Value: slog.GroupValue(attrs...)}}
+	}
+	return []slog.Attr{
+		{Key: labelsKey, Value: slog.GroupValue(attrs[:h.opts.maxLabels]...)},
+		{Key: labelsOverflowKey, Value: slog.GroupValue(attrs[h.opts.maxLabels:]...)},
+	}
+}
+
+// maxLabelKeyLength は Cloud Logging のラベルのキーの最大長です。
+const maxLabelKeyLength = 63
+
+// sanitizeLabelKey は key を Cloud Logging のラベルのキーとして使える形式に変換します。
+// 英小文字・数字・アンダースコア・ハイフン以外の文字はアンダースコアに置き換え、英小文字以外で始まる場合は "label_" を前に付けます。
+func sanitizeLabelKey(key string) string {
+	key = strings.Map(func(c rune) rune {
+		switch {
+		case 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '_', c == '-':
+			return c
+		case 'A' <= c && c <= 'Z':
+			return c + ('a' - 'A')
+		default:
+			return '_'
+		}
+	}, key)
+	if key == "" || key[0] < 'a' || key[0] > 'z' {
+		key = "label_" + key
+	}
+	if len(key) > maxLabelKeyLength {
+		key = key[:maxLabelKeyLength]
+	}
+	return key
+}
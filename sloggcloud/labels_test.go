@@ -0,0 +1,336 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+func TestWithBaggageLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		members map[string]string
+		opts    []sloggcloud.Option
+		want    map[string]interface{}
+	}{
+		{
+			name:    "baggageのメンバーをラベルとして出力",
+			members: map[string]string{"tenant": "acme", "session": "s-1"},
+			opts:    []sloggcloud.Option{sloggcloud.WithBaggageLabels(true)},
+			want: map[string]interface{}{
+				"tenant":  "acme",
+				"session": "s-1",
+			},
+		},
+		{
+			name:    "ラベルに使えないキーを変換",
+			members: map[string]string{"Feature.Flag": "on", "1st": "yes"},
+			opts:    []sloggcloud.Option{sloggcloud.WithBaggageLabels(true)},
+			want: map[string]interface{}{
+				"feature_flag": "on",
+				"label_1st":    "yes",
+			},
+		},
+		{
+			name:    "WithLabelsとマージしてbaggageを優先",
+			members: map[string]string{"env": "staging", "tenant": "acme"},
+			opts: []sloggcloud.Option{
+				sloggcloud.WithLabels(map[string]string{"env": "prod", "team": "core"}),
+				sloggcloud.WithBaggageLabels(true),
+			},
+			want: map[string]interface{}{
+				"env":    "staging",
+				"team":   "core",
+				"tenant": "acme",
+			},
+		},
+		{
+			name:    "WithLabelsFromContextをbaggageより優先",
+			members: map[string]string{"tenant": "acme"},
+			opts: []sloggcloud.Option{
+				sloggcloud.WithBaggageLabels(true),
+				sloggcloud.WithLabelsFromContext(func(context.Context) map[string]string {
+					return map[string]string{"tenant": "from-context"}
+				}),
+			},
+			want: map[string]interface{}{
+				"tenant": "from-context",
+			},
+		},
+		{
+			name:    "無効の場合はbaggageを出力しない",
+			members: map[string]string{"tenant": "acme"},
+			opts:    []sloggcloud.Option{},
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members := make([]baggage.Member, 0, len(tt.members))
+			for key, value := range tt.members {
+				member, err := baggage.NewMemberRaw(key, value)
+				if err != nil {
+					t.Fatalf("failed to create baggage member: %v", err)
+				}
+				members = append(members, member)
+			}
+			bag, err := baggage.New(members...)
+			if err != nil {
+				t.Fatalf("failed to create baggage: %v", err)
+			}
+			ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...))
+			logger.InfoContext(ctx, "message with baggage")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+
+			var gotLabels map[string]interface{}
+			if labels, ok := got["logging.googleapis.com/labels"].(map[string]interface{}); ok {
+				gotLabels = labels
+			}
+			if diff := cmp.Diff(tt.want, gotLabels); diff != "" {
+				t.Errorf("labels mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithGroupAsLabel(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []sloggcloud.Option
+		groups []string
+		want   map[string]interface{}
+	}{
+		{
+			name:   "最も外側のグループ名をcomponentラベルとして出力",
+			opts:   []sloggcloud.Option{sloggcloud.WithGroupAsLabel(true)},
+			groups: []string{"db", "query"},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "grouped",
+				"logging.googleapis.com/labels": map[string]interface{}{
+					"component": "db",
+				},
+				"db": map[string]interface{}{
+					"query": map[string]interface{}{
+						"key": "value",
+					},
+				},
+			},
+		},
+		{
+			name:   "グループがない場合はcomponentラベルを出力しない",
+			opts:   []sloggcloud.Option{sloggcloud.WithGroupAsLabel(true)},
+			groups: nil,
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "grouped",
+				"key":      "value",
+			},
+		},
+		{
+			name: "WithLabelsとマージ",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithGroupAsLabel(true),
+				sloggcloud.WithLabels(map[string]string{"env": "prod"}),
+			},
+			groups: []string{"db"},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "grouped",
+				"logging.googleapis.com/labels": map[string]interface{}{
+					"component": "db",
+					"env":       "prod",
+				},
+				"db": map[string]interface{}{
+					"key": "value",
+				},
+			},
+		},
+		{
+			name:   "無効の場合はcomponentラベルを出力しない",
+			opts:   []sloggcloud.Option{},
+			groups: []string{"db"},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "grouped",
+				"db": map[string]interface{}{
+					"key": "value",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...))
+			for _, group := range tt.groups {
+				logger = logger.WithGroup(group)
+			}
+
+			logger.Info("grouped", "key", "value")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithLabelKeySanitize(t *testing.T) {
+	labels := map[string]string{
+		"X-Request-ID": "r-1",
+		"user.id":      "u-1",
+		"1st":          "first",
+		"env":          "prod",
+	}
+
+	tests := []struct {
+		name string
+		opts []sloggcloud.Option
+		want map[string]interface{}
+	}{
+		{
+			name: "キーを変換",
+			opts: []sloggcloud.Option{sloggcloud.WithLabelKeySanitize(true)},
+			want: map[string]interface{}{
+				"x_request_id": "r-1",
+				"user_id":      "u-1",
+				"label_1st":    "first",
+				"env":          "prod",
+			},
+		},
+		{
+			name: "無効の場合はキーを変換しない",
+			opts: []sloggcloud.Option{},
+			want: map[string]interface{}{
+				"X-Request-ID": "r-1",
+				"user.id":      "u-1",
+				"1st":          "first",
+				"env":          "prod",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts,
+				sloggcloud.WithSource(false),
+				sloggcloud.WithLabelsFromContext(func(context.Context) map[string]string { return labels }),
+			)...))
+
+			logger.Info("labels")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, got["logging.googleapis.com/labels"]); diff != "" {
+				t.Errorf("labels mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithMaxLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []sloggcloud.Option
+		want map[string]interface{}
+	}{
+		{
+			name: "上限を超えたラベルをlabels_overflowに出力",
+			opts: []sloggcloud.Option{sloggcloud.WithMaxLabels(2)},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "labels",
+				"logging.googleapis.com/labels": map[string]interface{}{
+					"a_static": "1",
+					"b_ctx":    "2",
+				},
+				"labels_overflow": map[string]interface{}{
+					"c_static": "3",
+					"d_ctx":    "4",
+				},
+			},
+		},
+		{
+			name: "上限以下の場合はすべてラベルとして出力",
+			opts: []sloggcloud.Option{sloggcloud.WithMaxLabels(4)},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "labels",
+				"logging.googleapis.com/labels": map[string]interface{}{
+					"a_static": "1",
+					"b_ctx":    "2",
+					"c_static": "3",
+					"d_ctx":    "4",
+				},
+			},
+		},
+		{
+			name: "デフォルトでは制限しない",
+			opts: []sloggcloud.Option{},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "labels",
+				"logging.googleapis.com/labels": map[string]interface{}{
+					"a_static": "1",
+					"b_ctx":    "2",
+					"c_static": "3",
+					"d_ctx":    "4",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ラベルの数が上限を超えても、残るラベルは実行ごとに変わらない
+			for range 10 {
+				var buf bytes.Buffer
+				logger := slog.New(sloggcloud.New(&buf, append(tt.opts,
+					sloggcloud.WithSource(false),
+					sloggcloud.WithLabels(map[string]string{"c_static": "3", "a_static": "1"}),
+					sloggcloud.WithLabelsFromContext(func(context.Context) map[string]string {
+						return map[string]string{"d_ctx": "4", "b_ctx": "2"}
+					}),
+				)...))
+
+				logger.Info("labels")
+
+				var got map[string]interface{}
+				if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+					t.Fatalf("failed to parse JSON: %v", err)
+				}
+				delete(got, "time")
+
+				if diff := cmp.Diff(tt.want, got); diff != "" {
+					t.Fatalf("output mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
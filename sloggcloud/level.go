@@ -0,0 +1,86 @@
+package sloggcloud
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// Google Cloud Logging の severity に対応する slog.Level です。
+// slog の標準レベルに存在しない severity を出力するために利用します。
+const (
+	// LevelNotice は NOTICE に対応するレベルです。
+	LevelNotice slog.Level = slog.LevelInfo + 2
+	// LevelCritical は CRITICAL に対応するレベルです。
+	LevelCritical slog.Level = slog.LevelError + 4
+	// LevelAlert は ALERT に対応するレベルです。
+	LevelAlert slog.Level = slog.LevelError + 8
+	// LevelEmergency は EMERGENCY に対応するレベルです。
+	LevelEmergency slog.Level = slog.LevelError + 12
+)
+
+func levelToSeverity(level slog.Level) string {
+	switch {
+	case level >= LevelEmergency:
+		return "EMERGENCY"
+	case level >= LevelAlert:
+		return "ALERT"
+	case level >= LevelCritical:
+		return "CRITICAL"
+	case level >= slog.LevelError:
+		return "ERROR"
+	case level >= slog.LevelWarn:
+		return "WARNING"
+	case level >= LevelNotice:
+		return "NOTICE"
+	case level >= slog.LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// severityToLevel は Google Cloud Logging の severity 名に対応する slog.Level です。
+var severityToLevel = map[string]slog.Level{
+	"DEBUG":     slog.LevelDebug,
+	"INFO":      slog.LevelInfo,
+	"NOTICE":    LevelNotice,
+	"WARNING":   slog.LevelWarn,
+	"ERROR":     slog.LevelError,
+	"CRITICAL":  LevelCritical,
+	"ALERT":     LevelAlert,
+	"EMERGENCY": LevelEmergency,
+}
+
+// parseLevel は slog のレベル名または Google Cloud Logging の severity 名を大文字小文字を区別せずに解析します。
+func parseLevel(s string) (slog.Level, bool) {
+	s = strings.TrimSpace(s)
+	if level, ok := severityToLevel[strings.ToUpper(s)]; ok {
+		return level, true
+	}
+	// "WARN" や "INFO+2" のような slog の表記は slog.Level 自身に解析させる
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, false
+	}
+	return level, true
+}
+
+// isSeverity は s が Cloud Logging の severity 名かどうかを返します。
+// DEFAULT は対応する slog.Level を持たないため severityToLevel には含まれません。
+func isSeverity(s string) bool {
+	if s == "DEFAULT" {
+		return true
+	}
+	_, ok := severityToLevel[s]
+	return ok
+}
+
+// overrideSeverity は overrides に含まれるレベルのみ severity を置き換え、それ以外は mapper で変換する関数を返します。
+func overrideSeverity(mapper func(slog.Level) string, overrides map[slog.Level]string) func(slog.Level) string {
+	return func(level slog.Level) string {
+		if severity, ok := overrides[level]; ok {
+			return severity
+		}
+		return mapper(level)
+	}
+}
@@ -0,0 +1,223 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestLevelToSeverity(t *testing.T) {
+	tests := []struct {
+		name  string
+		level slog.Level
+		want  string
+	}{
+		{name: "DEBUGより低いレベル", level: slog.LevelDebug - 4, want: "DEBUG"},
+		{name: "DEBUGレベル", level: slog.LevelDebug, want: "DEBUG"},
+		{name: "INFOレベル", level: slog.LevelInfo, want: "INFO"},
+		{name: "NOTICEレベル", level: sloggcloud.LevelNotice, want: "NOTICE"},
+		{name: "WARNレベル", level: slog.LevelWarn, want: "WARNING"},
+		{name: "ERRORレベル", level: slog.LevelError, want: "ERROR"},
+		{name: "CRITICALレベル", level: sloggcloud.LevelCritical, want: "CRITICAL"},
+		{name: "ALERTレベル", level: sloggcloud.LevelAlert, want: "ALERT"},
+		{name: "EMERGENCYレベル", level: sloggcloud.LevelEmergency, want: "EMERGENCY"},
+		{name: "EMERGENCYより高いレベル", level: sloggcloud.LevelEmergency + 4, want: "EMERGENCY"},
+		{name: "INFOとNOTICEの間のレベル", level: sloggcloud.LevelNotice - 1, want: "INFO"},
+		{name: "ERRORとCRITICALの間のレベル", level: sloggcloud.LevelCritical - 1, want: "ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sloggcloud.LevelToSeverity(tt.level); got != tt.want {
+				t.Errorf("LevelToSeverity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithLevelFromEnv(t *testing.T) {
+	const envName = "SLOGGCLOUD_TEST_LOG_LEVEL"
+
+	tests := []struct {
+		name  string
+		value string
+		set   bool
+		want  slog.Level
+	}{
+		{name: "DEBUG", value: "DEBUG", set: true, want: slog.LevelDebug},
+		{name: "小文字のdebug", value: "debug", set: true, want: slog.LevelDebug},
+		{name: "INFO", value: "INFO", set: true, want: slog.LevelInfo},
+		{name: "WARN", value: "WARN", set: true, want: slog.LevelWarn},
+		{name: "小文字のwarn", value: "warn", set: true, want: slog.LevelWarn},
+		{name: "severity名のWARNING", value: "WARNING", set: true, want: slog.LevelWarn},
+		{name: "小文字のwarning", value: "warning", set: true, want: slog.LevelWarn},
+		{name: "ERROR", value: "ERROR", set: true, want: slog.LevelError},
+		{name: "severity名のNOTICE", value: "Notice", set: true, want: sloggcloud.LevelNotice},
+		{name: "severity名のCRITICAL", value: "CRITICAL", set: true, want: sloggcloud.LevelCritical},
+		{name: "前後の空白を無視", value: " error ", set: true, want: slog.LevelError},
+		{name: "未設定の場合はINFO", value: "", set: false, want: slog.LevelInfo},
+		{name: "空文字の場合はINFO", value: "", set: true, want: slog.LevelInfo},
+		{name: "解析できない場合はINFO", value: "verbose", set: true, want: slog.LevelInfo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.set {
+				t.Setenv(envName, tt.value)
+			} else {
+				t.Setenv(envName, "")
+				if err := os.Unsetenv(envName); err != nil {
+					t.Fatalf("failed to unset env: %v", err)
+				}
+			}
+
+			handler := sloggcloud.New(io.Discard, sloggcloud.WithLevelFromEnv(envName))
+
+			ctx := context.Background()
+			if !handler.Enabled(ctx, tt.want) {
+				t.Errorf("Enabled(%v) = false, want true", tt.want)
+			}
+			if handler.Enabled(ctx, tt.want-1) {
+				t.Errorf("Enabled(%v) = true, want false", tt.want-1)
+			}
+		})
+	}
+}
+
+func TestWithLevelSeverity(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []sloggcloud.Option
+		want     map[slog.Level]string
+		wantWarn bool
+	}{
+		{
+			name: "WARNをNOTICEに置き換え",
+			opts: []sloggcloud.Option{sloggcloud.WithLevelSeverity(slog.LevelWarn, "NOTICE")},
+			want: map[slog.Level]string{
+				slog.LevelInfo:     "INFO",
+				slog.LevelWarn:     "NOTICE",
+				slog.LevelWarn + 1: "WARNING",
+				slog.LevelError:    "ERROR",
+			},
+			wantWarn: false,
+		},
+		{
+			name: "INFOをDEFAULTに置き換え",
+			opts: []sloggcloud.Option{sloggcloud.WithLevelSeverity(slog.LevelInfo, "DEFAULT")},
+			want: map[slog.Level]string{
+				slog.LevelInfo:  "DEFAULT",
+				slog.LevelWarn:  "WARNING",
+				slog.LevelError: "ERROR",
+			},
+			wantWarn: false,
+		},
+		{
+			name: "後に指定したWithSeverityMapperよりも優先",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithLevelSeverity(slog.LevelWarn, "NOTICE"),
+				sloggcloud.WithSeverityMapper(func(slog.Level) string { return "DEBUG" }),
+			},
+			want: map[slog.Level]string{
+				slog.LevelInfo:  "DEBUG",
+				slog.LevelWarn:  "NOTICE",
+				slog.LevelError: "DEBUG",
+			},
+			wantWarn: false,
+		},
+		{
+			name: "不正なseverityの場合は警告して無視",
+			opts: []sloggcloud.Option{sloggcloud.WithLevelSeverity(slog.LevelWarn, "notice")},
+			want: map[slog.Level]string{
+				slog.LevelInfo: "INFO",
+				slog.LevelWarn: "WARNING",
+			},
+			wantWarn: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warn bytes.Buffer
+			sloggcloud.SetWarnOutput(t, &warn)
+
+			var buf bytes.Buffer
+			handler := sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false), sloggcloud.WithLevel(slog.LevelDebug))...)
+
+			for level, want := range tt.want {
+				buf.Reset()
+				if err := handler.Handle(context.Background(), slog.NewRecord(time.Now(), level, "hello", 0)); err != nil {
+					t.Fatalf("failed to handle record: %v", err)
+				}
+				var got struct {
+					Severity string `json:"severity"`
+				}
+				if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+					t.Fatalf("failed to parse JSON: %v", err)
+				}
+				if got.Severity != want {
+					t.Errorf("severity of %s = %s, want %s", level, got.Severity, want)
+				}
+			}
+			if gotWarn := warn.Len() > 0; gotWarn != tt.wantWarn {
+				t.Errorf("warned = %v, want %v: %s", gotWarn, tt.wantWarn, warn.String())
+			}
+		})
+	}
+}
+
+func TestWithSeverityKey(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []sloggcloud.Option
+		args []any
+		want map[string]interface{}
+	}{
+		{
+			name: "デフォルトではseverityに出力",
+			opts: nil,
+			args: nil,
+			want: map[string]interface{}{"severity": "WARNING", "message": "hello"},
+		},
+		{
+			name: "指定したキーにseverityを出力",
+			opts: []sloggcloud.Option{sloggcloud.WithSeverityKey("level")},
+			args: nil,
+			want: map[string]interface{}{"level": "WARNING", "message": "hello"},
+		},
+		{
+			name: "空文字列の場合はデフォルトのキー",
+			opts: []sloggcloud.Option{sloggcloud.WithSeverityKey("")},
+			args: nil,
+			want: map[string]interface{}{"severity": "WARNING", "message": "hello"},
+		},
+		{
+			name: "変更したキーと衝突する属性は名前を変えて出力",
+			opts: []sloggcloud.Option{sloggcloud.WithSeverityKey("level")},
+			args: []any{"level", "user value"},
+			want: map[string]interface{}{"level": "WARNING", "message": "hello", "attr_level": "user value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...))
+
+			logger.Warn("hello", tt.args...)
+
+			got := decodeLines(t, &buf)
+			if diff := cmp.Diff([]map[string]interface{}{tt.want}, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
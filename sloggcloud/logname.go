@@ -0,0 +1,38 @@
+package sloggcloud
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// logNameKey はログの出力先となるログ名を出力するキーです。
+const logNameKey = "logging.googleapis.com/logName"
+
+// maxLogNameLength は Cloud Logging が受け付けるログ名の最大長です。
+const maxLogNameLength = 512
+
+// warnOutput は設定の誤りを警告する出力先です。テストで差し替えられるように変数にしています。
+var warnOutput io.Writer = os.Stderr
+
+// isValidLogName は name が Cloud Logging の受け付けるログ名かどうかを判定します。
+// ログ名に使えるのは英数字とスラッシュ、アンダースコア、ハイフン、ピリオドのみです。
+func isValidLogName(name string) bool {
+	if len(name) > maxLogNameLength {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '/', c == '_', c == '-', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// warnf は設定の誤りを警告として出力します。
+func warnf(format string, args ...any) {
+	_, _ = fmt.Fprintf(warnOutput, "sloggcloud: "+format+"\n", args...)
+}
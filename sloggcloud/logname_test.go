@@ -0,0 +1,91 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithLogName(t *testing.T) {
+	tests := []struct {
+		name     string
+		logName  string
+		want     map[string]interface{}
+		wantWarn string
+	}{
+		{
+			name:    "ログ名をトップレベルに出力",
+			logName: "projects/test-project/logs/app",
+			want: map[string]interface{}{
+				"severity":                       "INFO",
+				"message":                        "log name message",
+				"logging.googleapis.com/logName": "projects/test-project/logs/app",
+			},
+			wantWarn: "",
+		},
+		{
+			name:    "空のログ名は出力せずに警告",
+			logName: "",
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "log name message",
+			},
+			wantWarn: "log name must not be empty",
+		},
+		{
+			name:    "使えない文字を含む場合は警告して出力",
+			logName: "my log",
+			want: map[string]interface{}{
+				"severity":                       "INFO",
+				"message":                        "log name message",
+				"logging.googleapis.com/logName": "my log",
+			},
+			wantWarn: `log name "my log" is rejected by Cloud Logging`,
+		},
+		{
+			name:    "長すぎる場合は警告して出力",
+			logName: strings.Repeat("a", 513),
+			want: map[string]interface{}{
+				"severity":                       "INFO",
+				"message":                        "log name message",
+				"logging.googleapis.com/logName": strings.Repeat("a", 513),
+			},
+			wantWarn: "is rejected by Cloud Logging",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warn bytes.Buffer
+			sloggcloud.SetWarnOutput(t, &warn)
+
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf,
+				sloggcloud.WithLogName(tt.logName),
+				sloggcloud.WithSource(false),
+			))
+			logger.Info("log name message")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+			if tt.wantWarn == "" && warn.Len() > 0 {
+				t.Errorf("unexpected warning: %s", warn.String())
+			}
+			if !strings.Contains(warn.String(), tt.wantWarn) {
+				t.Errorf("warning = %q, want to contain %q", warn.String(), tt.wantWarn)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package sloggcloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// metadataHostEnv はメタデータサーバーのホストを上書きするための環境変数です。
+	// Google Cloud のクライアントライブラリと同じ環境変数を利用します。
+	metadataHostEnv = "GCE_METADATA_HOST"
+	// defaultMetadataHost はメタデータサーバーのデフォルトのホストです。
+	defaultMetadataHost = "metadata.google.internal"
+	// metadataTimeout はメタデータサーバーへの問い合わせのタイムアウトです。
+	// ローカル環境などメタデータサーバーが存在しない場合でも New が長時間ブロックしないように短くしています。
+	metadataTimeout = 500 * time.Millisecond
+)
+
+// projectIDEnvs は Project ID を取得する環境変数の一覧で、先頭のものほど優先されます。
+var projectIDEnvs = []string{"GOOGLE_CLOUD_PROJECT", "GCLOUD_PROJECT"}
+
+// projectIDFromEnv は Google Cloud のランタイムが設定する環境変数から Project ID を取得します。
+func projectIDFromEnv() string {
+	for _, env := range projectIDEnvs {
+		if projectID := os.Getenv(env); projectID != "" {
+			return projectID
+		}
+	}
+	return ""
+}
+
+// projectIDFromMetadata はメタデータサーバーから Google Cloud Project ID を取得します。
+func projectIDFromMetadata(ctx context.Context) (string, error) {
+	return metadataValue(ctx, "project/project-id")
+}
+
+// metadataValue はメタデータサーバーから path の値を取得します。path は /computeMetadata/v1/ からの相対パスです。
+func metadataValue(ctx context.Context, path string) (string, error) {
+	host := os.Getenv(metadataHostEnv)
+	if host == "" {
+		host = defaultMetadataHost
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	url := "http://" + host + "/computeMetadata/v1/" + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create metadata request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request metadata server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code from metadata server: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read metadata response: %w", err)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,87 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/p1ass/go-pkg/sloggcloud"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithProjectIDFromMetadata(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		opts      []sloggcloud.Option
+		wantTrace string
+	}{
+		{
+			name: "メタデータサーバーから取得したProject IDを利用",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Path != "/computeMetadata/v1/project/project-id" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				_, _ = io.WriteString(w, "metadata-project")
+			},
+			opts:      []sloggcloud.Option{},
+			wantTrace: "projects/metadata-project/traces/01020304050607080102030405060708",
+		},
+		{
+			name: "取得に失敗した場合はProject IDなしで動作",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			opts:      []sloggcloud.Option{},
+			wantTrace: "01020304050607080102030405060708",
+		},
+		{
+			name: "WithProjectIDで指定した値を優先",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = io.WriteString(w, "metadata-project")
+			},
+			opts:      []sloggcloud.Option{sloggcloud.WithProjectID("explicit-project")},
+			wantTrace: "projects/explicit-project/traces/01020304050607080102030405060708",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+			t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(srv.URL, "http://"))
+			t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+			t.Setenv("GCLOUD_PROJECT", "")
+
+			var buf bytes.Buffer
+			opts := append([]sloggcloud.Option{
+				sloggcloud.WithProjectIDFromMetadata(),
+				sloggcloud.WithSource(false),
+			}, tt.opts...)
+			logger := slog.New(sloggcloud.New(&buf, opts...))
+
+			traceID, _ := trace.TraceIDFromHex("01020304050607080102030405060708")
+			spanID, _ := trace.SpanIDFromHex("0102030405060708")
+			ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID: traceID,
+				SpanID:  spanID,
+			}))
+			logger.InfoContext(ctx, "message with project id")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if got["logging.googleapis.com/trace"] != tt.wantTrace {
+				t.Errorf("trace = %v, want %v", got["logging.googleapis.com/trace"], tt.wantTrace)
+			}
+		})
+	}
+}
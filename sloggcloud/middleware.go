@@ -0,0 +1,177 @@
+package sloggcloud
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDKey は Middleware がリクエスト ID を出力するラベルと属性のキーです。
+const requestIDKey = "request_id"
+
+// maxRequestIDLength はヘッダーから受け取るリクエスト ID の最大長です。
+// 外部から任意の値を送れるため、長すぎる値はラベルに出力せずに生成し直します。
+const maxRequestIDLength = 128
+
+// MiddlewareOption は Middleware の設定を変更するオプションです。
+type MiddlewareOption func(*middlewareOptions)
+
+type middlewareOptions struct {
+	// requestIDHeader はリクエスト ID を受け取るヘッダーで、空の場合はリクエスト ID を扱わない
+	requestIDHeader string
+}
+
+// WithRequestIDHeader は header からリクエスト ID を取得し、リクエスト中の全てのログに request_id のラベルと属性として出力します。
+// ヘッダーがない場合や値が不正な場合はリクエスト ID を生成します。
+// 後続のサービスやクライアントが同じ ID を使えるように、リクエスト ID はレスポンスの同じヘッダーにも設定します。
+// リクエスト中のログに出力するには、ログの出力時に r.Context() から派生したコンテキストを渡す必要があります。
+func WithRequestIDHeader(header string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.requestIDHeader = header
+	}
+}
+
+// Middleware は HTTP リクエストごとに Cloud Logging の httpRequest 形式のログを 1 件出力するミドルウェアを返します。
+// リクエストのコンテキストに有効なスパンがない場合は、X-Cloud-Trace-Context ヘッダーまたは traceparent ヘッダーから
+// トレース情報を取得してコンテキストに設定するため、後続のハンドラのログともトレースで紐づけられます。
+// ステータスコードが 500 以上の場合は ERROR、それ以外は INFO レベルで出力します。
+func Middleware(logger *slog.Logger, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	o := &middlewareOptions{requestIDHeader: ""}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			ctx := r.Context()
+			if !trace.SpanContextFromContext(ctx).IsValid() {
+				if spanCtx, ok := spanContextFromRequest(r); ok {
+					ctx = trace.ContextWithRemoteSpanContext(ctx, spanCtx)
+				}
+			}
+			if o.requestIDHeader != "" {
+				requestID := r.Header.Get(o.requestIDHeader)
+				if !isValidRequestID(requestID) {
+					requestID = newRequestID()
+				}
+				w.Header().Set(o.requestIDHeader, requestID)
+				ctx = contextWithLabels(ctx, map[string]string{requestIDKey: requestID})
+				ctx = ContextWithAttrs(ctx, slog.String(requestIDKey, requestID))
+			}
+			if ctx != r.Context() {
+				r = r.WithContext(ctx)
+			}
+
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK, size: 0, wroteHeader: false}
+			next.ServeHTTP(rw, r)
+
+			level := slog.LevelInfo
+			if rw.status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			req := &HTTPRequest{
+				RequestMethod: r.Method,
+				RequestURL:    r.URL.String(),
+				RequestSize:   max(r.ContentLength, 0),
+				Status:        rw.status,
+				ResponseSize:  rw.size,
+				UserAgent:     r.UserAgent(),
+				RemoteIP:      remoteIP(r.RemoteAddr),
+				ServerIP:      "",
+				Referer:       r.Referer(),
+				Latency:       time.Since(start),
+				Protocol:      r.Proto,
+			}
+			logger.LogAttrs(ctx, level, r.Method+" "+r.URL.Path, HTTPRequestAttr(req))
+		})
+	}
+}
+
+// isValidRequestID はヘッダーから受け取ったリクエスト ID をそのまま出力してよいかどうかを返します。
+// ログの表示や検索を崩さないように、表示可能な ASCII 文字のみで maxRequestIDLength 以下の値のみを受け付けます。
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID はランダムな 32 文字の 16 進数文字列のリクエスト ID を生成します。
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// spanContextFromRequest はリクエストヘッダーからトレース情報を取得します。
+func spanContextFromRequest(r *http.Request) (trace.SpanContext, bool) {
+	if header := r.Header.Get(cloudTraceContextHeader); header != "" {
+		if spanCtx, ok := parseCloudTraceContext(header); ok {
+			return spanCtx, true
+		}
+	}
+	if header := r.Header.Get(traceparentHeader); header != "" {
+		return TraceFromTraceparent(header)
+	}
+	return trace.SpanContext{}, false
+}
+
+// remoteIP は "host:port" 形式のアドレスからホスト部分を取り出します。
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// responseWriter はステータスコードとレスポンスサイズを記録する http.ResponseWriter です。
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err //nolint:wrapcheck // http.ResponseWriter のエラーをそのまま返す
+}
+
+// Unwrap は http.ResponseController が元の http.ResponseWriter を取得するために利用します。
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush はストリーミングのために http.Flusher を型アサーションで取得するハンドラが、ミドルウェアを通しても Flush できるようにします。
+// 元の http.ResponseWriter が http.Flusher を実装していない場合は何もしません。
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		// Flush は未送信のヘッダーを 200 で送信するため、以降の WriteHeader のステータスは記録しない
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
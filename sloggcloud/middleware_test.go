@@ -0,0 +1,376 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		target          string
+		body            string
+		header          http.Header
+		setupContext    func() context.Context
+		handler         http.HandlerFunc
+		wantSeverity    string
+		wantHTTPRequest map[string]interface{}
+		wantTrace       string
+		wantSpanID      string
+	}{
+		{
+			name:   "GETリクエストのログを出力",
+			method: http.MethodGet,
+			target: "/users?id=1",
+			header: http.Header{},
+			setupContext: func() context.Context {
+				return context.Background()
+			},
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = io.WriteString(w, "hello")
+			},
+			wantSeverity: "INFO",
+			wantHTTPRequest: map[string]interface{}{
+				"requestMethod": "GET",
+				"requestUrl":    "/users?id=1",
+				"status":        float64(200),
+				"responseSize":  "5",
+				"remoteIp":      "192.0.2.1",
+				"protocol":      "HTTP/1.1",
+			},
+		},
+		{
+			name:   "POSTリクエストでステータスコードを記録",
+			method: http.MethodPost,
+			target: "/users",
+			body:   `{"name":"alice"}`,
+			header: http.Header{},
+			setupContext: func() context.Context {
+				return context.Background()
+			},
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			},
+			wantSeverity: "INFO",
+			wantHTTPRequest: map[string]interface{}{
+				"requestMethod": "POST",
+				"requestUrl":    "/users",
+				"requestSize":   "16",
+				"status":        float64(201),
+				"remoteIp":      "192.0.2.1",
+				"protocol":      "HTTP/1.1",
+			},
+		},
+		{
+			name:   "5xxのステータスコードはERRORで出力",
+			method: http.MethodGet,
+			target: "/error",
+			header: http.Header{},
+			setupContext: func() context.Context {
+				return context.Background()
+			},
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantSeverity: "ERROR",
+			wantHTTPRequest: map[string]interface{}{
+				"requestMethod": "GET",
+				"requestUrl":    "/error",
+				"status":        float64(500),
+				"remoteIp":      "192.0.2.1",
+				"protocol":      "HTTP/1.1",
+			},
+		},
+		{
+			name:   "X-Cloud-Trace-Contextヘッダーからトレース情報を取得",
+			method: http.MethodGet,
+			target: "/",
+			header: http.Header{
+				"X-Cloud-Trace-Context": []string{"0102030405060708090a0b0c0d0e0f10/1;o=1"},
+			},
+			setupContext: func() context.Context {
+				return context.Background()
+			},
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantSeverity: "INFO",
+			wantHTTPRequest: map[string]interface{}{
+				"requestMethod": "GET",
+				"requestUrl":    "/",
+				"status":        float64(204),
+				"remoteIp":      "192.0.2.1",
+				"protocol":      "HTTP/1.1",
+			},
+			wantTrace:  "projects/test-project/traces/0102030405060708090a0b0c0d0e0f10",
+			wantSpanID: "0000000000000001",
+		},
+		{
+			name:   "traceparentヘッダーからトレース情報を取得",
+			method: http.MethodGet,
+			target: "/",
+			header: http.Header{
+				"Traceparent": []string{"00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01"},
+			},
+			setupContext: func() context.Context {
+				return context.Background()
+			},
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantSeverity: "INFO",
+			wantHTTPRequest: map[string]interface{}{
+				"requestMethod": "GET",
+				"requestUrl":    "/",
+				"status":        float64(204),
+				"remoteIp":      "192.0.2.1",
+				"protocol":      "HTTP/1.1",
+			},
+			wantTrace:  "projects/test-project/traces/0102030405060708090a0b0c0d0e0f10",
+			wantSpanID: "0102030405060708",
+		},
+		{
+			name:   "既存のスパンがある場合はヘッダーより優先",
+			method: http.MethodGet,
+			target: "/",
+			header: http.Header{
+				"X-Cloud-Trace-Context": []string{"0102030405060708090a0b0c0d0e0f10/1;o=1"},
+			},
+			setupContext: func() context.Context {
+				traceID, _ := trace.TraceIDFromHex("a1a2a3a4a5a6a7a8a1a2a3a4a5a6a7a8")
+				spanID, _ := trace.SpanIDFromHex("a1a2a3a4a5a6a7a8")
+				spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+					TraceID:    traceID,
+					SpanID:     spanID,
+					TraceFlags: trace.FlagsSampled,
+				})
+				return trace.ContextWithSpanContext(context.Background(), spanCtx)
+			},
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantSeverity: "INFO",
+			wantHTTPRequest: map[string]interface{}{
+				"requestMethod": "GET",
+				"requestUrl":    "/",
+				"status":        float64(204),
+				"remoteIp":      "192.0.2.1",
+				"protocol":      "HTTP/1.1",
+			},
+			wantTrace:  "projects/test-project/traces/a1a2a3a4a5a6a7a8a1a2a3a4a5a6a7a8",
+			wantSpanID: "a1a2a3a4a5a6a7a8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf,
+				sloggcloud.WithProjectID("test-project"),
+				sloggcloud.WithSource(false),
+			))
+
+			req := httptest.NewRequestWithContext(tt.setupContext(), tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header = tt.header
+			rec := httptest.NewRecorder()
+
+			sloggcloud.Middleware(logger)(tt.handler).ServeHTTP(rec, req)
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+
+			if got["severity"] != tt.wantSeverity {
+				t.Errorf("severity = %v, want %v", got["severity"], tt.wantSeverity)
+			}
+
+			httpRequest, ok := got["httpRequest"].(map[string]interface{})
+			if !ok {
+				t.Fatalf("httpRequest field is missing: %v", got)
+			}
+			// レイテンシは実行ごとに変わるため形式のみ検証する
+			if latency, ok := httpRequest["latency"].(string); !ok || !strings.HasSuffix(latency, "s") {
+				t.Errorf("httpRequest.latency = %v, want duration string", httpRequest["latency"])
+			}
+			delete(httpRequest, "latency")
+			if diff := cmp.Diff(tt.wantHTTPRequest, httpRequest); diff != "" {
+				t.Errorf("httpRequest mismatch (-want +got):\n%s", diff)
+			}
+
+			if tt.wantTrace == "" {
+				if _, ok := got["logging.googleapis.com/trace"]; ok {
+					t.Errorf("unexpected trace field: %v", got["logging.googleapis.com/trace"])
+				}
+				return
+			}
+			if got["logging.googleapis.com/trace"] != tt.wantTrace {
+				t.Errorf("trace = %v, want %v", got["logging.googleapis.com/trace"], tt.wantTrace)
+			}
+			if got["logging.googleapis.com/spanId"] != tt.wantSpanID {
+				t.Errorf("spanId = %v, want %v", got["logging.googleapis.com/spanId"], tt.wantSpanID)
+			}
+		})
+	}
+}
+
+func TestMiddleware_requestID(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []sloggcloud.MiddlewareOption
+		requestHeader string
+		wantID        string
+		wantGenerated bool
+	}{
+		{
+			name:          "ヘッダーのリクエストIDを出力",
+			opts:          []sloggcloud.MiddlewareOption{sloggcloud.WithRequestIDHeader("X-Request-Id")},
+			requestHeader: "req-123",
+			wantID:        "req-123",
+			wantGenerated: false,
+		},
+		{
+			name:          "ヘッダーがない場合は生成",
+			opts:          []sloggcloud.MiddlewareOption{sloggcloud.WithRequestIDHeader("X-Request-Id")},
+			requestHeader: "",
+			wantID:        "",
+			wantGenerated: true,
+		},
+		{
+			name:          "不正な値の場合は生成",
+			opts:          []sloggcloud.MiddlewareOption{sloggcloud.WithRequestIDHeader("X-Request-Id")},
+			requestHeader: strings.Repeat("a", 129),
+			wantID:        "",
+			wantGenerated: true,
+		},
+		{
+			name:          "指定しない場合は出力しない",
+			opts:          nil,
+			requestHeader: "req-123",
+			wantID:        "",
+			wantGenerated: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, sloggcloud.WithSource(false)))
+			handler := sloggcloud.Middleware(logger, tt.opts...)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				logger.InfoContext(r.Context(), "in handler")
+			}))
+
+			req := httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil)
+			if tt.requestHeader != "" {
+				req.Header.Set("X-Request-Id", tt.requestHeader)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			gotID := rec.Header().Get("X-Request-Id")
+			switch {
+			case tt.wantGenerated:
+				if len(gotID) != 32 || gotID == tt.requestHeader {
+					t.Errorf("response header = %q, want a generated 32-char ID", gotID)
+				}
+			case gotID != tt.wantID:
+				t.Errorf("response header = %q, want %q", gotID, tt.wantID)
+			}
+
+			// ハンドラの中のログとアクセスログの両方にリクエスト ID を出力する
+			entries := decodeLines(t, &buf)
+			if len(entries) != 2 {
+				t.Fatalf("got %d entries, want 2", len(entries))
+			}
+			for _, entry := range entries {
+				labels, _ := entry["logging.googleapis.com/labels"].(map[string]interface{})
+				if gotID == "" {
+					if _, ok := entry["request_id"]; ok {
+						t.Errorf("request_id should not be output: %v", entry)
+					}
+					continue
+				}
+				if entry["request_id"] != gotID {
+					t.Errorf("request_id = %v, want %v", entry["request_id"], gotID)
+				}
+				if labels["request_id"] != gotID {
+					t.Errorf("labels.request_id = %v, want %v", labels["request_id"], gotID)
+				}
+			}
+		})
+	}
+}
+
+// nonFlusher は http.Flusher を実装しない http.ResponseWriter です。
+type nonFlusher struct {
+	http.ResponseWriter
+}
+
+func TestMiddleware_flush(t *testing.T) {
+	tests := []struct {
+		name        string
+		wrap        func(rec *httptest.ResponseRecorder) http.ResponseWriter
+		wantFlushed bool
+	}{
+		{
+			name: "元のhttp.ResponseWriterにFlushを転送",
+			wrap: func(rec *httptest.ResponseRecorder) http.ResponseWriter {
+				return rec
+			},
+			wantFlushed: true,
+		},
+		{
+			name: "元のhttp.ResponseWriterがhttp.Flusherでない場合は何もしない",
+			wrap: func(rec *httptest.ResponseRecorder) http.ResponseWriter {
+				return nonFlusher{ResponseWriter: rec}
+			},
+			wantFlushed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, sloggcloud.WithSource(false)))
+			handler := sloggcloud.Middleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				f, ok := w.(http.Flusher)
+				if !ok {
+					t.Fatal("response writer does not implement http.Flusher")
+				}
+				_, _ = io.WriteString(w, "chunk")
+				f.Flush()
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(tt.wrap(rec), httptest.NewRequestWithContext(context.Background(), http.MethodGet, "/", nil))
+
+			if rec.Flushed != tt.wantFlushed {
+				t.Errorf("Flushed = %v, want %v", rec.Flushed, tt.wantFlushed)
+			}
+			if got := rec.Body.String(); got != "chunk" {
+				t.Errorf("body = %q, want %q", got, "chunk")
+			}
+
+			entries := decodeLines(t, &buf)
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			httpRequest, _ := entries[0]["httpRequest"].(map[string]interface{})
+			if httpRequest["status"] != float64(http.StatusOK) || httpRequest["responseSize"] != "5" {
+				t.Errorf("unexpected httpRequest: %v", httpRequest)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package sloggcloud
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// multiHandler は複数のハンドラにレコードを配信する slog.Handler です。
+type multiHandler struct {
+	handlers []slog.Handler
+}
+
+var _ slog.Handler = (*multiHandler)(nil)
+
+// Multi は全ての handlers にレコードを配信する slog.Handler を返します。
+// 標準出力に Cloud Logging 形式の JSON を出力しつつ、エラーを通知用の出力先にも送る場合などに使用します。
+// WithAttrs と WithGroup は全ての handlers に適用されます。
+func Multi(handlers ...slog.Handler) slog.Handler {
+	return &multiHandler{handlers: handlers}
+}
+
+// Enabled はいずれかのハンドラが level のレコードを処理する場合に true を返します。
+func (h *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle はレコードを処理するハンドラ全てにレコードを渡します。
+// 一部のハンドラが失敗しても残りのハンドラには配信し、発生したエラーをまとめて返します。
+func (h *multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		// 非同期に書き込むハンドラがレコードを保持しても、呼び出し元や他のハンドラの影響を受けないように属性の中身まで複製して渡す
+		if err := handler.Handle(ctx, cloneRecord(r)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs は全てのハンドラに属性を追加した新しいハンドラを返します。
+func (h *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+// WithGroup は全てのハンドラにグループを追加した新しいハンドラを返します。
+func (h *multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &multiHandler{handlers: handlers}
+}
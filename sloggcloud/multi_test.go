@@ -0,0 +1,116 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+// decodeLines は 1 行ずつ出力された JSON を解析し、time を取り除いて返します。
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var entry map[string]interface{}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		delete(entry, "time")
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestMulti(t *testing.T) {
+	var stdout, alert bytes.Buffer
+	logger := slog.New(sloggcloud.Multi(
+		sloggcloud.New(&stdout, sloggcloud.WithSource(false)),
+		sloggcloud.New(&alert, sloggcloud.WithSource(false), sloggcloud.WithLevel(slog.LevelError)),
+	))
+
+	logger.Info("info")
+	logger.With("user", "u-1").WithGroup("req").Error("error", "id", "r-1")
+
+	tests := []struct {
+		name string
+		buf  *bytes.Buffer
+		want []map[string]interface{}
+	}{
+		{
+			name: "全てのレベルを出力するハンドラ",
+			buf:  &stdout,
+			want: []map[string]interface{}{
+				{"severity": "INFO", "message": "info"},
+				{"severity": "ERROR", "message": "error", "req": map[string]interface{}{"user": "u-1", "id": "r-1"}},
+			},
+		},
+		{
+			name: "ERROR以上のみ出力するハンドラ",
+			buf:  &alert,
+			want: []map[string]interface{}{
+				{"severity": "ERROR", "message": "error", "req": map[string]interface{}{"user": "u-1", "id": "r-1"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, decodeLines(t, tt.buf)); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMulti_Enabled(t *testing.T) {
+	handler := sloggcloud.Multi(
+		sloggcloud.New(&bytes.Buffer{}, sloggcloud.WithLevel(slog.LevelWarn)),
+		sloggcloud.New(&bytes.Buffer{}, sloggcloud.WithLevel(slog.LevelError)),
+	)
+
+	tests := []struct {
+		name  string
+		level slog.Level
+		want  bool
+	}{
+		{name: "いずれのハンドラも処理しないレベル", level: slog.LevelInfo, want: false},
+		{name: "一方のハンドラのみ処理するレベル", level: slog.LevelWarn, want: true},
+		{name: "両方のハンドラが処理するレベル", level: slog.LevelError, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := handler.Enabled(context.Background(), tt.level); got != tt.want {
+				t.Errorf("Enabled(%v) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMulti_Handle_error(t *testing.T) {
+	errFirst := errors.New("first failed")
+	errSecond := errors.New("second failed")
+	var buf bytes.Buffer
+	handler := sloggcloud.Multi(
+		sloggcloud.New(errWriter{err: errFirst}),
+		sloggcloud.New(&buf),
+		sloggcloud.New(errWriter{err: errSecond}),
+	)
+
+	err := handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "hello", 0))
+	if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+		t.Errorf("Handle() error = %v, want both %v and %v", err, errFirst, errSecond)
+	}
+	// 失敗したハンドラがあっても他のハンドラには配信する
+	if buf.Len() == 0 {
+		t.Error("record was not delivered to the healthy handler")
+	}
+}
@@ -0,0 +1,54 @@
+package sloggcloud
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// lineWriter は 1 回の書き込みを、改行で終わる 1 行として書き込み先に渡す io.Writer です。
+// 行単位でログを読み込むエージェントが 1 つのエントリを複数の行に分けて解析しないように、
+// 書き込まれたデータの途中の改行を空白に置き換え、末尾の改行をちょうど 1 つにします。
+// JSON の文字列の中の改行はエスケープされるため、生の改行はトークンの間の空白としてのみ現れ、空白に置き換えても意味は変わりません。
+type lineWriter struct {
+	w io.Writer
+}
+
+func newLineWriter(w io.Writer) *lineWriter {
+	return &lineWriter{w: w}
+}
+
+// Write は p を 1 行にして 1 回の書き込みで書き込み先に渡します。
+func (w *lineWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	line := p
+	// slog.JSONHandler の出力はほとんどの場合既に 1 行のため、その場合はコピーせずにそのまま書き込む
+	if !isSingleLine(p) {
+		line = toSingleLine(p)
+	}
+	if _, err := w.w.Write(line); err != nil {
+		return 0, fmt.Errorf("failed to write line: %w", err)
+	}
+	// slog.JSONHandler に対しては受け取ったデータをすべて書き込んだものとして報告する
+	return len(p), nil
+}
+
+// isSingleLine は p が末尾の改行以外に改行を含まない 1 行かどうかを返します。
+func isSingleLine(p []byte) bool {
+	return bytes.IndexAny(p, "\r\n") == len(p)-1 && p[len(p)-1] == '\n'
+}
+
+// toSingleLine は p の末尾の改行を取り除き、途中の改行を空白に置き換えてから改行を 1 つ付与します。
+func toSingleLine(p []byte) []byte {
+	trimmed := bytes.TrimRight(p, "\r\n")
+	line := make([]byte, 0, len(trimmed)+1)
+	for _, b := range trimmed {
+		if b == '\r' || b == '\n' {
+			b = ' '
+		}
+		line = append(line, b)
+	}
+	return append(line, '\n')
+}
@@ -0,0 +1,125 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+// countingWriter は書き込みの回数を数える io.Writer です。
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestHandler_Handle_newlineDelimited(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		args []any
+	}{
+		{
+			name: "メッセージに改行を含む場合",
+			msg:  "first line\nsecond line\n",
+			args: nil,
+		},
+		{
+			name: "メッセージに CRLF を含む場合",
+			msg:  "first line\r\nsecond line",
+			args: nil,
+		},
+		{
+			name: "属性の値とキーに改行を含む場合",
+			msg:  "multi-line attr",
+			args: []any{"stack", "goroutine 1\n\tmain.go:10", "key\nwith newline", "value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w countingWriter
+			logger := slog.New(sloggcloud.New(&w, sloggcloud.WithSource(false)))
+
+			logger.Info(tt.msg, tt.args...)
+
+			if w.writes != 1 {
+				t.Errorf("writes = %d, want 1", w.writes)
+			}
+			out := w.String()
+			if !strings.HasSuffix(out, "\n") || strings.Count(out, "\n") != 1 || strings.Contains(out, "\r") {
+				t.Fatalf("output is not a single line: %q", out)
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if diff := cmp.Diff(tt.msg, got["message"]); diff != "" {
+				t.Errorf("message mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "1行の場合はそのまま書き込む",
+			input: "{\"a\":1}\n",
+			want:  "{\"a\":1}\n",
+		},
+		{
+			name:  "末尾に改行がない場合は付与",
+			input: "{\"a\":1}",
+			want:  "{\"a\":1}\n",
+		},
+		{
+			name:  "末尾の複数の改行を1つにまとめる",
+			input: "{\"a\":1}\r\n\n",
+			want:  "{\"a\":1}\n",
+		},
+		{
+			name:  "途中の改行を空白に置き換え",
+			input: "{\n\"a\":1,\r\n\"b\":2\n}\n",
+			want:  "{ \"a\":1,  \"b\":2 }\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			n, err := sloggcloud.NewLineWriter(&buf).Write([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("failed to write: %v", err)
+			}
+			if n != len(tt.input) {
+				t.Errorf("n = %d, want %d", n, len(tt.input))
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("書き込みに失敗した場合はエラーを返す", func(t *testing.T) {
+		errWrite := errors.New("broken pipe")
+		_, err := sloggcloud.NewLineWriter(errWriter{err: errWrite}).Write([]byte("{}\n"))
+		if !errors.Is(err, errWrite) {
+			t.Errorf("err = %v, want %v", err, errWrite)
+		}
+	})
+}
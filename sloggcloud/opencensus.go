@@ -0,0 +1,30 @@
+package sloggcloud
+
+import (
+	"context"
+	"encoding/hex"
+)
+
+// OpenCensusSpanContext は OpenCensus のスパンのうち、ログに出力するトレース情報です。
+// go.opencensus.io/trace の TraceID と SpanID はそれぞれ [16]byte と [8]byte を基にした型のため、そのまま代入できます。
+type OpenCensusSpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// openCensusTraceIDFunc は fromContext で取得した OpenCensus のスパンを WithTraceIDFunc の形式に変換する関数を返します。
+func openCensusTraceIDFunc(fromContext func(ctx context.Context) OpenCensusSpanContext) func(ctx context.Context) (string, string, bool) {
+	return func(ctx context.Context) (string, string, bool) {
+		sc := fromContext(ctx)
+		// OpenCensus ではゼロのトレース ID は無効なため、スパンがないものとして扱う
+		if sc.TraceID == [16]byte{} {
+			return "", "", false
+		}
+		spanID := ""
+		if sc.SpanID != [8]byte{} {
+			spanID = hex.EncodeToString(sc.SpanID[:])
+		}
+		return hex.EncodeToString(sc.TraceID[:]), spanID, sc.Sampled
+	}
+}
@@ -0,0 +1,125 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// go.opencensus.io/trace の型を模したスタブです。
+type (
+	ocTraceID     [16]byte
+	ocSpanID      [8]byte
+	ocSpanContext struct {
+		TraceID      ocTraceID
+		SpanID       ocSpanID
+		TraceOptions uint32
+	}
+	ocSpanKey struct{}
+)
+
+// ocFromContext は go.opencensus.io/trace の FromContext と SpanContext を使ってスパンを変換する処理を模しています。
+func ocFromContext(ctx context.Context) sloggcloud.OpenCensusSpanContext {
+	sc, ok := ctx.Value(ocSpanKey{}).(ocSpanContext)
+	if !ok {
+		return sloggcloud.OpenCensusSpanContext{}
+	}
+	return sloggcloud.OpenCensusSpanContext{
+		TraceID: sc.TraceID,
+		SpanID:  sc.SpanID,
+		Sampled: sc.TraceOptions&1 == 1,
+	}
+}
+
+func TestWithOpenCensusTrace(t *testing.T) {
+	ocCtx := context.WithValue(context.Background(), ocSpanKey{}, ocSpanContext{
+		TraceID:      ocTraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:       ocSpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceOptions: 1,
+	})
+	otelCtx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+		SpanID:     trace.SpanID{0, 0, 0, 0, 0, 0, 0, 0x01},
+		TraceFlags: 0,
+	}))
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want map[string]interface{}
+	}{
+		{
+			name: "OpenCensusのスパンのトレース情報を出力",
+			ctx:  ocCtx,
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "message with trace",
+				"logging.googleapis.com/trace":         "projects/test-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+				"logging.googleapis.com/spanId":        "00f067aa0ba902b7",
+				"logging.googleapis.com/trace_sampled": true,
+			},
+		},
+		{
+			name: "スパンIDがない場合はトレースIDのみ出力",
+			ctx: context.WithValue(context.Background(), ocSpanKey{}, ocSpanContext{
+				TraceID:      ocTraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+				SpanID:       ocSpanID{},
+				TraceOptions: 0,
+			}),
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "message with trace",
+				"logging.googleapis.com/trace":         "projects/test-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+				"logging.googleapis.com/trace_sampled": false,
+			},
+		},
+		{
+			name: "OpenCensusのスパンがない場合はOpenTelemetryのスパンを使用",
+			ctx:  otelCtx,
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "message with trace",
+				"logging.googleapis.com/trace":         "projects/test-project/traces/0102030405060708090a0b0c0d0e0f10",
+				"logging.googleapis.com/spanId":        "0000000000000001",
+				"logging.googleapis.com/trace_sampled": false,
+			},
+		},
+		{
+			name: "スパンがない場合はトレース情報を出力しない",
+			ctx:  context.Background(),
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message with trace",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf,
+				sloggcloud.WithProjectID("test-project"),
+				sloggcloud.WithSource(false),
+				sloggcloud.WithOpenCensusTrace(ocFromContext),
+			))
+
+			logger.InfoContext(tt.ctx, "message with trace")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
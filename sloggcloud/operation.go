@@ -0,0 +1,54 @@
+package sloggcloud
+
+import (
+	"context"
+	"log/slog"
+)
+
+// operationKey は Cloud Logging の operation フィールドを出力するキーです。
+const operationKey = "logging.googleapis.com/operation"
+
+// Operation は関連する一連のログをまとめるための Cloud Logging の operation フィールドです。
+// 同じ ID と Producer を持つログは Logs Explorer でひとまとまりとして表示されます。
+type Operation struct {
+	// ID は operation の識別子です。
+	ID string
+	// Producer は operation の発生元です。
+	Producer string
+	// First は operation の最初のログであることを示します。
+	First bool
+	// Last は operation の最後のログであることを示します。
+	Last bool
+}
+
+type operationKeyType struct{}
+
+// ContextWithOperation は Operation を設定したコンテキストを返します。
+// このコンテキストを渡して出力したログには logging.googleapis.com/operation がトップレベルに付与されます。
+func ContextWithOperation(ctx context.Context, op Operation) context.Context {
+	return context.WithValue(ctx, operationKeyType{}, op)
+}
+
+// operationFromContext はコンテキストから Operation を取得します。
+func operationFromContext(ctx context.Context) (Operation, bool) {
+	op, ok := ctx.Value(operationKeyType{}).(Operation)
+	return op, ok
+}
+
+// attr は Operation を Cloud Logging の operation の形式の slog.Attr に変換します。
+func (op Operation) attr() slog.Attr {
+	attrs := make([]slog.Attr, 0, 4)
+	if op.ID != "" {
+		attrs = append(attrs, slog.String("id", op.ID))
+	}
+	if op.Producer != "" {
+		attrs = append(attrs, slog.String("producer", op.Producer))
+	}
+	if op.First {
+		attrs = append(attrs, slog.Bool("first", true))
+	}
+	if op.Last {
+		attrs = append(attrs, slog.Bool("last", true))
+	}
+	return slog.Attr{Key: operationKey, Value: slog.GroupValue(attrs...)}
+}
@@ -0,0 +1,101 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestContextWithOperation(t *testing.T) {
+	tests := []struct {
+		name        string
+		op          sloggcloud.Operation
+		setupGroups func(h *sloggcloud.Handler) slog.Handler
+		want        map[string]interface{}
+	}{
+		{
+			name: "最初のログを示すoperation",
+			op: sloggcloud.Operation{
+				ID:       "request-1",
+				Producer: "github.com/p1ass/go-pkg",
+				First:    true,
+			},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "operation message",
+				"logging.googleapis.com/operation": map[string]interface{}{
+					"id":       "request-1",
+					"producer": "github.com/p1ass/go-pkg",
+					"first":    true,
+				},
+			},
+		},
+		{
+			name: "最後のログを示すoperation",
+			op: sloggcloud.Operation{
+				ID:       "request-1",
+				Producer: "github.com/p1ass/go-pkg",
+				Last:     true,
+			},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "operation message",
+				"logging.googleapis.com/operation": map[string]interface{}{
+					"id":       "request-1",
+					"producer": "github.com/p1ass/go-pkg",
+					"last":     true,
+				},
+			},
+		},
+		{
+			name: "グループを指定してもトップレベルに出力",
+			op: sloggcloud.Operation{
+				ID:       "request-2",
+				Producer: "github.com/p1ass/go-pkg",
+			},
+			setupGroups: func(h *sloggcloud.Handler) slog.Handler {
+				return h.WithGroup("server")
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "operation message",
+				"logging.googleapis.com/operation": map[string]interface{}{
+					"id":       "request-2",
+					"producer": "github.com/p1ass/go-pkg",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := sloggcloud.New(&buf, sloggcloud.WithSource(false))
+			logger := slog.New(tt.setupGroups(handler))
+
+			ctx := sloggcloud.ContextWithOperation(context.Background(), tt.op)
+			logger.InfoContext(ctx, "operation message")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
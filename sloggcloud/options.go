@@ -1,14 +1,95 @@
 package sloggcloud
 
 import (
+	"compress/gzip"
+	"context"
+	"io"
 	"log/slog"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 )
 
 // options はハンドラーの設定オプションを保持する構造体です。
 type options struct {
-	level     slog.Level
-	addSource bool
-	projectID string
+	level        slog.Level
+	addSource    bool
+	addTraceInfo bool
+	projectID    string
+	messageKey   string
+	program      string
+	labels       map[string]string
+	// labelsFromContext はレコードごとにコンテキストからラベルを取得する
+	labelsFromContext func(context.Context) map[string]string
+	// severityMapper は slog.Level を Cloud Logging の severity に変換する
+	severityMapper        func(slog.Level) string
+	insertIDFunc          func(context.Context, slog.Record) string
+	errorReporting        *serviceContext
+	addStackTrace         bool
+	stackTraceLevel       slog.Level
+	redactKeys            map[string]struct{}
+	timeKey               string
+	timeFormat            string
+	projectIDFromMetadata bool
+	errorWriter           io.Writer
+	console               bool
+	levelVar              *slog.LevelVar
+	payloadMode           PayloadMode
+	resource              *monitoredResource
+	serviceContext        *serviceContext
+	logName               string
+	contentHashInsertID   bool
+	sampler               Sampler
+	rateLimiter           *rateLimiter
+	baggageLabels         bool
+	spanAttributeKeys     map[string]struct{}
+	initialAttrs          []slog.Attr
+	durationFormat        DurationFormat
+	errorUnwrap           bool
+	groupLevels           map[string]slog.Level
+	groupAsLabel          bool
+	bufferSize            int
+	bufferFlushInterval   time.Duration
+	replaceAttr           func(groups []string, a slog.Attr) slog.Attr
+	version               string
+	sourceKey             string
+	sourceShortFile       bool
+	sourceFallback        bool
+	binaryFormat          BinaryFormat
+	traceIDFunc           func(ctx context.Context) (traceID, spanID string, sampled bool)
+	maxAttrDepth          int
+	maxMessageBytes       int
+	maxEntryBytes         int
+	gzip                  bool
+	gzipLevel             int
+	onError               func(err error)
+	metricsHook           func(severity string)
+	spanEventOnError      bool
+	defaultAttrsFunc      func(ctx context.Context) []slog.Attr
+	labelKeySanitize      bool
+	maxLabels             int
+	callerSkip            int
+	timestampSource       TimestampSource
+	levelSeverities       map[slog.Level]string
+	sourceStackDepth      int
+	entrySizeHook         func(bytes int, truncated bool)
+	autoResource          bool
+	severityKey           string
+	largeIntAsString      bool
+	contextFuncTimeout    time.Duration
+	fieldAllowlist        map[string]struct{}
+	strict                bool
+	// configErrors はオプションの適用中に見つかった設定の誤り
+	configErrors []string
+	// sourceMinLevel はソースコードの位置情報を出力する最小のレベルで、nil の場合は全てのレベルで出力する
+	sourceMinLevel     slog.Leveler
+	flattenSeparator   string
+	attrPoolCapacity   int
+	valueScrubbers     []*regexp.Regexp
+	sourceFunctionTrim bool
 }
 
 // Option はハンドラーを設定するための関数型です。
@@ -17,9 +98,77 @@ type Option func(*options)
 // defaultOptions はデフォルトのオプション値を返します。
 func defaultOptions() *options {
 	return &options{
-		level:     slog.LevelInfo,
-		addSource: true,
-		projectID: "",
+		level:                 slog.LevelInfo,
+		addSource:             true,
+		addTraceInfo:          true,
+		projectID:             "",
+		messageKey:            "message",
+		program:               "",
+		labels:                nil,
+		labelsFromContext:     nil,
+		severityMapper:        levelToSeverity,
+		insertIDFunc:          nil,
+		errorReporting:        nil,
+		addStackTrace:         false,
+		stackTraceLevel:       slog.LevelError,
+		redactKeys:            nil,
+		timeKey:               slog.TimeKey,
+		timeFormat:            time.RFC3339Nano,
+		projectIDFromMetadata: false,
+		errorWriter:           nil,
+		console:               false,
+		levelVar:              nil,
+		payloadMode:           PayloadModeFlat,
+		resource:              nil,
+		serviceContext:        nil,
+		logName:               "",
+		contentHashInsertID:   false,
+		sampler:               nil,
+		rateLimiter:           nil,
+		baggageLabels:         false,
+		spanAttributeKeys:     nil,
+		initialAttrs:          nil,
+		durationFormat:        DurationFormatString,
+		errorUnwrap:           false,
+		groupLevels:           nil,
+		groupAsLabel:          false,
+		bufferSize:            0,
+		bufferFlushInterval:   0,
+		replaceAttr:           nil,
+		version:               "",
+		sourceKey:             sourceLocationKey,
+		sourceShortFile:       false,
+		sourceFallback:        false,
+		binaryFormat:          BinaryFormatBase64,
+		traceIDFunc:           nil,
+		maxAttrDepth:          0,
+		maxMessageBytes:       0,
+		maxEntryBytes:         0,
+		gzip:                  false,
+		gzipLevel:             0,
+		onError:               nil,
+		metricsHook:           nil,
+		spanEventOnError:      false,
+		defaultAttrsFunc:      nil,
+		labelKeySanitize:      false,
+		maxLabels:             0,
+		callerSkip:            0,
+		timestampSource:       TimestampSourceRecord,
+		levelSeverities:       nil,
+		sourceStackDepth:      1,
+		entrySizeHook:         nil,
+		autoResource:          false,
+		severityKey:           severityKey,
+		largeIntAsString:      false,
+		contextFuncTimeout:    0,
+		fieldAllowlist:        nil,
+		strict:                false,
+		configErrors:          nil,
+		sourceMinLevel:        nil,
+		flattenSeparator:      "",
+		attrPoolCapacity:      0,
+		valueScrubbers:        nil,
+		sourceFunctionTrim:    false,
 	}
 }
 
@@ -37,9 +186,655 @@ func WithSource(enabled bool) Option {
 	}
 }
 
+// WithTraceInfo は OpenTelemetry のトレース ID とスパン ID の出力を有効にします。
+func WithTraceInfo(enabled bool) Option {
+	return func(o *options) {
+		o.addTraceInfo = enabled
+	}
+}
+
 // WithProjectID は Google Cloud Project ID を設定します。
 func WithProjectID(projectID string) Option {
 	return func(o *options) {
 		o.projectID = projectID
 	}
 }
+
+// WithMessageKey はログメッセージを出力するキーを設定します。
+func WithMessageKey(key string) Option {
+	return func(o *options) {
+		o.messageKey = key
+	}
+}
+
+// WithProgram はログを出力したプログラム名を設定します。
+// 設定したプログラム名は logging.googleapis.com/labels の program キーとして出力されます。
+func WithProgram(program string) Option {
+	return func(o *options) {
+		o.program = program
+	}
+}
+
+// WithSeverityMapper は slog.Level から Cloud Logging の severity への変換方法を設定します。
+// 独自のレベル体系を利用している場合に、組み込みの変換を置き換えるために利用します。
+func WithSeverityMapper(mapper func(slog.Level) string) Option {
+	return func(o *options) {
+		o.severityMapper = mapper
+	}
+}
+
+// WithLabels は全てのログに付与するラベルを設定します。
+// ラベルは logging.googleapis.com/labels としてトップレベルに出力されます。
+func WithLabels(labels map[string]string) Option {
+	return func(o *options) {
+		o.labels = maps.Clone(labels)
+	}
+}
+
+// WithLabelsFromContext はレコードごとにコンテキストからラベルを取得する関数を設定します。
+// 取得したラベルは WithLabels で設定したラベルとマージされ、キーが重複した場合はこちらが優先されます。
+func WithLabelsFromContext(fn func(context.Context) map[string]string) Option {
+	return func(o *options) {
+		o.labelsFromContext = fn
+	}
+}
+
+// WithInsertIDFunc はレコードごとに logging.googleapis.com/insertId を生成する関数を設定します。
+// 関数が空文字列を返した場合、insertId は出力されません。
+func WithInsertIDFunc(fn func(context.Context, slog.Record) string) Option {
+	return func(o *options) {
+		o.insertIDFunc = fn
+	}
+}
+
+// WithErrorReporting は ERROR 以上のログを Error Reporting に送るための情報を出力します。
+// 有効にすると、ERROR 以上のログに @type・serviceContext・stack_trace が付与されます。
+func WithErrorReporting(service, version string) Option {
+	return func(o *options) {
+		o.errorReporting = &serviceContext{service: service, version: version}
+	}
+}
+
+// WithStackTrace は minLevel 以上のログにスタックトレースを stack_trace として付与します。
+// スタックトレースはログの出力箇所から始まり、slog やハンドラの内部のフレームは含みません。
+func WithStackTrace(minLevel slog.Level) Option {
+	return func(o *options) {
+		o.addStackTrace = true
+		o.stackTraceLevel = minLevel
+	}
+}
+
+// WithRedactKeys は指定したキーを持つ属性の値を "[REDACTED]" に置き換えます。
+// キーは大文字と小文字を区別せずに比較され、グループの中の属性も対象になります。
+func WithRedactKeys(keys ...string) Option {
+	return func(o *options) {
+		if o.redactKeys == nil {
+			o.redactKeys = make(map[string]struct{}, len(keys))
+		}
+		for _, key := range keys {
+			o.redactKeys[strings.ToLower(key)] = struct{}{}
+		}
+	}
+}
+
+// WithTimeKey はログの時刻を出力するキーを設定します。
+func WithTimeKey(key string) Option {
+	return func(o *options) {
+		o.timeKey = key
+	}
+}
+
+// WithTimeFormat はログの時刻のフォーマットを time.Time.Format のレイアウトで設定します。
+// slog.Time などで追加した time.Time の属性も同じフォーマットで出力します。
+func WithTimeFormat(layout string) Option {
+	return func(o *options) {
+		o.timeFormat = layout
+	}
+}
+
+// WithProjectIDFromMetadata は New の呼び出し時にメタデータサーバーから Google Cloud Project ID を取得します。
+// 取得できなかった場合は Project ID を空のまま扱います。WithProjectID で明示的に指定した値が優先されます。
+func WithProjectIDFromMetadata() Option {
+	return func(o *options) {
+		o.projectIDFromMetadata = true
+	}
+}
+
+// WithErrorWriter は ERROR 以上のログの書き込み先を設定します。
+// 設定した場合、ERROR 未満のログは New に渡した書き込み先に、ERROR 以上のログはこの書き込み先に出力されます。
+func WithErrorWriter(w io.Writer) Option {
+	return func(o *options) {
+		o.errorWriter = w
+	}
+}
+
+// WithConsole はローカル開発向けに、JSON ではなく人が読みやすい形式でログを出力します。
+// 有効にすると "time severity message key=value ..." の形式で出力し、severity を色付けします。
+func WithConsole(enabled bool) Option {
+	return func(o *options) {
+		o.console = enabled
+	}
+}
+
+// WithLevelVar は実行中に変更できる最小ログレベルを設定します。
+// WithLevel と併用した場合は、こちらが優先されます。
+func WithLevelVar(levelVar *slog.LevelVar) Option {
+	return func(o *options) {
+		o.levelVar = levelVar
+	}
+}
+
+// leveler は最小ログレベルを返します。WithLevelVar が指定されている場合はその値を動的に参照します。
+func (o *options) leveler() slog.Leveler {
+	if o.levelVar != nil {
+		return o.levelVar
+	}
+	return o.level
+}
+
+// WithLevelFromEnv は環境変数 name から最小ログレベルを読み込みます。
+// DEBUG / INFO / WARN / ERROR に加えて WARNING などの severity 名も大文字小文字を区別せずに受け付けます。
+// 環境変数が未設定または解析できない場合は最小ログレベルを変更しません。
+func WithLevelFromEnv(name string) Option {
+	return func(o *options) {
+		if level, ok := parseLevel(os.Getenv(name)); ok {
+			o.level = level
+		}
+	}
+}
+
+// WithPayloadMode はユーザーが指定した属性の配置方法を設定します。
+// PayloadModeNested を指定すると、属性は attributes オブジェクトの下にまとめて出力されます。
+func WithPayloadMode(mode PayloadMode) Option {
+	return func(o *options) {
+		o.payloadMode = mode
+	}
+}
+
+// WithMonitoredResource はログの出力元となるモニタリング対象リソースを設定します。
+// 設定した resource はトップレベルに出力され、Logs Explorer で指定したリソースのログとして関連付けられます。
+func WithMonitoredResource(resType string, labels map[string]string) Option {
+	return func(o *options) {
+		o.resource = &monitoredResource{resType: resType, labels: maps.Clone(labels)}
+	}
+}
+
+// WithServiceContext は ERROR 以上のログに serviceContext を付与します。
+// WithErrorReporting と異なり @type や stack_trace は付与せず、Error Reporting がエラーをグループ化するための情報のみを出力します。
+// WithErrorReporting と併用した場合は、こちらの service と version が優先されます。
+func WithServiceContext(service, version string) Option {
+	return func(o *options) {
+		o.serviceContext = &serviceContext{service: service, version: version}
+	}
+}
+
+// WithLogName はログの出力先となるログ名を logging.googleapis.com/logName として出力します。
+// name が空の場合は無視し、Cloud Logging が受け付けない文字を含む場合は標準エラー出力に警告を出力します。
+func WithLogName(name string) Option {
+	return func(o *options) {
+		if name == "" {
+			o.warnf("log name must not be empty")
+			return
+		}
+		if !isValidLogName(name) {
+			o.warnf("log name %q is rejected by Cloud Logging: use up to 512 characters of [A-Za-z0-9/_.-]", name)
+		}
+		o.logName = name
+	}
+}
+
+// WithContentHashInsertID は severity・メッセージ・属性のハッシュから決定的な insertId を生成します。
+// 同じ内容のログは同じ insertId になるため、少なくとも 1 回配送するパイプラインでも Cloud Logging で重複排除されます。
+// WithInsertIDFunc が指定されている場合はそちらが優先されます。
+func WithContentHashInsertID(enabled bool) Option {
+	return func(o *options) {
+		o.contentHashInsertID = enabled
+	}
+}
+
+// WithSampler はレコードを出力するかどうかを判定する Sampler を設定します。
+// Sampler が false を返したレコードは出力されません。
+// RandomSampler と PerKeySampler は ERROR 以上のレコードを常に出力しますが、独自の Sampler ではレベルに関わらず判定結果に従います。
+func WithSampler(sampler Sampler) Option {
+	return func(o *options) {
+		o.sampler = sampler
+	}
+}
+
+// WithRateLimit はトークンバケットで 1 秒あたりに出力するレコードを perSecond 件、連続して出力できるレコードを burst 件までに制限します。
+// 制限を超えたレコードは破棄され、破棄した件数は 1 秒ごとに WARNING のログとして出力されます。
+// 最後の集計以降に破棄した件数は、Flush や Close を呼び出したときにも出力されます。
+// 制限は WithAttrs や WithGroup で派生した Handler 間で共有されます。
+// perSecond または burst が 0 以下の場合は全てのレコードが破棄されてしまうため、標準エラー出力に警告を出力して流量を制限しません。
+func WithRateLimit(perSecond int, burst int) Option {
+	return func(o *options) {
+		if perSecond <= 0 || burst <= 0 {
+			o.warnf("rate limit %d per second with burst %d is invalid: disable the rate limit", perSecond, burst)
+			o.rateLimiter = nil
+			return
+		}
+		o.rateLimiter = newRateLimiter(perSecond, burst)
+	}
+}
+
+// WithBaggageLabels はコンテキストの OpenTelemetry の baggage のメンバーを logging.googleapis.com/labels に追加します。
+// キーは Cloud Logging のラベルとして使える形式に変換されます。WithLabelsFromContext と同じキーの場合はそちらが優先されます。
+func WithBaggageLabels(enabled bool) Option {
+	return func(o *options) {
+		o.baggageLabels = enabled
+	}
+}
+
+// WithSpanAttributes はコンテキストのスパンが持つ属性のうち、keys に指定したものをログの属性として出力します。
+// OpenTelemetry SDK のスパンのように属性を参照できるスパンのみが対象で、それ以外のスパンの場合は何も出力しません。
+func WithSpanAttributes(keys ...string) Option {
+	return func(o *options) {
+		o.spanAttributeKeys = make(map[string]struct{}, len(keys))
+		for _, key := range keys {
+			o.spanAttributeKeys[key] = struct{}{}
+		}
+	}
+}
+
+// WithInitialAttrs は New で作成する Handler にあらかじめ属性を付与します。
+// 付与した属性は WithAttrs で追加した属性と同じように扱われます。
+func WithInitialAttrs(attrs ...slog.Attr) Option {
+	return func(o *options) {
+		o.initialAttrs = append(slices.Clip(o.initialAttrs), attrs...)
+	}
+}
+
+// WithDurationFormat は slog.KindDuration の属性の出力形式を設定します。
+func WithDurationFormat(format DurationFormat) Option {
+	return func(o *options) {
+		o.durationFormat = format
+	}
+}
+
+// WithErrorUnwrap は値が error の属性を、message と原因となったエラーのメッセージの配列 causes を持つオブジェクトとして出力します。
+// 無効の場合、error の属性は Error() の文字列として出力されます。
+func WithErrorUnwrap(enabled bool) Option {
+	return func(o *options) {
+		o.errorUnwrap = enabled
+	}
+}
+
+// WithGroupLevel は group から始まるグループを持つ Handler の最小ログレベルを設定します。
+// logger.WithGroup("db") のようにサブシステムごとにグループを分けることで、そのサブシステムだけログレベルを変更できます。
+func WithGroupLevel(group string, level slog.Level) Option {
+	return func(o *options) {
+		o.groupLevels = maps.Clone(o.groupLevels)
+		if o.groupLevels == nil {
+			o.groupLevels = make(map[string]slog.Level)
+		}
+		o.groupLevels[group] = level
+	}
+}
+
+// WithGroupAsLabel は最も外側のグループ名を logging.googleapis.com/labels の component としても出力します。
+// 属性のグループによる入れ子はそのまま出力されます。
+func WithGroupAsLabel(enabled bool) Option {
+	return func(o *options) {
+		o.groupAsLabel = enabled
+	}
+}
+
+// WithBuffer は書き込みを size バイトまでまとめてから書き出します。
+// バッファは一杯になった時と flushInterval ごとに書き出されます。flushInterval が 0 以下の場合は定期的な書き出しを行いません。
+// 書き出し用の goroutine を止めて残りのログを書き出すため、終了時には Handler の Close を呼び出してください。
+func WithBuffer(size int, flushInterval time.Duration) Option {
+	return func(o *options) {
+		o.bufferSize = size
+		o.bufferFlushInterval = flushInterval
+	}
+}
+
+// WithReplaceAttr は JSON 形式で出力する属性を書き換える関数を設定します。
+// 関数には severity や時刻などを Cloud Logging の形式に変換した後の属性が渡されます。
+// slog.HandlerOptions の ReplaceAttr と同様に、空のキーを返した属性は出力されません。
+func WithReplaceAttr(fn func(groups []string, a slog.Attr) slog.Attr) Option {
+	return func(o *options) {
+		o.replaceAttr = fn
+	}
+}
+
+// WithVersion はアプリケーションのバージョンを logging.googleapis.com/labels の version として出力します。
+// version が空の場合は、ビルド情報に埋め込まれた VCS のリビジョンまたはモジュールのバージョンを使います。
+// WithErrorReporting でバージョンを指定していない場合は、serviceContext の version にも使われます。
+func WithVersion(version string) Option {
+	return func(o *options) {
+		if version == "" {
+			version = versionFromBuildInfo()
+		}
+		o.version = version
+	}
+}
+
+// WithSourceKey はソースコードの位置情報を出力するキーを設定します。
+// Cloud Logging 以外の基盤にログを取り込む場合など、logging.googleapis.com/sourceLocation 以外のキーで出力したい場合に使用します。
+// 空文字列を指定した場合はデフォルトのキーを使用します。
+func WithSourceKey(key string) Option {
+	return func(o *options) {
+		if key == "" {
+			key = sourceLocationKey
+		}
+		o.sourceKey = key
+	}
+}
+
+// WithSourceShortFile はソースコードの位置情報の file をファイル名のみに短縮するかどうかを設定します。
+func WithSourceShortFile(enabled bool) Option {
+	return func(o *options) {
+		o.sourceShortFile = enabled
+	}
+}
+
+// WithSourceFallback は呼び出し元の情報がないレコードでも、ソースコードの位置情報を出力するかどうかを設定します。
+// 有効にすると、手動で作成したレコードなど PC が 0 のレコードに対して function が "unknown" の位置情報を出力し、
+// 位置情報の出力を有効にしているのに取得できなかったことを判別できるようにします。
+func WithSourceFallback(enabled bool) Option {
+	return func(o *options) {
+		o.sourceFallback = enabled
+	}
+}
+
+// WithBinaryFormat は []byte の属性の出力形式を設定します。
+func WithBinaryFormat(format BinaryFormat) Option {
+	return func(o *options) {
+		o.binaryFormat = format
+	}
+}
+
+// WithTraceIDFunc はレコードごとにコンテキストからトレース情報を取得する関数を設定します。
+// OpenTelemetry 以外のトレーシングライブラリを使用している場合などに利用します。
+// 設定した場合は OpenTelemetry のスパンや ContextWithTrace よりも優先し、f が空のトレース ID を返した場合のみそれらを使用します。
+func WithTraceIDFunc(f func(ctx context.Context) (traceID, spanID string, sampled bool)) Option {
+	return func(o *options) {
+		o.traceIDFunc = f
+	}
+}
+
+// WithMaxAttrDepth はグループの入れ子の深さの上限を設定します。
+// WithGroup と属性のグループを合わせて n 段より深いグループは出力せず、代わりにその親に "_truncated": true を出力します。
+// 自動生成されたログなどで入れ子が深くなりすぎ、ペイロードが肥大化するのを防ぎます。0 以下の場合は制限しません。
+func WithMaxAttrDepth(n int) Option {
+	return func(o *options) {
+		o.maxAttrDepth = n
+	}
+}
+
+// WithMaxMessageBytes はメッセージの最大バイト数を設定します。
+// 超えた場合はメッセージを "...[truncated]" を含めて n バイト以下に切り詰め、truncated ラベルに "true" を出力します。
+// 0 以下の場合は制限しません。JSON 形式で出力する場合のみ有効です。
+func WithMaxMessageBytes(n int) Option {
+	return func(o *options) {
+		o.maxMessageBytes = n
+	}
+}
+
+// WithMaxEntryBytes は改行を含むエントリ全体の最大バイト数を設定します。
+// Cloud Logging は 256KB を超えるエントリを受け付けないため、超えた場合は Cloud Logging が特別に扱うフィールド以外を大きい順に取り除き、
+// それでも収まらない場合はメッセージを切り詰めます。切り詰めた場合は truncated ラベルに "true" を出力します。
+// 0 以下の場合は制限しません。JSON 形式で出力する場合のみ有効です。
+func WithMaxEntryBytes(n int) Option {
+	return func(o *options) {
+		o.maxEntryBytes = n
+	}
+}
+
+// WithGzip は書き込み先に gzip で圧縮したログを出力します。
+// level には gzip.DefaultCompression や gzip.BestSpeed などの圧縮レベルを指定し、不正な値の場合は gzip.DefaultCompression を使用します。
+// gzip のトレーラーを書き出すため、終了時に Handler.Close を呼び出してください。WithErrorWriter の書き込み先は圧縮しません。
+func WithGzip(level int) Option {
+	return func(o *options) {
+		if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+			o.warnf("gzip level %d is invalid: use gzip.DefaultCompression instead", level)
+			level = gzip.DefaultCompression
+		}
+		o.gzip = true
+		o.gzipLevel = level
+	}
+}
+
+// WithOnError は Handle の内部でログの書き込みに失敗した場合に呼び出す関数を設定します。
+// slog.Logger は Handle が返すエラーを無視するため、失敗をメトリクスに記録したり別の出力先に書き出したりする場合に使用します。
+// f から同じ Handler にログを出力すると失敗と呼び出しが繰り返されるため、別の出力先を使用してください。
+func WithOnError(f func(err error)) Option {
+	return func(o *options) {
+		o.onError = f
+	}
+}
+
+// WithMetricsHook は出力したレコードごとに、そのレコードの severity を渡して呼び出す関数を設定します。
+// サンプリングやレベルで出力しなかったレコードと、書き込みに失敗したレコードでは呼び出しません。
+// severity ごとのログの件数をメトリクスとして記録する場合などに、特定のメトリクスのライブラリに依存せずに利用できます。
+func WithMetricsHook(f func(severity string)) Option {
+	return func(o *options) {
+		o.metricsHook = f
+	}
+}
+
+// WithSpanEventOnError は ERROR 以上のレコードを、コンテキストの記録中のスパンにイベントとして記録するかどうかを設定します。
+// レコードの属性にエラーがある場合は span.RecordError で、ない場合は span.AddEvent でメッセージと属性を記録します。
+func WithSpanEventOnError(enabled bool) Option {
+	return func(o *options) {
+		o.spanEventOnError = enabled
+	}
+}
+
+// WithDefaultAttrsFunc はレコードごとに全てのログに付与する属性を返す関数を設定します。
+// メモリの使用量や goroutine の数など、ログを出力する時点で計算する属性を呼び出し側で指定せずに付与できます。
+// f はレコードを出力するたびに呼び出されるため、時間のかかる処理は避けてください。
+func WithDefaultAttrsFunc(f func(ctx context.Context) []slog.Attr) Option {
+	return func(o *options) {
+		o.defaultAttrsFunc = f
+	}
+}
+
+// WithLabelKeySanitize はラベルのキーを [a-z][a-z0-9_]* の形式に変換するかどうかを設定します。
+// 有効にすると、キーを小文字にし、英小文字・数字・アンダースコア以外の文字をアンダースコアに置き換えます。
+// "X-Request-ID" は "x_request_id" に、"user.id" は "user_id" になります。
+func WithLabelKeySanitize(enabled bool) Option {
+	return func(o *options) {
+		o.labelKeySanitize = enabled
+	}
+}
+
+// WithMaxLabels はエントリに出力するラベルの数の上限を設定します。
+// Cloud Logging はエントリあたりのラベルの数に上限があり、超えたエントリは拒否されることがあるため、
+// 上限を超えた場合はキーの辞書順で先頭から n 個をラベルとして出力し、残りは jsonPayload の labels_overflow に出力します。
+// 0 以下の場合は制限しません。
+func WithMaxLabels(n int) Option {
+	return func(o *options) {
+		o.maxLabels = n
+	}
+}
+
+// WithCallerSkip はソースコードの位置情報として、ログを出力した関数から n 個さかのぼった呼び出し元を出力します。
+// slog.Logger をラップした関数からログを出力する場合に、ラップした関数ではなくその呼び出し元を出力するために使用します。
+// レコードの PC がラップした関数を指し、同じ goroutine で同期的に Handle が呼び出される場合のみ有効です。
+func WithCallerSkip(n int) Option {
+	return func(o *options) {
+		o.callerSkip = n
+	}
+}
+
+// WithTimestampSource はログの時刻として出力する時刻の取得元を設定します。
+// デフォルトはレコードを作成した時刻 (TimestampSourceRecord) です。
+// 不正な値を指定した場合は警告を出力し、TimestampSourceRecord を使用します。
+func WithTimestampSource(source TimestampSource) Option {
+	return func(o *options) {
+		if source != TimestampSourceRecord && source != TimestampSourceNow {
+			o.warnf("timestamp source %q is invalid: use %q instead", source, TimestampSourceRecord)
+			source = TimestampSourceRecord
+		}
+		o.timestampSource = source
+	}
+}
+
+// WithOpenCensusTrace は OpenCensus のスパンからトレース情報を取得して出力します。
+// OpenCensus を使用しない場合に依存関係が増えないように、スパンの取得は fromContext で行います。
+// 通常は go.opencensus.io/trace の FromContext で取得したスパンの SpanContext を変換して返します。
+// fromContext がゼロのトレース ID を返した場合は、OpenTelemetry のスパンや ContextWithTrace のトレース情報を使用します。
+// WithTraceIDFunc を使用して実装しているため、WithTraceIDFunc と併用した場合は後に指定したものが有効になります。
+func WithOpenCensusTrace(fromContext func(ctx context.Context) OpenCensusSpanContext) Option {
+	return WithTraceIDFunc(openCensusTraceIDFunc(fromContext))
+}
+
+// WithLevelSeverity は level と等しいレベルのログの severity を severity に置き換えます。
+// WithSeverityMapper のように変換全体を置き換えずに、slog.LevelWarn を NOTICE にするなど一部の対応だけを変更したい場合に利用します。
+// WithSeverityMapper と併用した場合は、指定した順序に関わらず WithLevelSeverity を優先します。
+// severity が Cloud Logging の severity 名でない場合は警告を出力し、設定を無視します。
+func WithLevelSeverity(level slog.Level, severity string) Option {
+	return func(o *options) {
+		if !isSeverity(severity) {
+			o.warnf("severity %q is invalid: ignore the override for level %s", severity, level)
+			return
+		}
+		if o.levelSeverities == nil {
+			o.levelSeverities = map[slog.Level]string{}
+		}
+		o.levelSeverities[level] = severity
+	}
+}
+
+// WithSourceStackDepth はソースコードの位置情報に加えて、呼び出し元から順に最大 depth 個のフレームを callStack として出力します。
+// 1 以下の場合は callStack を出力せず、logging.googleapis.com/sourceLocation の 1 フレームのみを出力します。
+// ログを出力した goroutine の外で Handle を呼び出した場合など、スタックをさかのぼれない場合は 1 フレームのみを出力します。
+// WithSource(false) の場合は出力しません。
+func WithSourceStackDepth(depth int) Option {
+	return func(o *options) {
+		o.sourceStackDepth = depth
+	}
+}
+
+// WithEntrySizeHook は 1 行の JSON として書き込んだエントリごとに呼び出す関数を設定します。
+// f は改行を含むエントリのバイト数と、WithMaxMessageBytes や WithMaxEntryBytes で切り詰めたかどうかを受け取ります。
+// バイト数は切り詰めた後、圧縮する前の大きさで、切り詰めの上限を調整するための指標の収集に利用します。
+// 書き込みに失敗したエントリでは呼び出しません。コンソール形式で出力する場合は呼び出しません。
+// f はログの出力と同期的に呼び出されるため、すぐに処理を返す必要があります。
+func WithEntrySizeHook(f func(bytes int, truncated bool)) Option {
+	return func(o *options) {
+		o.entrySizeHook = f
+	}
+}
+
+// WithAutoResource は実行環境からモニタリング対象リソースを判定して出力します。
+// Cloud Run (K_SERVICE、K_REVISION)、App Engine (GAE_SERVICE)、GKE (KUBERNETES_SERVICE_HOST) は環境変数で判定し、
+// いずれでもない場合はメタデータサーバーに問い合わせられれば Compute Engine と判定します。
+// 場所などのラベルはメタデータサーバーから取得するため、New の呼び出しがブロックすることがあります。
+// WithMonitoredResource を指定した場合はそちらを優先します。
+func WithAutoResource() Option {
+	return func(o *options) {
+		o.autoResource = true
+	}
+}
+
+// WithSeverityKey は severity を出力するキーを設定します。
+// Cloud Logging 以外の基盤にログを取り込む場合など、level などのキーで出力したい場合に使用します。
+// Cloud Logging は severity キーの値のみをログの重大度として扱うため、変更すると重大度が DEFAULT として扱われます。
+// 空文字列を指定した場合はデフォルトのキーを使用します。
+func WithSeverityKey(key string) Option {
+	return func(o *options) {
+		if key == "" {
+			key = severityKey
+		}
+		o.severityKey = key
+	}
+}
+
+// WithLargeIntAsString は float64 で誤差なく表せない整数 (絶対値が 2^53 以上) の属性を文字列として出力するかどうかを設定します。
+// 整数は常に正確な JSON の数値として出力しますが、Cloud Logging の jsonPayload は数値を float64 として保持するため、
+// 2^53 を超える ID などは取り込まれた時点で値が変わります。値を正確に残したい場合に有効にします。
+func WithLargeIntAsString(enabled bool) Option {
+	return func(o *options) {
+		o.largeIntAsString = enabled
+	}
+}
+
+// WithContextFuncTimeout は WithLabelsFromContext、WithDefaultAttrsFunc、WithTraceIDFunc で設定した関数を呼び出す時間の上限を設定します。
+// 関数が時間内に終わらなかった場合は、その関数によるラベルや属性、トレース情報を出力せずにログを出力します。
+// 関数には期限を設定したコンテキストを渡すため、期限を過ぎたら早めに処理を返すようにしてください。
+// 0 以下の場合は上限を設けずに、ログを出力する goroutine で関数を呼び出します。
+// 上限を設けた場合はレコードごとに goroutine を起動するため、関数が遅くなりうる場合にのみ指定してください。
+func WithContextFuncTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.contextFuncTimeout = d
+	}
+}
+
+// WithFieldAllowlist は指定したキー以外の属性を出力しないように設定します。
+// 対象はレコードや WithAttrs などで追加した属性のキーで、slog.Group の場合はグループ名で判定します。
+// WithGroup を使用した場合は、グループの中に入れる前の属性のキーで判定します。
+// severity や httpRequest など、ハンドラ自身が出力するフィールドは常に出力します。
+// スキーマが決まっているログの基盤で、想定していない属性から個人情報などが漏れることを防ぐために利用します。
+// 複数回指定した場合は、全てのキーを許可します。
+func WithFieldAllowlist(keys ...string) Option {
+	return func(o *options) {
+		if o.fieldAllowlist == nil {
+			o.fieldAllowlist = make(map[string]struct{}, len(keys))
+		}
+		for _, key := range keys {
+			o.fieldAllowlist[key] = struct{}{}
+		}
+	}
+}
+
+// WithStrict は設定の誤りを検出した場合に New で panic させます。
+// 通常は警告を出力して動作を続けるところを、Project ID がなくトレースを関連付けられない場合や、
+// WithConsole と併用して効果のないオプション、Cloud Logging が受け付けないラベルのキーも誤りとして扱います。
+// テストや CI で設定の誤りを早期に検出するためのもので、本番環境では有効にしないでください。
+func WithStrict(enabled bool) Option {
+	return func(o *options) {
+		o.strict = enabled
+	}
+}
+
+// WithSourceMinLevel は level 以上のログにのみソースコードの位置情報を出力します。
+// 位置情報の取得はログ 1 件ごとのコストが大きいため、INFO などの頻繁に出力するログで取得を省きたい場合に利用します。
+// WithSource(false) の場合はレベルに関わらず出力しません。
+func WithSourceMinLevel(level slog.Level) Option {
+	return func(o *options) {
+		o.sourceMinLevel = level
+	}
+}
+
+// WithFlattenGroups はグループを入れ子の JSON オブジェクトにせず、グループ名を separator でつないだキーで出力します。
+// 例えば separator に "." を指定すると、WithGroup("db") と slog.Group("query", slog.Duration("duration", d)) の属性は
+// db.query.duration として出力されます。入れ子のフィールドを扱いにくいログのフィルタや集計で利用します。
+// 空文字列の場合はグループを入れ子にして出力します。
+func WithFlattenGroups(separator string) Option {
+	return func(o *options) {
+		o.flattenSeparator = separator
+	}
+}
+
+// WithAttrPoolCapacity はレコードごとに属性を組み立てるスライスを、容量 n で確保してから再利用します。
+// 指定しない場合は全ての Handler で共有するプールを使い、容量はこれまでのレコードの属性の数から見積もるため、
+// 属性の多いアプリケーションでは最初の数件のログでスライスの確保し直しが発生します。
+// コンテキストや WithDefaultAttrsFunc の属性も含めた、1 件のログの典型的な属性の数を指定してください。
+// 64 を超える属性のスライスは通常は再利用しませんが、n まではプールに戻して再利用します。0 以下の場合は指定しない場合と同じです。
+func WithAttrPoolCapacity(n int) Option {
+	return func(o *options) {
+		o.attrPoolCapacity = n
+	}
+}
+
+// WithValueScrubber は文字列の属性とメッセージのうち、patterns のいずれかに一致した部分を "[SCRUBBED]" に置き換えます。
+// WithRedactKeys がキーで属性全体を隠すのに対し、自由記述のメッセージや属性に紛れ込んだメールアドレスやトークンなどを値から検出して隠します。
+// グループの中の属性も対象になりますが、構造体などの文字列以外の値は対象になりません。全てのログで正規表現を評価するため、パターンは必要なものに絞ってください。
+// トレース ID や httpRequest など、ハンドラが出力する logging.googleapis.com/ で始まるフィールドは置き換えません。
+func WithValueScrubber(patterns ...*regexp.Regexp) Option {
+	return func(o *options) {
+		o.valueScrubbers = append(o.valueScrubbers, patterns...)
+	}
+}
+
+// WithSourceFunctionTrim はソースコードの位置情報の関数名からインポートパスを取り除き、pkg.Func の形式で出力します。
+// 関数名は github.com/org/repo/pkg.Func のように完全なインポートパスを含み、全てのログで長くなるため、短くしたい場合に利用します。
+// stack_trace は Error Reporting が解釈できるように完全な関数名のまま出力します。
+func WithSourceFunctionTrim(enabled bool) Option {
+	return func(o *options) {
+		o.sourceFunctionTrim = enabled
+	}
+}
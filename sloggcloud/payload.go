@@ -0,0 +1,61 @@
+package sloggcloud
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// PayloadMode はユーザーが指定した属性を jsonPayload にどのように配置するかを表します。
+type PayloadMode string
+
+const (
+	// PayloadModeFlat はユーザーの属性を jsonPayload のトップレベルに出力します。
+	// Cloud Logging が特別に扱うキーと衝突した属性には reservedKeyPrefix が付与されます。
+	// ただし logging.googleapis.com/trace などのトレース情報の属性はそのまま出力し、スパンから取得したトレース情報より優先します。
+	PayloadModeFlat PayloadMode = "flat"
+	// PayloadModeNested はユーザーの属性を attributes オブジェクトの下にまとめて出力します。
+	PayloadModeNested PayloadMode = "nested"
+)
+
+const (
+	// attributesKey は PayloadModeNested でユーザーの属性をまとめるキーです。
+	attributesKey = "attributes"
+	// reservedKeyPrefix は PayloadModeFlat で予約済みのキーと衝突した属性に付与するプレフィックスです。
+	reservedKeyPrefix = "attr_"
+	// reservedKeyNamespace は Cloud Logging が特別に扱うキーの名前空間です。
+	reservedKeyNamespace = "logging.googleapis.com/"
+)
+
+// payloadAttrs はユーザーの属性を PayloadMode に従って配置し直します。
+func (h *Handler) payloadAttrs(attrs []slog.Attr) []slog.Attr {
+	if len(attrs) == 0 {
+		return attrs
+	}
+
+	if h.opts.payloadMode == PayloadModeNested {
+		return []slog.Attr{{Key: attributesKey, Value: slog.GroupValue(attrs...)}}
+	}
+
+	// 同じキーが重複すると Cloud Logging が予約済みのフィールドを正しく解釈できないため、ユーザーの属性をリネームする
+	// トレース情報は明示的に指定したものを優先し、topLevelAttrs でスパンから取得したものを出力しない
+	for i, attr := range attrs {
+		if h.isReservedKey(attr.Key) && !isTraceKey(attr.Key) {
+			attrs[i].Key = reservedKeyPrefix + attr.Key
+		}
+	}
+	return attrs
+}
+
+// isReservedKey は key がハンドラ自身の出力するフィールドと衝突するかどうかを返します。
+func (h *Handler) isReservedKey(key string) bool {
+	switch key {
+	// slog の組み込みのキーは cloudLoggingReplaceAttr で変換されるため、ユーザーの属性が同じキーだと変換後のキーが重複する
+	case slog.LevelKey, slog.MessageKey, slog.TimeKey:
+		return true
+	case h.opts.severityKey, h.opts.messageKey, h.opts.timeKey, httpRequestKey, resourceKey, "@type", "serviceContext", stackTraceKey, h.opts.sourceKey:
+		return true
+	case callStackKey:
+		return h.opts.addSource && h.opts.sourceStackDepth > 1
+	}
+	return strings.HasPrefix(key, reservedKeyNamespace)
+}
@@ -0,0 +1,205 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithPayloadMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []sloggcloud.Option
+		groups []string
+		args   []slog.Attr
+		want   map[string]interface{}
+	}{
+		{
+			name: "デフォルトではトップレベルに出力",
+			opts: []sloggcloud.Option{},
+			args: []slog.Attr{slog.String("user_id", "x")},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "hello",
+				"user_id":  "x",
+			},
+		},
+		{
+			name: "flatではseverityと衝突するキーにプレフィックスを付与",
+			opts: []sloggcloud.Option{sloggcloud.WithPayloadMode(sloggcloud.PayloadModeFlat)},
+			args: []slog.Attr{
+				slog.String("severity", "user value"),
+				slog.String("user_id", "x"),
+			},
+			want: map[string]interface{}{
+				"severity":      "INFO",
+				"message":       "hello",
+				"attr_severity": "user value",
+				"user_id":       "x",
+			},
+		},
+		{
+			name: "flatではmessageとlogging.googleapis.comのキーにもプレフィックスを付与",
+			opts: []sloggcloud.Option{sloggcloud.WithPayloadMode(sloggcloud.PayloadModeFlat)},
+			args: []slog.Attr{
+				slog.String("message", "user message"),
+				slog.String("logging.googleapis.com/insertId", "user insert id"),
+			},
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "hello",
+				"attr_message":                         "user message",
+				"attr_logging.googleapis.com/insertId": "user insert id",
+			},
+		},
+		{
+			name: "flatではslogのメッセージのキーmsgにもプレフィックスを付与",
+			opts: []sloggcloud.Option{sloggcloud.WithPayloadMode(sloggcloud.PayloadModeFlat)},
+			args: []slog.Attr{
+				slog.String("msg", "user msg"),
+				slog.String("level", "user level"),
+			},
+			want: map[string]interface{}{
+				"severity":   "INFO",
+				"message":    "hello",
+				"attr_msg":   "user msg",
+				"attr_level": "user level",
+			},
+		},
+		{
+			name:   "グループの中のmsgはメッセージのキーに変換しない",
+			opts:   []sloggcloud.Option{sloggcloud.WithPayloadMode(sloggcloud.PayloadModeFlat)},
+			groups: []string{"req"},
+			args:   []slog.Attr{slog.String("msg", "x")},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "hello",
+				"req": map[string]interface{}{
+					"msg": "x",
+				},
+			},
+		},
+		{
+			name: "flatではWithMessageKeyで変更したキーと衝突した場合にプレフィックスを付与",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithPayloadMode(sloggcloud.PayloadModeFlat),
+				sloggcloud.WithMessageKey("msg"),
+			},
+			args: []slog.Attr{
+				slog.String("msg", "user message"),
+				slog.String("message", "not reserved"),
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"msg":      "hello",
+				"attr_msg": "user message",
+				"message":  "not reserved",
+			},
+		},
+		{
+			name: "nestedではattributesの下に出力",
+			opts: []sloggcloud.Option{sloggcloud.WithPayloadMode(sloggcloud.PayloadModeNested)},
+			args: []slog.Attr{
+				slog.String("user_id", "x"),
+				slog.Int("code", 200),
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "hello",
+				"attributes": map[string]interface{}{
+					"user_id": "x",
+					"code":    float64(200),
+				},
+			},
+		},
+		{
+			name: "nestedではseverityと同じキーもそのまま出力",
+			opts: []sloggcloud.Option{sloggcloud.WithPayloadMode(sloggcloud.PayloadModeNested)},
+			args: []slog.Attr{slog.String("severity", "user value")},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "hello",
+				"attributes": map[string]interface{}{
+					"severity": "user value",
+				},
+			},
+		},
+		{
+			name:   "nestedではグループをattributesの下に出力",
+			opts:   []sloggcloud.Option{sloggcloud.WithPayloadMode(sloggcloud.PayloadModeNested)},
+			groups: []string{"server"},
+			args:   []slog.Attr{slog.String("host", "example.com")},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "hello",
+				"attributes": map[string]interface{}{
+					"server": map[string]interface{}{
+						"host": "example.com",
+					},
+				},
+			},
+		},
+		{
+			name: "nestedで属性がない場合はattributesを出力しない",
+			opts: []sloggcloud.Option{sloggcloud.WithPayloadMode(sloggcloud.PayloadModeNested)},
+			args: []slog.Attr{},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "hello",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var handler slog.Handler = sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...)
+			for _, group := range tt.groups {
+				handler = handler.WithGroup(group)
+			}
+
+			slog.New(handler).LogAttrs(context.Background(), slog.LevelInfo, "hello", tt.args...)
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			// map に変換すると重複したキーは 1 つにまとめられるため、出力されたキーの数と比べる
+			if keys := topLevelKeys(t, buf.Bytes()); len(keys) != len(got) {
+				t.Errorf("duplicate top-level keys: %v", keys)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// topLevelKeys は JSON オブジェクトのトップレベルのキーを出力された順に重複も含めて返します。
+func topLevelKeys(t *testing.T, b []byte) []string {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if _, err := dec.Token(); err != nil {
+		t.Fatalf("failed to read JSON: %v", err)
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			t.Fatalf("failed to read key: %v", err)
+		}
+		keys = append(keys, tok.(string))
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			t.Fatalf("failed to read value: %v", err)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,59 @@
+package sloggcloud
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// maxPooledAttrs はプールに戻す属性のスライスの容量の上限です。
+// 属性の多いレコードが一度あっただけで大きなスライスを持ち続けないようにします。
+const maxPooledAttrs = 64
+
+// attrPool はレコードごとに属性を組み立てるスライスを再利用するプールです。
+// 新しく確保するスライスの容量は、これまでのレコードの属性の数の最大値から見積もります。
+type attrPool struct {
+	pool sync.Pool
+	// estimate は新しく確保するスライスの容量の見積もり
+	estimate atomic.Int64
+	// capacity は WithAttrPoolCapacity で指定した新しく確保するスライスの最小の容量で、0 の場合は見積もりのみを使用する
+	capacity int
+}
+
+// attrBufPool は WithAttrPoolCapacity を指定していない全ての Handler で共有する属性のスライスのプールです。
+var attrBufPool = newAttrPool(0)
+
+// newAttrPool は新しく確保するスライスの容量が capacity 以上になるプールを作成します。
+func newAttrPool(capacity int) *attrPool {
+	return &attrPool{pool: sync.Pool{New: nil}, estimate: atomic.Int64{}, capacity: capacity}
+}
+
+// get は長さ 0 で容量が n 以上の属性のスライスを返します。使い終わったら put で戻してください。
+func (p *attrPool) get(n int) *[]slog.Attr {
+	if buf, ok := p.pool.Get().(*[]slog.Attr); ok {
+		if cap(*buf) < n {
+			*buf = make([]slog.Attr, 0, n)
+		}
+		return buf
+	}
+	buf := make([]slog.Attr, 0, max(n, int(p.estimate.Load()), p.capacity))
+	return &buf
+}
+
+// put はスライスをプールに戻します。
+// 戻したスライスは別のレコードで上書きされるため、書き込んだエントリからスライスを参照し続けないでください。
+func (p *attrPool) put(buf *[]slog.Attr) {
+	// 容量を指定した場合は、その大きさのスライスも再利用できるように上限を引き上げる
+	limit := max(maxPooledAttrs, p.capacity)
+	n := len(*buf)
+	if n > limit || cap(*buf) > limit {
+		return
+	}
+	if int64(n) > p.estimate.Load() {
+		p.estimate.Store(int64(n))
+	}
+	// 属性の値が参照するメモリを解放できるように中身を消してから戻す
+	clear(*buf)
+	*buf = (*buf)[:0]
+	p.pool.Put(buf)
+}
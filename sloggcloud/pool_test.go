@@ -0,0 +1,163 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestHandler_Handle_pooledAttrs(t *testing.T) {
+	const (
+		goroutines = 20
+		messages   = 50
+	)
+
+	var mu sync.Mutex
+	var buf bytes.Buffer
+	var wg sync.WaitGroup
+	for i := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 書き込み先の異なるハンドラ間でもプールを共有するため、属性の数やグループを変えて並行に出力する
+			var local bytes.Buffer
+			logger := slog.New(newPoolTestHandler(&local, i))
+			for j := range messages {
+				args := make([]any, 0, 2*(i%5+1))
+				for k := range i%5 + 1 {
+					args = append(args, fmt.Sprintf("k%d", k), fmt.Sprintf("g%d-m%d-k%d", i, j, k))
+				}
+				logger.Info("pooled", args...)
+			}
+			mu.Lock()
+			buf.Write(local.Bytes())
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != goroutines*messages {
+		t.Fatalf("got %d lines, want %d", len(lines), goroutines*messages)
+	}
+	for _, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid JSON line: %s", line)
+		}
+		group, _ := entry["worker"].(map[string]interface{})
+		goroutine := int(group["goroutine"].(float64))
+		// 他のレコードの属性が混ざっていないことを確認する
+		for key, value := range group {
+			if key == "goroutine" {
+				continue
+			}
+			if !strings.HasPrefix(value.(string), fmt.Sprintf("g%d-", goroutine)) {
+				t.Errorf("attribute %s=%v does not belong to goroutine %d: %s", key, value, goroutine, line)
+			}
+		}
+		if got, want := len(group)-1, goroutine%5+1; got != want {
+			t.Errorf("got %d attributes, want %d: %s", got, want, line)
+		}
+	}
+}
+
+func newPoolTestHandler(w *bytes.Buffer, goroutine int) slog.Handler {
+	return sloggcloud.New(w, sloggcloud.WithSource(false)).
+		WithGroup("worker").
+		WithAttrs([]slog.Attr{slog.Int("goroutine", goroutine)})
+}
+
+func TestWithAttrPoolCapacity(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []sloggcloud.Option
+		numAttrs int
+	}{
+		{
+			name:     "容量より少ない属性",
+			opts:     []sloggcloud.Option{sloggcloud.WithAttrPoolCapacity(32)},
+			numAttrs: 20,
+		},
+		{
+			name:     "共有のプールの上限を超える属性も容量までは再利用",
+			opts:     []sloggcloud.Option{sloggcloud.WithAttrPoolCapacity(128)},
+			numAttrs: 100,
+		},
+		{
+			name:     "容量を超える属性",
+			opts:     []sloggcloud.Option{sloggcloud.WithAttrPoolCapacity(8)},
+			numAttrs: 20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...))
+
+			// 再利用したスライスに前のレコードの属性が残らないことを確認するため、属性の数を変えて 2 回出力する
+			for _, n := range []int{tt.numAttrs, tt.numAttrs / 2} {
+				buf.Reset()
+				logger.LogAttrs(context.Background(), slog.LevelInfo, "pooled", attrsN(n)...)
+
+				var got map[string]interface{}
+				if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+					t.Fatalf("failed to parse JSON: %v", err)
+				}
+				for _, key := range []string{"time", "severity", "message"} {
+					delete(got, key)
+				}
+				if len(got) != n {
+					t.Errorf("got %d attributes, want %d", len(got), n)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWithAttrPoolCapacity(b *testing.B) {
+	benchmarks := []struct {
+		name     string
+		numAttrs int
+		opts     []sloggcloud.Option
+	}{
+		{name: "20属性/デフォルト", numAttrs: 20, opts: []sloggcloud.Option{}},
+		{name: "20属性/属性の数に合わせた容量", numAttrs: 20, opts: []sloggcloud.Option{sloggcloud.WithAttrPoolCapacity(24)}},
+		// 共有のプールは 64 を超える属性のスライスを再利用しない
+		{name: "100属性/デフォルト", numAttrs: 100, opts: []sloggcloud.Option{}},
+		{name: "100属性/属性の数に合わせた容量", numAttrs: 100, opts: []sloggcloud.Option{sloggcloud.WithAttrPoolCapacity(128)}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			// 属性の半分はコンテキストから追加するため、レコードの属性の数だけでは容量が足りない
+			ctx := sloggcloud.ContextWithAttrs(context.Background(), attrsN(bm.numAttrs/2)...)
+			attrs := attrsN(bm.numAttrs / 2)
+			logger := slog.New(sloggcloud.New(io.Discard, append(bm.opts, sloggcloud.WithSource(false))...))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				logger.LogAttrs(ctx, slog.LevelInfo, "benchmark message", attrs...)
+			}
+		})
+	}
+}
+
+// attrsN は n 個の文字列の属性を返します。
+func attrsN(n int) []slog.Attr {
+	attrs := make([]slog.Attr, n)
+	for i := range attrs {
+		attrs[i] = slog.String(fmt.Sprintf("key%d", i), fmt.Sprintf("value%d", i))
+	}
+	return attrs
+}
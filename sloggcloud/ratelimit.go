@@ -0,0 +1,103 @@
+package sloggcloud
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// suppressionSummaryInterval は破棄したレコードの件数を出力する間隔です。
+const suppressionSummaryInterval = time.Second
+
+// rateLimiter はトークンバケットでレコードの出力数を制限します。
+// 派生した Handler 間で共有されるため、並行に呼び出しても安全です。
+type rateLimiter struct {
+	perSecond float64
+	burst     float64
+
+	mu          sync.Mutex
+	tokens      float64
+	last        time.Time
+	lastSummary time.Time
+	suppressed  int
+}
+
+// newRateLimiter は 1 秒あたり perSecond 件、最大 burst 件まで連続して出力できる rateLimiter を作成します。
+func newRateLimiter(perSecond, burst int) *rateLimiter {
+	return &rateLimiter{
+		perSecond:   float64(perSecond),
+		burst:       float64(burst),
+		mu:          sync.Mutex{},
+		tokens:      float64(burst),
+		last:        time.Time{},
+		lastSummary: time.Time{},
+		suppressed:  0,
+	}
+}
+
+// allow は時刻 t のレコードを出力してよいかどうかと、前回の集計から破棄したレコードの件数を返します。
+// 破棄した件数は suppressionSummaryInterval ごとに 1 度だけ返されます。
+func (l *rateLimiter) allow(t time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.last.IsZero() {
+		l.last = t
+		l.lastSummary = t
+	}
+	// 時刻が前後した場合にトークンが減らないように、経過時間が正の場合のみ補充する
+	if elapsed := t.Sub(l.last); elapsed > 0 {
+		l.tokens = min(l.burst, l.tokens+elapsed.Seconds()*l.perSecond)
+		l.last = t
+	}
+
+	allowed := l.tokens >= 1
+	if allowed {
+		l.tokens--
+	} else {
+		l.suppressed++
+	}
+
+	suppressed := 0
+	if l.suppressed > 0 && t.Sub(l.lastSummary) >= suppressionSummaryInterval {
+		suppressed = l.suppressed
+		l.suppressed = 0
+		l.lastSummary = t
+	}
+	return allowed, suppressed
+}
+
+// takeSuppressed は時刻 t の時点でまだ出力していない破棄したレコードの件数を返し、集計をリセットします。
+func (l *rateLimiter) takeSuppressed(t time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	suppressed := l.suppressed
+	if suppressed > 0 {
+		l.suppressed = 0
+		l.lastSummary = t
+	}
+	return suppressed
+}
+
+// flushSuppressed は流量制限で破棄したレコードのうち、まだ件数を出力していない分を出力します。
+// 集計は次のレコードを処理するときに出力されるため、大量のログの後にログが途絶えると最後の件数が出力されないままになる。
+// Flush や Close で出力することで、終了前に破棄した件数を取りこぼさないようにする。
+func (h *Handler) flushSuppressed() error {
+	if h.opts.rateLimiter == nil {
+		return nil
+	}
+	now := time.Now()
+	if suppressed := h.opts.rateLimiter.takeSuppressed(now); suppressed > 0 {
+		return h.handleSuppressed(context.Background(), now, suppressed)
+	}
+	return nil
+}
+
+// handleSuppressed は破棄したレコードの件数を WARNING のレコードとして出力します。
+func (h *Handler) handleSuppressed(ctx context.Context, t time.Time, suppressed int) error {
+	r := slog.NewRecord(t, slog.LevelWarn, "log messages suppressed by rate limit", 0)
+	r.AddAttrs(slog.Int("suppressed", suppressed))
+	return h.handle(ctx, r)
+}
@@ -0,0 +1,220 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	handler := sloggcloud.New(&buf,
+		sloggcloud.WithRateLimit(10, 5),
+		sloggcloud.WithSource(false),
+	)
+
+	// 1000 件のレコードを 2 秒の間に詰め込んで出力する
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 1000 {
+		r := slog.NewRecord(base.Add(time.Duration(i)*2*time.Millisecond), slog.LevelInfo, "storm", 0)
+		if err := handler.Handle(context.Background(), r); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+	// 最後の集計以降に破棄した件数は Flush で出力される
+	if err := handler.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var logged, suppressed, summaries int
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var got map[string]interface{}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		switch got["message"] {
+		case "storm":
+			logged++
+		case "log messages suppressed by rate limit":
+			summaries++
+			if got["severity"] != "WARNING" {
+				t.Errorf("summary severity = %v, want WARNING", got["severity"])
+			}
+			n, _ := got["suppressed"].(float64)
+			suppressed += int(n)
+		default:
+			t.Errorf("unexpected message: %v", got["message"])
+		}
+	}
+
+	// burst の 5 件と 2 秒間に補充される約 20 件のみ出力される
+	if logged < 20 || logged > 30 {
+		t.Errorf("logged = %d, want between 20 and 30", logged)
+	}
+	if summaries == 0 {
+		t.Fatal("suppression summary was not emitted")
+	}
+	if logged+suppressed != 1000 {
+		t.Errorf("suppressed = %d, logged = %d, want suppressed = %d", suppressed, logged, 1000-logged)
+	}
+}
+
+func TestWithRateLimit_flushSuppressed(t *testing.T) {
+	tests := []struct {
+		name  string
+		flush func(h *sloggcloud.Handler) error
+	}{
+		{
+			name:  "Flushで破棄した件数を出力",
+			flush: (*sloggcloud.Handler).Flush,
+		},
+		{
+			name:  "Closeで破棄した件数を出力",
+			flush: (*sloggcloud.Handler).Close,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := sloggcloud.New(&buf,
+				sloggcloud.WithRateLimit(1, 1),
+				sloggcloud.WithSource(false),
+			)
+
+			// 集計の間隔より短い間に出力し、次のレコードが来ないまま終了する
+			now := time.Now()
+			for range 3 {
+				if err := handler.Handle(context.Background(), slog.NewRecord(now, slog.LevelInfo, "storm", 0)); err != nil {
+					t.Fatalf("Handle() error = %v", err)
+				}
+			}
+			if err := tt.flush(handler); err != nil {
+				t.Fatalf("flush error = %v", err)
+			}
+
+			var got []map[string]any
+			dec := json.NewDecoder(&buf)
+			for dec.More() {
+				var entry map[string]any
+				if err := dec.Decode(&entry); err != nil {
+					t.Fatalf("failed to parse JSON: %v", err)
+				}
+				got = append(got, entry)
+			}
+			if len(got) != 2 {
+				t.Fatalf("got %d entries, want 2", len(got))
+			}
+			if got[1]["message"] != "log messages suppressed by rate limit" || got[1]["suppressed"] != float64(2) {
+				t.Errorf("unexpected summary: %v", got[1])
+			}
+
+			// 出力済みの件数は再度出力しない
+			buf.Reset()
+			if err := handler.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("unexpected output after second flush: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestWithRateLimit_invalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		perSecond int
+		burst     int
+	}{
+		{
+			name:      "perSecondが0",
+			perSecond: 0,
+			burst:     5,
+		},
+		{
+			name:      "burstが0",
+			perSecond: 5,
+			burst:     0,
+		},
+		{
+			name:      "負の値",
+			perSecond: -1,
+			burst:     -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warn bytes.Buffer
+			sloggcloud.SetWarnOutput(t, &warn)
+
+			var buf bytes.Buffer
+			handler := sloggcloud.New(&buf,
+				sloggcloud.WithRateLimit(tt.perSecond, tt.burst),
+				sloggcloud.WithSource(false),
+			)
+
+			// 流量を制限しないため、全てのレコードが出力される
+			now := time.Now()
+			for range 10 {
+				if err := handler.Handle(context.Background(), slog.NewRecord(now, slog.LevelInfo, "hello", 0)); err != nil {
+					t.Fatalf("Handle() error = %v", err)
+				}
+			}
+			if got := bytes.Count(buf.Bytes(), []byte("\n")); got != 10 {
+				t.Errorf("logged = %d, want 10", got)
+			}
+			if warn.Len() == 0 {
+				t.Error("expected a warning for the invalid rate limit")
+			}
+		})
+	}
+}
+
+func TestWithRateLimit_shared(t *testing.T) {
+	var buf bytes.Buffer
+	handler := sloggcloud.New(&buf,
+		sloggcloud.WithRateLimit(1, 10),
+		sloggcloud.WithSource(false),
+	)
+	handlers := []slog.Handler{
+		handler,
+		handler.WithAttrs([]slog.Attr{slog.String("key", "value")}),
+		handler.WithGroup("group"),
+	}
+
+	now := time.Now()
+	var wg sync.WaitGroup
+	for _, h := range handlers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				_ = h.Handle(context.Background(), slog.NewRecord(now, slog.LevelInfo, "storm", 0))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 派生した Handler 間で同じトークンバケットを共有するため、合計で burst の件数のみ出力される
+	var logged int
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var got map[string]interface{}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		logged++
+	}
+	if logged != 10 {
+		t.Errorf("logged = %d, want 10", logged)
+	}
+}
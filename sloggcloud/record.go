@@ -0,0 +1,34 @@
+package sloggcloud
+
+import (
+	"log/slog"
+)
+
+// cloneRecord はレコードを、属性の中身も含めて複製します。
+// slog.Record.Clone は属性のスライスのみを複製するため、グループの値は呼び出し元のスライスを参照したままになります。
+// 非同期に書き込む出力先にレコードを渡す場合は、呼び出し元がスライスを再利用しても影響を受けないようにこちらを使用してください。
+// LogValuer は複製した時点の値で解決します。
+func cloneRecord(r slog.Record) slog.Record {
+	clone := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		clone.AddAttrs(cloneAttr(a))
+		return true
+	})
+	return clone
+}
+
+// cloneAttr は属性を、グループの中も含めて複製します。
+func cloneAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		return a
+	}
+
+	group := a.Value.Group()
+	cloned := make([]slog.Attr, len(group))
+	for i, ga := range group {
+		cloned[i] = cloneAttr(ga)
+	}
+	a.Value = slog.GroupValue(cloned...)
+	return a
+}
@@ -0,0 +1,84 @@
+package sloggcloud_test
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+// recordAttrs はレコードの属性をグループの中も含めて文字列に変換します。
+func recordAttrs(r slog.Record) []string {
+	var attrs []string
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a.String())
+		return true
+	})
+	return attrs
+}
+
+func TestCloneRecord(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		mutate func(r *slog.Record, group []slog.Attr)
+	}{
+		{
+			name: "元のレコードのグループが参照するスライスを書き換え",
+			mutate: func(_ *slog.Record, group []slog.Attr) {
+				group[0] = slog.String("id", "mutated")
+			},
+		},
+		{
+			name: "元のレコードに属性を追加",
+			mutate: func(r *slog.Record, _ []slog.Attr) {
+				r.AddAttrs(slog.String("added", "after clone"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := []slog.Attr{slog.String("id", "r-1"), slog.Int("size", 10)}
+			r := slog.NewRecord(now, slog.LevelInfo, "original", 0)
+			// slog.Record が属性をスライスに持つように、内部の配列に収まる数より多くの属性を追加する
+			r.AddAttrs(
+				slog.String("a", "1"), slog.String("b", "2"), slog.String("c", "3"),
+				slog.String("d", "4"), slog.String("e", "5"),
+				slog.Attr{Key: "req", Value: slog.GroupValue(group...)},
+			)
+
+			clone := sloggcloud.CloneRecord(r)
+			want := recordAttrs(r)
+
+			tt.mutate(&r, group)
+
+			if diff := cmp.Diff(want, recordAttrs(clone)); diff != "" {
+				t.Errorf("clone attrs changed (-want +got):\n%s", diff)
+			}
+			if clone.Message != r.Message || !clone.Time.Equal(r.Time) || clone.Level != r.Level {
+				t.Errorf("clone = %v, want same message, time and level as %v", clone, r)
+			}
+		})
+	}
+}
+
+func TestCloneRecord_logValuer(t *testing.T) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "valuer", 0)
+	r.AddAttrs(slog.Any("user", user{name: "alice", password: "secret"}))
+
+	clone := sloggcloud.CloneRecord(r)
+
+	// LogValuer は複製した時点で解決され、グループとして保持される
+	var got slog.Value
+	clone.Attrs(func(a slog.Attr) bool {
+		got = a.Value
+		return false
+	})
+	if got.Kind() != slog.KindGroup {
+		t.Errorf("cloned value kind = %v, want %v", got.Kind(), slog.KindGroup)
+	}
+}
@@ -0,0 +1,70 @@
+package sloggcloud
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// panicStack は panic が発生した箇所のスタックトレースです。
+// Handler はこの値を stack_trace としてトップレベルに出力し、他の slog.Handler では文字列として出力されます。
+type panicStack struct {
+	message string
+	pcs     []uintptr
+}
+
+// LogValue は panicStack を runtime/debug.Stack と同じ形式の文字列に変換します。
+func (s panicStack) LogValue() slog.Value {
+	return slog.StringValue(formatStack(s.message, s.pcs))
+}
+
+// panicStackAttr は属性が Recover の付与したスタックトレースの場合に、stack_trace の属性を返します。
+func panicStackAttr(a slog.Attr) (slog.Attr, bool) {
+	if a.Key != stackTraceKey || a.Value.Kind() != slog.KindLogValuer {
+		return slog.Attr{}, false
+	}
+	stack, ok := a.Value.Any().(panicStack)
+	if !ok {
+		return slog.Attr{}, false
+	}
+	return slog.Attr{Key: stackTraceKey, Value: stack.LogValue()}, true
+}
+
+// Recover は panic から回復し、panic の値と発生箇所のスタックトレースを CRITICAL のログとして出力します。
+// スタックトレースは Error Reporting が解釈できる形式で stack_trace に出力されます。
+// repanic が true の場合は、ログを出力した後に同じ値で再度 panic します。
+// recover を呼び出すため、defer sloggcloud.Recover(ctx, logger, false) のように defer で直接呼び出す必要があります。
+// logger が nil の場合は slog.Default を利用します。
+func Recover(ctx context.Context, logger *slog.Logger, repanic bool) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if logger.Enabled(ctx, LevelCritical) {
+		// runtime.Callers、Recover、panic のフレームを読み飛ばし、panic が発生した関数から記録する
+		pcs := make([]uintptr, maxStackDepth)
+		pcs = pcs[:runtime.Callers(3, pcs)]
+		var pc uintptr
+		if len(pcs) > 0 {
+			pc = pcs[0]
+		}
+
+		message := fmt.Sprintf("panic: %v", recovered)
+		r := slog.NewRecord(time.Now(), LevelCritical, message, pc)
+		r.AddAttrs(
+			slog.Any("panic", recovered),
+			slog.Any(stackTraceKey, panicStack{message: message, pcs: pcs}),
+		)
+		_ = logger.Handler().Handle(ctx, r)
+	}
+
+	if repanic {
+		panic(recovered)
+	}
+}
@@ -0,0 +1,103 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func panicWithRecover(ctx context.Context, logger *slog.Logger, repanic bool) {
+	defer sloggcloud.Recover(ctx, logger, repanic)
+	panic("boom")
+}
+
+func TestRecover(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(sloggcloud.New(&buf))
+
+	panicWithRecover(context.Background(), logger, false)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+
+	if got["severity"] != "CRITICAL" {
+		t.Errorf("severity = %v, want CRITICAL", got["severity"])
+	}
+	if got["message"] != "panic: boom" {
+		t.Errorf("message = %v, want %v", got["message"], "panic: boom")
+	}
+	if got["panic"] != "boom" {
+		t.Errorf("panic = %v, want %v", got["panic"], "boom")
+	}
+
+	stackTrace, ok := got["stack_trace"].(string)
+	if !ok {
+		t.Fatalf("stack_trace is not a string: %v", got["stack_trace"])
+	}
+	if !strings.HasPrefix(stackTrace, "panic: boom\n\ngoroutine 1 [running]:\n") {
+		t.Errorf("stack_trace does not start with message and goroutine header: %s", stackTrace)
+	}
+	if !strings.Contains(stackTrace, "panicWithRecover") {
+		t.Errorf("stack_trace does not contain panicking function: %s", stackTrace)
+	}
+	if strings.Contains(stackTrace, "sloggcloud.Recover") {
+		t.Errorf("stack_trace should not contain Recover frame: %s", stackTrace)
+	}
+
+	sourceLocation, _ := got["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	if function, _ := sourceLocation["function"].(string); !strings.HasSuffix(function, "panicWithRecover") {
+		t.Errorf("sourceLocation.function = %v, want panicWithRecover", sourceLocation["function"])
+	}
+}
+
+func TestRecover_repanic(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(sloggcloud.New(&buf))
+
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recovered = %v, want boom", r)
+		}
+		if !strings.Contains(buf.String(), `"severity":"CRITICAL"`) {
+			t.Errorf("CRITICAL log was not written before repanic: %s", buf.String())
+		}
+	}()
+	panicWithRecover(context.Background(), logger, true)
+	t.Error("panic was swallowed")
+}
+
+func TestRecover_otherHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	panicWithRecover(context.Background(), logger, false)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	// sloggcloud 以外のハンドラでもスタックトレースは文字列として出力される
+	if stackTrace, _ := got["stack_trace"].(string); !strings.Contains(stackTrace, "panicWithRecover") {
+		t.Errorf("stack_trace does not contain panicking function: %v", got["stack_trace"])
+	}
+}
+
+func TestRecover_noPanic(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(sloggcloud.New(&buf))
+
+	func() {
+		defer sloggcloud.Recover(context.Background(), logger, false)
+	}()
+
+	if buf.Len() != 0 {
+		t.Errorf("unexpected log: %s", buf.String())
+	}
+}
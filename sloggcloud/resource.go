@@ -0,0 +1,92 @@
+package sloggcloud
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"slices"
+)
+
+// resourceKey はログの出力元となるモニタリング対象リソースを出力するキーです。
+const resourceKey = "resource"
+
+// monitoredResource は Cloud Logging の MonitoredResource です。
+type monitoredResource struct {
+	resType string
+	labels  map[string]string
+}
+
+// attr は monitoredResource を Cloud Logging の resource の形式の slog.Attr に変換します。
+func (r *monitoredResource) attr() slog.Attr {
+	attrs := []slog.Attr{slog.String("type", r.resType)}
+	if len(r.labels) > 0 {
+		labels := make([]slog.Attr, 0, len(r.labels))
+		for _, key := range slices.Sorted(maps.Keys(r.labels)) {
+			labels = append(labels, slog.String(key, r.labels[key]))
+		}
+		attrs = append(attrs, slog.Attr{Key: "labels", Value: slog.GroupValue(labels...)})
+	}
+	return slog.Attr{Key: resourceKey, Value: slog.GroupValue(attrs...)}
+}
+
+// k8sNamespaceFile は Kubernetes が Pod にマウントする、Pod の名前空間を記載したファイルです。
+const k8sNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// detectResource は環境変数とメタデータサーバーから実行環境を判定し、対応するモニタリング対象リソースを返します。
+// Cloud Run、App Engine、GKE、Compute Engine の順に判定し、どれにも当てはまらない場合は nil を返します。
+// メタデータサーバーから取得できなかったラベルは出力しません。
+func detectResource(ctx context.Context, projectID string) *monitoredResource {
+	labels := map[string]string{}
+	if projectID != "" {
+		labels["project_id"] = projectID
+	}
+	setEnv := func(key, env string) {
+		if v := os.Getenv(env); v != "" {
+			labels[key] = v
+		}
+	}
+	// region や zone は projects/<プロジェクト番号>/regions/<リージョン> の形式で返されるため、末尾のみを使用する
+	setMetadata := func(key, metadataPath string) bool {
+		v, err := metadataValue(ctx, metadataPath)
+		if err != nil || v == "" {
+			return false
+		}
+		labels[key] = path.Base(v)
+		return true
+	}
+
+	switch {
+	case os.Getenv("K_SERVICE") != "" && os.Getenv("K_REVISION") != "":
+		setEnv("service_name", "K_SERVICE")
+		setEnv("revision_name", "K_REVISION")
+		setEnv("configuration_name", "K_CONFIGURATION")
+		setMetadata("location", "instance/region")
+		return &monitoredResource{resType: "cloud_run_revision", labels: labels}
+	case os.Getenv("GAE_SERVICE") != "":
+		setEnv("module_id", "GAE_SERVICE")
+		setEnv("version_id", "GAE_VERSION")
+		setMetadata("zone", "instance/zone")
+		return &monitoredResource{resType: "gae_app", labels: labels}
+	case os.Getenv("KUBERNETES_SERVICE_HOST") != "":
+		setMetadata("cluster_name", "instance/attributes/cluster-name")
+		setMetadata("location", "instance/attributes/cluster-location")
+		setEnv("namespace_name", "NAMESPACE_NAME")
+		if _, ok := labels["namespace_name"]; !ok {
+			if b, err := os.ReadFile(k8sNamespaceFile); err == nil && len(b) > 0 {
+				labels["namespace_name"] = string(b)
+			}
+		}
+		setEnv("pod_name", "HOSTNAME")
+		setEnv("container_name", "CONTAINER_NAME")
+		return &monitoredResource{resType: "k8s_container", labels: labels}
+	}
+
+	// 環境変数で判定できない場合は、メタデータサーバーに問い合わせられれば Compute Engine とみなす
+	if !setMetadata("instance_id", "instance/id") {
+		return nil
+	}
+	setMetadata("zone", "instance/zone")
+	return &monitoredResource{resType: "gce_instance", labels: labels}
+}
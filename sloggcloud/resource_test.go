@@ -0,0 +1,265 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithMonitoredResource(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []sloggcloud.Option
+		groups []string
+		want   map[string]interface{}
+	}{
+		{
+			name: "resourceのtypeとlabelsをトップレベルに出力",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithMonitoredResource("gce_instance", map[string]string{
+					"instance_id": "1234567890",
+					"zone":        "asia-northeast1-a",
+				}),
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "resource message",
+				"key":      "value",
+				"resource": map[string]interface{}{
+					"type": "gce_instance",
+					"labels": map[string]interface{}{
+						"instance_id": "1234567890",
+						"zone":        "asia-northeast1-a",
+					},
+				},
+			},
+		},
+		{
+			name: "labelsがない場合はtypeのみ出力",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithMonitoredResource("global", nil),
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "resource message",
+				"key":      "value",
+				"resource": map[string]interface{}{
+					"type": "global",
+				},
+			},
+		},
+		{
+			name: "グループを指定してもトップレベルに出力",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithMonitoredResource("cloud_run_revision", map[string]string{"service_name": "api"}),
+			},
+			groups: []string{"server"},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "resource message",
+				"server": map[string]interface{}{
+					"key": "value",
+				},
+				"resource": map[string]interface{}{
+					"type": "cloud_run_revision",
+					"labels": map[string]interface{}{
+						"service_name": "api",
+					},
+				},
+			},
+		},
+		{
+			name: "指定しない場合は出力しない",
+			opts: []sloggcloud.Option{},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "resource message",
+				"key":      "value",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var handler slog.Handler = sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...)
+			for _, group := range tt.groups {
+				handler = handler.WithGroup(group)
+			}
+
+			slog.New(handler).InfoContext(context.Background(), "resource message", "key", "value")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithAutoResource(t *testing.T) {
+	// 判定に使用する環境変数は、テストを実行する環境の値に影響されないように全て空にしてから設定する
+	platformEnvs := []string{
+		"K_SERVICE", "K_REVISION", "K_CONFIGURATION",
+		"GAE_SERVICE", "GAE_VERSION",
+		"KUBERNETES_SERVICE_HOST", "NAMESPACE_NAME", "HOSTNAME", "CONTAINER_NAME",
+	}
+	metadata := map[string]string{
+		"instance/region":                      "projects/123456/regions/asia-northeast1",
+		"instance/zone":                        "projects/123456/zones/asia-northeast1-a",
+		"instance/id":                          "1234567890",
+		"instance/attributes/cluster-name":     "prod-cluster",
+		"instance/attributes/cluster-location": "asia-northeast1",
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		metadata bool
+		opts     []sloggcloud.Option
+		want     interface{}
+	}{
+		{
+			name:     "Cloud Run",
+			env:      map[string]string{"K_SERVICE": "api", "K_REVISION": "api-00001-abc", "K_CONFIGURATION": "api"},
+			metadata: true,
+			opts:     nil,
+			want: map[string]interface{}{
+				"type": "cloud_run_revision",
+				"labels": map[string]interface{}{
+					"project_id":         "test-project",
+					"service_name":       "api",
+					"revision_name":      "api-00001-abc",
+					"configuration_name": "api",
+					"location":           "asia-northeast1",
+				},
+			},
+		},
+		{
+			name:     "App Engine",
+			env:      map[string]string{"GAE_SERVICE": "default", "GAE_VERSION": "20240101t000000"},
+			metadata: true,
+			opts:     nil,
+			want: map[string]interface{}{
+				"type": "gae_app",
+				"labels": map[string]interface{}{
+					"project_id": "test-project",
+					"module_id":  "default",
+					"version_id": "20240101t000000",
+					"zone":       "asia-northeast1-a",
+				},
+			},
+		},
+		{
+			name: "GKE",
+			env: map[string]string{
+				"KUBERNETES_SERVICE_HOST": "10.0.0.1",
+				"NAMESPACE_NAME":          "prod",
+				"HOSTNAME":                "api-7d4b9c-xyz",
+				"CONTAINER_NAME":          "app",
+			},
+			metadata: true,
+			opts:     nil,
+			want: map[string]interface{}{
+				"type": "k8s_container",
+				"labels": map[string]interface{}{
+					"project_id":     "test-project",
+					"cluster_name":   "prod-cluster",
+					"location":       "asia-northeast1",
+					"namespace_name": "prod",
+					"pod_name":       "api-7d4b9c-xyz",
+					"container_name": "app",
+				},
+			},
+		},
+		{
+			name:     "Compute Engine",
+			env:      nil,
+			metadata: true,
+			opts:     nil,
+			want: map[string]interface{}{
+				"type": "gce_instance",
+				"labels": map[string]interface{}{
+					"project_id":  "test-project",
+					"instance_id": "1234567890",
+					"zone":        "asia-northeast1-a",
+				},
+			},
+		},
+		{
+			name:     "メタデータサーバーから取得できないラベルは出力しない",
+			env:      map[string]string{"K_SERVICE": "api", "K_REVISION": "api-00001-abc"},
+			metadata: false,
+			opts:     nil,
+			want: map[string]interface{}{
+				"type": "cloud_run_revision",
+				"labels": map[string]interface{}{
+					"project_id":    "test-project",
+					"service_name":  "api",
+					"revision_name": "api-00001-abc",
+				},
+			},
+		},
+		{
+			name:     "どの環境でもない場合は出力しない",
+			env:      nil,
+			metadata: false,
+			opts:     nil,
+			want:     nil,
+		},
+		{
+			name:     "WithMonitoredResourceで指定したリソースを優先",
+			env:      map[string]string{"K_SERVICE": "api", "K_REVISION": "api-00001-abc"},
+			metadata: true,
+			opts:     []sloggcloud.Option{sloggcloud.WithMonitoredResource("global", nil)},
+			want:     map[string]interface{}{"type": "global"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				value, ok := metadata[strings.TrimPrefix(r.URL.Path, "/computeMetadata/v1/")]
+				if !tt.metadata || !ok || r.Header.Get("Metadata-Flavor") != "Google" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = io.WriteString(w, value)
+			}))
+			defer srv.Close()
+			t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(srv.URL, "http://"))
+			t.Setenv("GOOGLE_CLOUD_PROJECT", "test-project")
+			for _, env := range platformEnvs {
+				t.Setenv(env, "")
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			var buf bytes.Buffer
+			slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithAutoResource(), sloggcloud.WithSource(false))...)).Info("hello")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got["resource"]); diff != "" {
+				t.Errorf("resource mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
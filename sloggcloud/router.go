@@ -0,0 +1,78 @@
+package sloggcloud
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// RouterRule は Router がレコードを配信する条件です。
+type RouterRule struct {
+	// MinLevel は Handler に配信するレコードの最小のレベルです。
+	MinLevel slog.Level
+	// Handler はレコードを配信するハンドラです。
+	Handler slog.Handler
+}
+
+// routerHandler はレベルに応じてレコードを複数のハンドラに振り分ける slog.Handler です。
+type routerHandler struct {
+	rules []RouterRule
+}
+
+var _ slog.Handler = (*routerHandler)(nil)
+
+// Router はレコードのレベルが MinLevel 以上の全ての rules のハンドラにレコードを配信する slog.Handler を返します。
+// 全てのログを標準出力に出力しつつ、ERROR 以上のログのみ通知用の出力先にも送る場合などに使用します。
+// WithAttrs と WithGroup は全ての rules のハンドラに適用されます。
+func Router(rules ...RouterRule) slog.Handler {
+	return &routerHandler{rules: rules}
+}
+
+// Enabled はいずれかの rules が level のレコードを配信する場合に true を返します。
+func (h *routerHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, rule := range h.rules {
+		if rule.accepts(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle は条件を満たす全ての rules のハンドラにレコードを渡します。
+// 一部のハンドラが失敗しても残りのハンドラには配信し、発生したエラーをまとめて返します。
+func (h *routerHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, rule := range h.rules {
+		if !rule.accepts(ctx, r.Level) {
+			continue
+		}
+		// 非同期に書き込むハンドラがレコードを保持しても、呼び出し元や他のハンドラの影響を受けないように属性の中身まで複製して渡す
+		if err := rule.Handler.Handle(ctx, cloneRecord(r)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs は全ての rules のハンドラに属性を追加した新しいハンドラを返します。
+func (h *routerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	rules := make([]RouterRule, len(h.rules))
+	for i, rule := range h.rules {
+		rules[i] = RouterRule{MinLevel: rule.MinLevel, Handler: rule.Handler.WithAttrs(attrs)}
+	}
+	return &routerHandler{rules: rules}
+}
+
+// WithGroup は全ての rules のハンドラにグループを追加した新しいハンドラを返します。
+func (h *routerHandler) WithGroup(name string) slog.Handler {
+	rules := make([]RouterRule, len(h.rules))
+	for i, rule := range h.rules {
+		rules[i] = RouterRule{MinLevel: rule.MinLevel, Handler: rule.Handler.WithGroup(name)}
+	}
+	return &routerHandler{rules: rules}
+}
+
+// accepts はレベルが MinLevel 以上で、かつハンドラが処理するレベルかどうかを返します。
+func (r RouterRule) accepts(ctx context.Context, level slog.Level) bool {
+	return level >= r.MinLevel && r.Handler.Enabled(ctx, level)
+}
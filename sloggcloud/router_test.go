@@ -0,0 +1,100 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestRouter(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        func(logger *slog.Logger)
+		wantStdout []map[string]interface{}
+		wantAlert  []map[string]interface{}
+	}{
+		{
+			name: "INFOは全てのログの出力先のみに配信",
+			log: func(logger *slog.Logger) {
+				logger.Info("info")
+			},
+			wantStdout: []map[string]interface{}{
+				{"severity": "INFO", "message": "info"},
+			},
+			wantAlert: nil,
+		},
+		{
+			name: "ERRORは両方の出力先に配信",
+			log: func(logger *slog.Logger) {
+				logger.Error("error")
+			},
+			wantStdout: []map[string]interface{}{
+				{"severity": "ERROR", "message": "error"},
+			},
+			wantAlert: []map[string]interface{}{
+				{"severity": "ERROR", "message": "error"},
+			},
+		},
+		{
+			name: "WithAttrsとWithGroupは全ての出力先に適用",
+			log: func(logger *slog.Logger) {
+				logger.WithGroup("req").With("id", "r-1").Error("error")
+			},
+			wantStdout: []map[string]interface{}{
+				{"severity": "ERROR", "message": "error", "req": map[string]interface{}{"id": "r-1"}},
+			},
+			wantAlert: []map[string]interface{}{
+				{"severity": "ERROR", "message": "error", "req": map[string]interface{}{"id": "r-1"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, alert bytes.Buffer
+			logger := slog.New(sloggcloud.Router(
+				sloggcloud.RouterRule{MinLevel: slog.LevelDebug, Handler: sloggcloud.New(&stdout, sloggcloud.WithSource(false))},
+				sloggcloud.RouterRule{MinLevel: slog.LevelError, Handler: sloggcloud.New(&alert, sloggcloud.WithSource(false))},
+			))
+
+			tt.log(logger)
+
+			if diff := cmp.Diff(tt.wantStdout, decodeLines(t, &stdout)); diff != "" {
+				t.Errorf("stdout mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantAlert, decodeLines(t, &alert)); diff != "" {
+				t.Errorf("alert mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRouter_Enabled(t *testing.T) {
+	handler := sloggcloud.Router(
+		// ハンドラのレベルが MinLevel より高い場合はハンドラのレベルで判定する
+		sloggcloud.RouterRule{MinLevel: slog.LevelDebug, Handler: sloggcloud.New(&bytes.Buffer{}, sloggcloud.WithLevel(slog.LevelWarn))},
+		sloggcloud.RouterRule{MinLevel: slog.LevelError, Handler: sloggcloud.New(&bytes.Buffer{}, sloggcloud.WithLevel(slog.LevelDebug))},
+	)
+
+	tests := []struct {
+		name  string
+		level slog.Level
+		want  bool
+	}{
+		{name: "いずれの条件も満たさないレベル", level: slog.LevelInfo, want: false},
+		{name: "一方の条件を満たすレベル", level: slog.LevelWarn, want: true},
+		{name: "両方の条件を満たすレベル", level: slog.LevelError, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := handler.Enabled(context.Background(), tt.level); got != tt.want {
+				t.Errorf("Enabled(%v) = %v, want %v", tt.level, got, tt.want)
+			}
+		})
+	}
+}
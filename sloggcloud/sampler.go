@@ -0,0 +1,52 @@
+package sloggcloud
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Sampler はレコードを出力するかどうかを判定する関数です。false を返したレコードは破棄されます。
+type Sampler func(ctx context.Context, r slog.Record) bool
+
+// RandomSampler は ERROR 未満のレコードを rate の確率で出力する Sampler を返します。
+// rate が 0 以下の場合は ERROR 未満のレコードをすべて破棄し、1 以上の場合はすべて出力します。
+// ERROR 以上のレコードは常に出力されます。
+func RandomSampler(rate float64) Sampler {
+	return func(_ context.Context, r slog.Record) bool {
+		if r.Level >= slog.LevelError || rate >= 1 {
+			return true
+		}
+		if rate <= 0 {
+			return false
+		}
+		return rand.Float64() < rate //nolint:gosec // サンプリングに暗号学的な乱数は不要
+	}
+}
+
+// PerKeySampler はメッセージごとに interval あたり最初の n 件までの ERROR 未満のレコードを出力する Sampler を返します。
+// 期間はレコードの時刻で区切られ、ERROR 以上のレコードは常に出力されます。
+func PerKeySampler(n int, interval time.Duration) Sampler {
+	var (
+		mu     sync.Mutex
+		start  time.Time
+		counts = make(map[string]int)
+	)
+	return func(_ context.Context, r slog.Record) bool {
+		if r.Level >= slog.LevelError {
+			return true
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		// 期間ごとにすべてのカウントを捨てることで、メッセージの種類が多くてもメモリが増え続けないようにする
+		if r.Time.Sub(start) >= interval || r.Time.Before(start) {
+			start = r.Time
+			clear(counts)
+		}
+		counts[r.Message]++
+		return counts[r.Message] <= n
+	}
+}
@@ -0,0 +1,152 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+// countSeverities は出力されたログの severity ごとの件数を返します。
+func countSeverities(t *testing.T, buf *bytes.Buffer) map[string]int {
+	t.Helper()
+
+	counts := make(map[string]int)
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var got map[string]interface{}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		severity, _ := got["severity"].(string)
+		counts[severity]++
+	}
+	return counts
+}
+
+func TestRandomSampler(t *testing.T) {
+	tests := []struct {
+		name string
+		rate float64
+		want map[string]int
+	}{
+		{
+			name: "rateが0の場合はERROR未満をすべて破棄",
+			rate: 0.0,
+			want: map[string]int{"ERROR": 10},
+		},
+		{
+			name: "rateが1の場合はすべて出力",
+			rate: 1.0,
+			want: map[string]int{"DEBUG": 10, "INFO": 10, "WARNING": 10, "ERROR": 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf,
+				sloggcloud.WithSampler(sloggcloud.RandomSampler(tt.rate)),
+				sloggcloud.WithLevel(slog.LevelDebug),
+				sloggcloud.WithSource(false),
+			))
+
+			for range 10 {
+				logger.Debug("debug")
+				logger.Info("info")
+				logger.Warn("warn")
+				logger.Error("error")
+			}
+
+			if diff := cmp.Diff(tt.want, countSeverities(t, &buf)); diff != "" {
+				t.Errorf("severity counts mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("rateが0.5の場合は一部を出力", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(sloggcloud.New(&buf,
+			sloggcloud.WithSampler(sloggcloud.RandomSampler(0.5)),
+			sloggcloud.WithSource(false),
+		))
+
+		for range 1000 {
+			logger.Info("info")
+		}
+
+		// 確率的なため、極端に偏っていないことだけを確認する
+		if got := countSeverities(t, &buf)["INFO"]; got < 300 || got > 700 {
+			t.Errorf("INFO count = %d, want around 500", got)
+		}
+	})
+}
+
+func TestPerKeySampler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := sloggcloud.New(&buf,
+		sloggcloud.WithSampler(sloggcloud.PerKeySampler(2, time.Second)),
+		sloggcloud.WithSource(false),
+	)
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	records := []struct {
+		offset  time.Duration
+		level   slog.Level
+		message string
+	}{
+		{offset: 0, level: slog.LevelInfo, message: "a"},
+		{offset: 100 * time.Millisecond, level: slog.LevelInfo, message: "a"},
+		{offset: 200 * time.Millisecond, level: slog.LevelInfo, message: "a"},
+		{offset: 300 * time.Millisecond, level: slog.LevelInfo, message: "b"},
+		{offset: 400 * time.Millisecond, level: slog.LevelError, message: "a"},
+		{offset: 1100 * time.Millisecond, level: slog.LevelInfo, message: "a"},
+	}
+	for _, rec := range records {
+		r := slog.NewRecord(base.Add(rec.offset), rec.level, rec.message, 0)
+		if err := handler.Handle(context.Background(), r); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+
+	var got []string
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var entry map[string]interface{}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		got = append(got, entry["severity"].(string)+" "+entry["message"].(string))
+	}
+
+	// 同じ期間内の 3 件目の "a" は破棄され、ERROR と次の期間の "a" は出力される
+	want := []string{"INFO a", "INFO a", "INFO b", "ERROR a", "INFO a"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWithSampler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(sloggcloud.New(&buf,
+		sloggcloud.WithSampler(func(_ context.Context, r slog.Record) bool {
+			return r.Message != "drop"
+		}),
+		sloggcloud.WithSource(false),
+	))
+
+	logger.Info("keep")
+	logger.Info("drop")
+	// 独自の Sampler では ERROR 以上も判定結果に従う
+	logger.Error("drop")
+
+	want := map[string]int{"INFO": 1}
+	if diff := cmp.Diff(want, countSeverities(t, &buf)); diff != "" {
+		t.Errorf("severity counts mismatch (-want +got):\n%s", diff)
+	}
+}
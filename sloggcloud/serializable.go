@@ -0,0 +1,46 @@
+package sloggcloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// serializableAttr は JSON に変換できない値を "<unserializable: 型>" の文字列で出力する属性に変換します。
+// slog.JSONHandler は変換に失敗した値を "!ERROR:..." の文字列で出力するため、
+// 1 つの属性のために読みにくいログにならないよう、値の型が分かる形に置き換えます。
+func serializableAttr(a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindAny {
+		return a
+	}
+	v := a.Value.Any()
+	// 値が nil の空の属性は slog.JSONHandler が出力しないため、包まずにそのまま返す
+	if v == nil {
+		return a
+	}
+	// slog.JSONHandler は json.Marshaler を実装しないエラーを Error() の文字列で出力するため、変換の対象外とする
+	if _, ok := v.(json.Marshaler); !ok {
+		if _, ok := v.(error); ok {
+			return a
+		}
+	}
+	return slog.Any(a.Key, serializableValue{v: v})
+}
+
+// serializableValue は JSON への変換に失敗した場合に、値の型を示す文字列を出力する json.Marshaler です。
+type serializableValue struct {
+	v any
+}
+
+// MarshalJSON は値を JSON に変換します。変換に失敗した場合は "<unserializable: 型>" の文字列を返します。
+func (s serializableValue) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// slog.JSONHandler と同じく HTML の特殊文字をエスケープしない
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s.v); err != nil {
+		return json.Marshal(fmt.Sprintf("<unserializable: %T>", s.v)) //nolint:wrapcheck // 文字列の変換は失敗しない
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
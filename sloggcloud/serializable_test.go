@@ -0,0 +1,85 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+// failingMarshaler は JSON への変換に必ず失敗する値です。
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("marshal failed")
+}
+
+func TestHandler_Handle_unserializable(t *testing.T) {
+	tests := []struct {
+		name string
+		attr slog.Attr
+		want interface{}
+	}{
+		{
+			name: "チャネル",
+			attr: slog.Any("value", make(chan int)),
+			want: "<unserializable: chan int>",
+		},
+		{
+			name: "関数",
+			attr: slog.Any("value", func() {}),
+			want: "<unserializable: func()>",
+		},
+		{
+			name: "MarshalJSONが失敗する値",
+			attr: slog.Any("value", failingMarshaler{}),
+			want: "<unserializable: sloggcloud_test.failingMarshaler>",
+		},
+		{
+			name: "変換できない値を含むマップ",
+			attr: slog.Any("value", map[string]any{"ch": make(chan int)}),
+			want: "<unserializable: map[string]interface {}>",
+		},
+		{
+			name: "変換できる値はそのまま出力",
+			attr: slog.Any("value", map[string]any{"html": "<b>&</b>"}),
+			want: map[string]interface{}{"html": "<b>&</b>"},
+		},
+		{
+			name: "エラーはメッセージを出力",
+			attr: slog.Any("value", errors.New("failed")),
+			want: "failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, sloggcloud.WithSource(false)))
+
+			logger.Info("unserializable", tt.attr, slog.Group("group", tt.attr), slog.String("after", "kept"))
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v: %s", err, buf.String())
+			}
+			delete(got, "time")
+
+			// 変換できない属性があっても、他の属性を含むエントリ全体が出力される
+			want := map[string]interface{}{
+				"severity": "INFO",
+				"message":  "unserializable",
+				"value":    tt.want,
+				"group":    map[string]interface{}{"value": tt.want},
+				"after":    "kept",
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
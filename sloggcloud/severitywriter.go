@@ -0,0 +1,102 @@
+package sloggcloud
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SeverityWriter はテキストのログを 1 行ずつ slog.Handler のレコードに変換する io.Writer です。
+// log.SetOutput に渡すことで、log.Printf などの既存のログを書き換えずに severity 付きの構造化ログとして出力できます。
+// 行の先頭の [ERROR] や [WARN] のようなトークンをレベルとして解釈し、トークンを除いた残りをメッセージとします。
+// panic: や fatal で始まる行は CRITICAL として扱い、それ以外の行は INFO として扱います。
+// 日時の接頭辞があるとトークンを解釈できないため、log.SetFlags(0) と併用してください。
+type SeverityWriter struct {
+	handler slog.Handler
+
+	mu sync.Mutex
+	// buf は改行で終わっていない書きかけの行
+	buf []byte
+}
+
+// NewSeverityWriter は h にレコードを出力する SeverityWriter を作成します。
+func NewSeverityWriter(h slog.Handler) *SeverityWriter {
+	return &SeverityWriter{handler: h, mu: sync.Mutex{}, buf: nil}
+}
+
+// Write は p を改行で区切り、行ごとにレコードとして出力します。
+// 改行で終わっていない部分は次の Write か Flush まで保持します。
+func (w *SeverityWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.buf[:i])
+		w.buf = w.buf[i+1:]
+		if err := w.handleLine(line); err != nil {
+			return len(p), err
+		}
+	}
+	// 保持し続けると元の配列を解放できないため、残りが空の場合は捨てる
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// Flush は改行で終わっていない書きかけの行をレコードとして出力します。
+func (w *SeverityWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := string(w.buf)
+	w.buf = nil
+	return w.handleLine(line)
+}
+
+// handleLine は 1 行をレコードに変換して出力します。空行は出力しません。
+func (w *SeverityWriter) handleLine(line string) error {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+
+	ctx := context.Background()
+	level, message := parseSeverityLine(line)
+	if !w.handler.Enabled(ctx, level) {
+		return nil
+	}
+	if err := w.handler.Handle(ctx, slog.NewRecord(time.Now(), level, message, 0)); err != nil {
+		return fmt.Errorf("failed to handle text log: %w", err)
+	}
+	return nil
+}
+
+// parseSeverityLine は行の先頭からレベルを判定し、レベルとメッセージを返します。
+func parseSeverityLine(line string) (slog.Level, string) {
+	if rest, ok := strings.CutPrefix(line, "["); ok {
+		if token, message, ok := strings.Cut(rest, "]"); ok {
+			if level, ok := parseLevel(token); ok {
+				return level, strings.TrimLeft(message, " ")
+			}
+		}
+	}
+	// panic や log.Fatal の出力はそれ自体が重要な情報のため、メッセージをそのまま残す
+	lower := strings.ToLower(line)
+	if strings.HasPrefix(lower, "panic:") || strings.HasPrefix(lower, "fatal") {
+		return LevelCritical, line
+	}
+	return slog.LevelInfo, line
+}
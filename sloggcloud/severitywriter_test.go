@@ -0,0 +1,126 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestSeverityWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []map[string]interface{}
+	}{
+		{
+			name:  "先頭のトークンをseverityに変換",
+			input: "[WARN] something\n",
+			want:  []map[string]interface{}{{"severity": "WARNING", "message": "something"}},
+		},
+		{
+			name:  "トークンは大文字小文字を区別しない",
+			input: "[error] failed\n[Notice] notice\n",
+			want: []map[string]interface{}{
+				{"severity": "ERROR", "message": "failed"},
+				{"severity": "NOTICE", "message": "notice"},
+			},
+		},
+		{
+			name:  "トークンがない行はINFO",
+			input: "plain line\n",
+			want:  []map[string]interface{}{{"severity": "INFO", "message": "plain line"}},
+		},
+		{
+			name:  "レベルでないトークンは残してINFO",
+			input: "[main] started\n",
+			want:  []map[string]interface{}{{"severity": "INFO", "message": "[main] started"}},
+		},
+		{
+			name:  "panicとfatalで始まる行はCRITICAL",
+			input: "panic: runtime error\nfatal error: all goroutines are asleep\n",
+			want: []map[string]interface{}{
+				{"severity": "CRITICAL", "message": "panic: runtime error"},
+				{"severity": "CRITICAL", "message": "fatal error: all goroutines are asleep"},
+			},
+		},
+		{
+			name:  "空行とCRLFの改行",
+			input: "\r\n[INFO] windows\r\n\n",
+			want:  []map[string]interface{}{{"severity": "INFO", "message": "windows"}},
+		},
+		{
+			name:  "レベルが低い行は出力しない",
+			input: "[DEBUG] verbose\n",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := sloggcloud.NewSeverityWriter(sloggcloud.New(&buf, sloggcloud.WithSource(false)))
+
+			n, err := w.Write([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("failed to write: %v", err)
+			}
+			if n != len(tt.input) {
+				t.Errorf("n = %d, want %d", n, len(tt.input))
+			}
+
+			got := decodeLines(t, &buf)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("logパッケージの出力を変換", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := log.New(sloggcloud.NewSeverityWriter(sloggcloud.New(&buf, sloggcloud.WithSource(false))), "", 0)
+
+		logger.Printf("[ERROR] failed to connect: %v", errors.New("timeout"))
+		logger.Print("done")
+
+		want := []map[string]interface{}{
+			{"severity": "ERROR", "message": "failed to connect: timeout"},
+			{"severity": "INFO", "message": "done"},
+		}
+		if diff := cmp.Diff(want, decodeLines(t, &buf)); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("改行で終わっていない行はFlushで出力", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := sloggcloud.NewSeverityWriter(sloggcloud.New(&buf, sloggcloud.WithSource(false)))
+
+		fmt.Fprint(w, "[WARN] par")
+		fmt.Fprint(w, "tial")
+		if buf.Len() != 0 {
+			t.Fatalf("output before flush: %s", buf.String())
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("failed to flush: %v", err)
+		}
+
+		want := []map[string]interface{}{{"severity": "WARNING", "message": "partial"}}
+		if diff := cmp.Diff(want, decodeLines(t, &buf)); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("出力に失敗した場合はエラーを返す", func(t *testing.T) {
+		errWrite := errors.New("broken pipe")
+		w := sloggcloud.NewSeverityWriter(sloggcloud.New(errWriter{err: errWrite}, sloggcloud.WithSource(false)))
+
+		if _, err := w.Write([]byte("[ERROR] failed\n")); !errors.Is(err, errWrite) {
+			t.Errorf("err = %v, want %v", err, errWrite)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package sloggcloud
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// HandleSignals は SIGTERM または SIGINT を受け取った時に h のバッファリングしたログを書き出し、返したコンテキストをキャンセルします。
+// Cloud Run などではコンテナを停止する前に SIGTERM が送られるため、WithBuffer や WithGzip を使用する場合にログを失わないように利用します。
+// signal.NotifyContext と同様にシグナルの既定の動作を置き換えるため、返したコンテキストの終了を待ってサーバーの停止などの終了処理を行ってください。
+// 終了処理の間もログを出力できるように、h は閉じずに Flush します。終了の直前に h.Close を呼び出してください。
+// シグナルを受け取った時点で監視をやめるため、2 回目のシグナルではプロセスが既定の動作で終了します。
+// ctx がキャンセルされた場合、または戻り値の stop を呼び出した場合も監視をやめます。
+// stop は監視の goroutine が終了するまで待つため、呼び出した後に goroutine が残ることはありません。
+// Flush が失敗した場合は WithOnError の関数に通知します。
+func HandleSignals(ctx context.Context, h *Handler) (context.Context, func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	return h.flushOnSignal(ctx, sigCh, func() { signal.Stop(sigCh) })
+}
+
+// flushOnSignal は sigCh からシグナルを受け取った時に h を Flush し、返したコンテキストをキャンセルする goroutine を起動します。
+// テストで実際のシグナルを送らずに済むように、シグナルの受け取り方は呼び出し側で決めます。
+// release はシグナルの登録を解除する関数で、監視をやめる時に 1 度だけ呼び出します。
+func (h *Handler) flushOnSignal(ctx context.Context, sigCh <-chan os.Signal, release func()) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-sigCh:
+			// 書き出しに時間がかかっても 2 回目のシグナルで終了できるように、先に登録を解除する
+			release()
+			if err := h.Flush(); err != nil && h.opts.onError != nil {
+				h.opts.onError(err)
+			}
+			cancel()
+		case <-ctx.Done():
+			release()
+		}
+	}()
+	return ctx, func() {
+		cancel()
+		<-done
+	}
+}
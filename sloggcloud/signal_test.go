@@ -0,0 +1,89 @@
+package sloggcloud_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestHandleSignals(t *testing.T) {
+	t.Run("シグナルを受け取るとバッファのログを書き出してコンテキストをキャンセル", func(t *testing.T) {
+		var buf syncBuffer
+		handler := sloggcloud.New(&buf, sloggcloud.WithBuffer(4096, time.Hour))
+		t.Cleanup(func() { _ = handler.Close() })
+		sigCh := make(chan os.Signal, 1)
+		ctx, stop := sloggcloud.FlushOnSignal(context.Background(), handler, sigCh)
+		t.Cleanup(stop)
+
+		logger := slog.New(handler)
+		logger.Info("pending")
+		if got := buf.String(); got != "" {
+			t.Errorf("log was written before signal: %s", got)
+		}
+
+		sigCh <- os.Interrupt
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Fatal("context was not canceled after signal")
+		}
+		if !strings.Contains(buf.String(), `"message":"pending"`) {
+			t.Errorf("pending log was not written: %s", buf.String())
+		}
+
+		// 終了処理の間のログも出力できるように閉じない
+		logger.Info("shutting down")
+		if err := handler.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+		if !strings.Contains(buf.String(), `"message":"shutting down"`) {
+			t.Errorf("log after signal was not written: %s", buf.String())
+		}
+		if buf.closed {
+			t.Error("writer was closed on signal")
+		}
+	})
+
+	t.Run("親のコンテキストをキャンセルすると書き出さずに監視をやめる", func(t *testing.T) {
+		var buf syncBuffer
+		handler := sloggcloud.New(&buf, sloggcloud.WithBuffer(4096, time.Hour))
+		t.Cleanup(func() { _ = handler.Close() })
+		parent, cancel := context.WithCancel(context.Background())
+		ctx, stop := sloggcloud.FlushOnSignal(parent, handler, make(chan os.Signal))
+
+		slog.New(handler).Info("pending")
+		cancel()
+		stop()
+
+		if ctx.Err() == nil {
+			t.Error("context was not canceled")
+		}
+		if got := buf.String(); got != "" {
+			t.Errorf("log was written without signal: %s", got)
+		}
+	})
+
+	t.Run("stopで監視の goroutine を終了する", func(t *testing.T) {
+		var buf syncBuffer
+		handler := sloggcloud.New(&buf, sloggcloud.WithBuffer(4096, time.Hour))
+		t.Cleanup(func() { _ = handler.Close() })
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			ctx, stop := sloggcloud.HandleSignals(context.Background(), handler)
+			stop()
+			<-ctx.Done()
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("stop did not return")
+		}
+	})
+}
@@ -0,0 +1,90 @@
+// Package sloggcloudtest は sloggcloud を使ったログ出力をテストするためのヘルパーを提供します。
+package sloggcloudtest
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+// messageKey は Entry の Message として取り出すキーです。
+const messageKey = "message"
+
+// Entry は Handler が出力したログエントリです。
+// Cloud Logging が特別に扱うフィールドは sloggcloud.Entry のフィールドとして、それ以外の属性は Payload として参照できます。
+// Payload の数値は精度が失われないように json.Number として保持されます。
+type Entry struct {
+	sloggcloud.Entry
+	// Message は message フィールドの値です。WithMessageKey でキーを変更した場合は Payload に残ります。
+	Message string
+}
+
+// Attr は path で指定した属性の値を返します。グループの中の属性は、グループ名から順にキーを指定します。
+func (e Entry) Attr(path ...string) (any, bool) {
+	if len(path) == 0 {
+		return nil, false
+	}
+
+	var value any = e.Payload
+	for _, key := range path {
+		group, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = group[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// String は path で指定した属性の値を文字列として返します。属性がない場合や文字列でない場合は空文字列を返します。
+func (e Entry) String(path ...string) string {
+	value, _ := e.Attr(path...)
+	s, _ := value.(string)
+	return s
+}
+
+// Recorder は Handler が出力したログエントリを記録する sloggcloud.EntryLogger です。
+// 複数の goroutine から安全に利用できます。
+type Recorder struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// NewTestHandler は出力したログエントリを Recorder に記録する Handler を作成します。
+// opts には sloggcloud.New と同じオプションを指定できます。
+func NewTestHandler(opts ...sloggcloud.Option) (*sloggcloud.Handler, *Recorder) {
+	recorder := &Recorder{mu: sync.Mutex{}, entries: nil}
+	return sloggcloud.NewAPIHandler(recorder, opts...), recorder
+}
+
+// Log はログエントリを記録します。
+func (r *Recorder) Log(entry sloggcloud.Entry) {
+	message, _ := entry.Payload[messageKey].(string)
+	delete(entry.Payload, messageKey)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, Entry{Entry: entry, Message: message})
+}
+
+// Flush は何もしません。sloggcloud.EntryLogger を満たすために定義しています。
+func (r *Recorder) Flush() error {
+	return nil
+}
+
+// Entries は記録したログエントリを出力した順に返します。
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.entries)
+}
+
+// Reset は記録したログエントリを破棄します。
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
@@ -0,0 +1,99 @@
+package sloggcloudtest_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+	"github.com/p1ass/go-pkg/sloggcloud/sloggcloudtest"
+)
+
+func TestNewTestHandler(t *testing.T) {
+	handler, recorder := sloggcloudtest.NewTestHandler(
+		sloggcloud.WithProjectID("test-project"),
+		sloggcloud.WithLabels(map[string]string{"env": "test"}),
+	)
+	logger := slog.New(handler)
+
+	ctx := sloggcloud.ContextWithTrace(context.Background(), "01020304050607080102030405060708", "0102030405060708", true)
+	logger.InfoContext(ctx, "first", "user_id", "u-1")
+	logger.WithGroup("db").Error("second", "query", "SELECT 1", "rows", 3)
+
+	entries := recorder.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("len(Entries()) = %d, want 2", len(entries))
+	}
+
+	first := entries[0]
+	if first.Severity != "INFO" {
+		t.Errorf("Severity = %v, want INFO", first.Severity)
+	}
+	if first.Message != "first" {
+		t.Errorf("Message = %v, want first", first.Message)
+	}
+	if first.Trace != "projects/test-project/traces/01020304050607080102030405060708" {
+		t.Errorf("Trace = %v, want projects/test-project/traces/01020304050607080102030405060708", first.Trace)
+	}
+	if first.SpanID != "0102030405060708" || !first.TraceSampled {
+		t.Errorf("SpanID, TraceSampled = %v, %v, want 0102030405060708, true", first.SpanID, first.TraceSampled)
+	}
+	if diff := cmp.Diff(map[string]string{"env": "test"}, first.Labels); diff != "" {
+		t.Errorf("Labels mismatch (-want +got):\n%s", diff)
+	}
+	if first.SourceLocation == nil || first.SourceLocation.Function == "" {
+		t.Errorf("SourceLocation is not recorded: %v", first.SourceLocation)
+	}
+	if got := first.String("user_id"); got != "u-1" {
+		t.Errorf(`String("user_id") = %v, want u-1`, got)
+	}
+	if first.Timestamp.IsZero() {
+		t.Error("Timestamp is zero")
+	}
+
+	second := entries[1]
+	if second.Severity != "ERROR" || second.Message != "second" {
+		t.Errorf("Severity, Message = %v, %v, want ERROR, second", second.Severity, second.Message)
+	}
+	if got := second.String("db", "query"); got != "SELECT 1" {
+		t.Errorf(`String("db", "query") = %v, want SELECT 1`, got)
+	}
+	if got, _ := second.Attr("db", "rows"); got != json.Number("3") {
+		t.Errorf(`Attr("db", "rows") = %v, want 3`, got)
+	}
+	if _, ok := second.Attr("db", "missing"); ok {
+		t.Error(`Attr("db", "missing") should not be found`)
+	}
+	if _, ok := second.Attr("db", "query", "nested"); ok {
+		t.Error(`Attr("db", "query", "nested") should not be found`)
+	}
+
+	recorder.Reset()
+	if got := recorder.Entries(); len(got) != 0 {
+		t.Errorf("len(Entries()) after Reset = %d, want 0", len(got))
+	}
+}
+
+func TestRecorder_concurrent(t *testing.T) {
+	handler, recorder := sloggcloudtest.NewTestHandler()
+	logger := slog.New(handler)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				logger.Info("concurrent")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(recorder.Entries()); got != 100 {
+		t.Errorf("len(Entries()) = %d, want 100", got)
+	}
+}
@@ -0,0 +1,131 @@
+package sloggcloud
+
+import (
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// unknownFunction は呼び出し元の情報がない場合に function に出力する値です。
+const unknownFunction = "unknown"
+
+// callStackKey は WithSourceStackDepth で出力する呼び出し元のスタックのキーです。
+const callStackKey = "callStack"
+
+// callStackFrame は callStack に出力する 1 つのフレームです。
+type callStackFrame struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Function string `json:"function"`
+}
+
+// sourceAttrs はレコードの呼び出し元からソースコードの位置情報の属性を作成します。
+// WithSourceStackDepth で 2 以上を指定した場合は、呼び出し元から順に並べたスタックも callStack として出力します。
+// 位置情報を出力しない場合は nil を返します。
+func (h *Handler) sourceAttrs(r slog.Record) []slog.Attr {
+	if !h.opts.addSource {
+		return nil
+	}
+	if h.opts.sourceMinLevel != nil && r.Level < h.opts.sourceMinLevel.Level() {
+		return nil
+	}
+	if r.PC == 0 {
+		if !h.opts.sourceFallback {
+			return nil
+		}
+		return []slog.Attr{slog.Group(h.opts.sourceKey, slog.String("function", unknownFunction))}
+	}
+
+	frames := h.callerFrames(r.PC, h.opts.sourceStackDepth)
+	frame := frames[0]
+	attrs := []slog.Attr{slog.Group(h.opts.sourceKey,
+		slog.String("file", h.sourceFile(frame.File)),
+		slog.Int("line", frame.Line),
+		slog.String("function", h.sourceFunction(frame.Function)),
+	)}
+	if h.opts.sourceStackDepth > 1 {
+		stack := make([]callStackFrame, len(frames))
+		for i, f := range frames {
+			stack[i] = callStackFrame{File: h.sourceFile(f.File), Line: f.Line, Function: h.sourceFunction(f.Function)}
+		}
+		attrs = append(attrs, slog.Any(callStackKey, stack))
+	}
+	return attrs
+}
+
+// sourceFile は WithSourceShortFile の設定に従って出力するファイル名を返します。
+func (h *Handler) sourceFile(file string) string {
+	if h.opts.sourceShortFile {
+		return filepath.Base(file)
+	}
+	return file
+}
+
+// sourceFunction は WithSourceFunctionTrim の設定に従って出力する関数名を返します。
+func (h *Handler) sourceFunction(function string) string {
+	if h.opts.sourceFunctionTrim {
+		return trimFunctionPath(function)
+	}
+	return function
+}
+
+// trimFunctionPath は github.com/org/repo/pkg.Func のような関数名からインポートパスのディレクトリを取り除き、pkg.Func にします。
+// 型引数にもインポートパスが含まれることがあるため、型引数やレシーバより前の部分だけを対象にします。
+func trimFunctionPath(function string) string {
+	end := len(function)
+	if i := strings.IndexAny(function, "[("); i >= 0 {
+		end = i
+	}
+	if i := strings.LastIndex(function[:end], "/"); i >= 0 {
+		return function[i+1:]
+	}
+	return function
+}
+
+// maxCallerDepth は呼び出し元を探すスタックの深さの上限です。
+const maxCallerDepth = 64
+
+// callerFrames は pc のフレームから WithCallerSkip で指定した数だけ呼び出し元をさかのぼったフレームを先頭に、
+// そこから呼び出し元に向かって最大 depth 個のフレームを返します。
+// レコードの PC は 1 つのフレームしか表さないため、現在の goroutine のスタックから pc を探してさかのぼります。
+// Handle がログを出力した goroutine で同期的に呼び出された場合のみ pc がスタックに含まれ、
+// 見つからない場合や指定した数だけさかのぼれない場合は pc のフレームのみを返します。
+func (h *Handler) callerFrames(pc uintptr, depth int) []runtime.Frame {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	pcFrame := []runtime.Frame{frame}
+	if h.opts.callerSkip <= 0 && depth <= 1 {
+		return pcFrame
+	}
+
+	var pcs [maxCallerDepth]uintptr
+	n := runtime.Callers(2, pcs[:])
+	i := slices.Index(pcs[:n], pc)
+	if i < 0 {
+		return pcFrame
+	}
+	// インライン展開された関数も 1 つのフレームとして数えるため、PC ではなくフレーム単位でさかのぼる
+	frames := runtime.CallersFrames(pcs[i:n])
+	caller, more := frames.Next()
+	for range h.opts.callerSkip {
+		if !more {
+			return pcFrame
+		}
+		caller, more = frames.Next()
+	}
+	if caller.PC == 0 {
+		return pcFrame
+	}
+
+	stack := []runtime.Frame{caller}
+	for more && len(stack) < depth {
+		caller, more = frames.Next()
+		// runtime.main や runtime.goexit はどのログでも同じでデバッグの役に立たないため含めない
+		if caller.PC == 0 || strings.HasPrefix(caller.Function, "runtime.") {
+			break
+		}
+		stack = append(stack, caller)
+	}
+	return stack
+}
@@ -0,0 +1,405 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithSourceFallback(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []sloggcloud.Option
+		want map[string]interface{}
+	}{
+		{
+			name: "フォールバックしない場合は位置情報を出力しない",
+			opts: nil,
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "no caller",
+			},
+		},
+		{
+			name: "フォールバックする場合はfunctionがunknownの位置情報を出力",
+			opts: []sloggcloud.Option{sloggcloud.WithSourceFallback(true)},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "no caller",
+				"logging.googleapis.com/sourceLocation": map[string]interface{}{
+					"function": "unknown",
+				},
+			},
+		},
+		{
+			name: "変更したキーでフォールバック",
+			opts: []sloggcloud.Option{sloggcloud.WithSourceFallback(true), sloggcloud.WithSourceKey("source")},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "no caller",
+				"source": map[string]interface{}{
+					"function": "unknown",
+				},
+			},
+		},
+		{
+			name: "位置情報の出力が無効な場合はフォールバックしない",
+			opts: []sloggcloud.Option{sloggcloud.WithSourceFallback(true), sloggcloud.WithSource(false)},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "no caller",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := sloggcloud.New(&buf, append([]sloggcloud.Option{sloggcloud.WithSource(true)}, tt.opts...)...)
+
+			// 手動で作成したレコードは呼び出し元の PC を持たない
+			r := slog.NewRecord(time.Now(), slog.LevelInfo, "no caller", 0)
+			if err := h.Handle(context.Background(), r); err != nil {
+				t.Fatalf("failed to handle record: %v", err)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// infof は slog.Logger をラップしたログ出力のヘルパーです。
+func infof(logger *slog.Logger, format string, args ...any) {
+	logger.Info(fmt.Sprintf(format, args...))
+}
+
+func TestWithCallerSkip(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []sloggcloud.Option
+		wantFunction string
+	}{
+		{
+			name:         "指定しない場合はラップした関数を出力",
+			opts:         nil,
+			wantFunction: "github.com/p1ass/go-pkg/sloggcloud_test.infof",
+		},
+		{
+			name:         "1つさかのぼった呼び出し元を出力",
+			opts:         []sloggcloud.Option{sloggcloud.WithCallerSkip(1)},
+			wantFunction: "github.com/p1ass/go-pkg/sloggcloud_test.TestWithCallerSkip.func1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append([]sloggcloud.Option{sloggcloud.WithSource(true)}, tt.opts...)...))
+
+			infof(logger, "through %s", "wrapper")
+
+			var got struct {
+				Source struct {
+					File     string `json:"file"`
+					Function string `json:"function"`
+				} `json:"logging.googleapis.com/sourceLocation"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantFunction, got.Source.Function); diff != "" {
+				t.Errorf("function mismatch (-want +got):\n%s", diff)
+			}
+			if filepath.Base(got.Source.File) != "source_test.go" {
+				t.Errorf("file = %s, want source_test.go", got.Source.File)
+			}
+		})
+	}
+
+	t.Run("スタックに含まれないPCの場合はそのPCを出力", func(t *testing.T) {
+		var buf bytes.Buffer
+		h := sloggcloud.New(&buf, sloggcloud.WithSource(true), sloggcloud.WithCallerSkip(1))
+
+		var pcs [1]uintptr
+		runtime.Callers(1, pcs[:])
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "manual", pcs[0])
+		// 別の goroutine で処理するとレコードの PC は Handle を呼び出したスタックに含まれない
+		done := make(chan error)
+		go func() {
+			done <- h.Handle(context.Background(), r)
+		}()
+		if err := <-done; err != nil {
+			t.Fatalf("failed to handle record: %v", err)
+		}
+
+		var got struct {
+			Source struct {
+				Function string `json:"function"`
+			} `json:"logging.googleapis.com/sourceLocation"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		if want := "github.com/p1ass/go-pkg/sloggcloud_test.TestWithCallerSkip.func2"; got.Source.Function != want {
+			t.Errorf("function = %s, want %s", got.Source.Function, want)
+		}
+	})
+}
+
+// logFrom3 から logFrom1 は呼び出し元のスタックを確認するために 3 段の呼び出しを経由してログを出力します。
+func logFrom3(logger *slog.Logger, args ...any) { logFrom2(logger, args...) }
+func logFrom2(logger *slog.Logger, args ...any) { logFrom1(logger, args...) }
+func logFrom1(logger *slog.Logger, args ...any) { logger.Info("nested", args...) }
+
+func TestWithSourceStackDepth(t *testing.T) {
+	const pkg = "github.com/p1ass/go-pkg/sloggcloud_test."
+
+	tests := []struct {
+		name          string
+		opts          []sloggcloud.Option
+		args          []any
+		wantFunctions []string
+		wantAttr      map[string]interface{}
+	}{
+		{
+			name:          "指定しない場合はスタックを出力しない",
+			opts:          nil,
+			args:          nil,
+			wantFunctions: nil,
+			wantAttr:      nil,
+		},
+		{
+			name:          "指定した数のフレームを呼び出し元から順に出力",
+			opts:          []sloggcloud.Option{sloggcloud.WithSourceStackDepth(3)},
+			args:          nil,
+			wantFunctions: []string{pkg + "logFrom1", pkg + "logFrom2", pkg + "logFrom3"},
+			wantAttr:      nil,
+		},
+		{
+			name:          "WithCallerSkipでさかのぼったフレームから出力",
+			opts:          []sloggcloud.Option{sloggcloud.WithSourceStackDepth(2), sloggcloud.WithCallerSkip(1)},
+			args:          nil,
+			wantFunctions: []string{pkg + "logFrom2", pkg + "logFrom3"},
+			wantAttr:      nil,
+		},
+		{
+			name:          "同じキーの属性はリネーム",
+			opts:          []sloggcloud.Option{sloggcloud.WithSourceStackDepth(2)},
+			args:          []any{"callStack", "user value"},
+			wantFunctions: []string{pkg + "logFrom1", pkg + "logFrom2"},
+			wantAttr:      map[string]interface{}{"attr_callStack": "user value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append([]sloggcloud.Option{sloggcloud.WithSource(true)}, tt.opts...)...))
+
+			logFrom3(logger, tt.args...)
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			// sourceLocation は常に callStack の先頭のフレームと同じ
+			wantSource := pkg + "logFrom1"
+			if len(tt.wantFunctions) > 0 {
+				wantSource = tt.wantFunctions[0]
+			}
+			source, _ := got["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+			if diff := cmp.Diff(wantSource, source["function"]); diff != "" {
+				t.Errorf("source function mismatch (-want +got):\n%s", diff)
+			}
+
+			stack, _ := got["callStack"].([]interface{})
+			var gotFunctions []string
+			for _, f := range stack {
+				frame, _ := f.(map[string]interface{})
+				if filepath.Base(fmt.Sprint(frame["file"])) != "source_test.go" {
+					t.Errorf("file = %v, want source_test.go", frame["file"])
+				}
+				if line, _ := frame["line"].(float64); line <= 0 {
+					t.Errorf("line = %v, want positive", frame["line"])
+				}
+				gotFunctions = append(gotFunctions, fmt.Sprint(frame["function"]))
+			}
+			if diff := cmp.Diff(tt.wantFunctions, gotFunctions); diff != "" {
+				t.Errorf("callStack functions mismatch (-want +got):\n%s", diff)
+			}
+			for key, want := range tt.wantAttr {
+				if diff := cmp.Diff(want, got[key]); diff != "" {
+					t.Errorf("%s mismatch (-want +got):\n%s", key, diff)
+				}
+			}
+		})
+	}
+
+	t.Run("スタックの底より深い場合はruntimeのフレームを含めない", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(sloggcloud.New(&buf, sloggcloud.WithSource(true), sloggcloud.WithSourceStackDepth(100)))
+
+		logFrom3(logger)
+
+		var got struct {
+			CallStack []struct {
+				Function string `json:"function"`
+			} `json:"callStack"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		if len(got.CallStack) < 4 {
+			t.Fatalf("len(callStack) = %d, want at least 4", len(got.CallStack))
+		}
+		if last := got.CallStack[len(got.CallStack)-1].Function; last != "testing.tRunner" {
+			t.Errorf("last function = %s, want testing.tRunner", last)
+		}
+	})
+}
+
+func TestWithSourceMinLevel(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []sloggcloud.Option
+		level      slog.Level
+		wantSource bool
+	}{
+		{
+			name:       "指定しない場合はINFOでも出力",
+			opts:       nil,
+			level:      slog.LevelInfo,
+			wantSource: true,
+		},
+		{
+			name:       "しきい値未満のINFOでは出力しない",
+			opts:       []sloggcloud.Option{sloggcloud.WithSourceMinLevel(slog.LevelWarn)},
+			level:      slog.LevelInfo,
+			wantSource: false,
+		},
+		{
+			name:       "しきい値と等しいWARNでは出力",
+			opts:       []sloggcloud.Option{sloggcloud.WithSourceMinLevel(slog.LevelWarn)},
+			level:      slog.LevelWarn,
+			wantSource: true,
+		},
+		{
+			name:       "しきい値を超えるERRORでは出力",
+			opts:       []sloggcloud.Option{sloggcloud.WithSourceMinLevel(slog.LevelWarn)},
+			level:      slog.LevelError,
+			wantSource: true,
+		},
+		{
+			name:       "位置情報の出力が無効な場合はERRORでも出力しない",
+			opts:       []sloggcloud.Option{sloggcloud.WithSourceMinLevel(slog.LevelWarn), sloggcloud.WithSource(false)},
+			level:      slog.LevelError,
+			wantSource: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append([]sloggcloud.Option{sloggcloud.WithSource(true)}, tt.opts...)...))
+
+			logger.Log(context.Background(), tt.level, "source min level")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if _, gotSource := got["logging.googleapis.com/sourceLocation"]; gotSource != tt.wantSource {
+				t.Errorf("sourceLocation present = %v, want %v", gotSource, tt.wantSource)
+			}
+		})
+	}
+}
+
+// logGeneric は型引数を持つ関数の名前の短縮を確認するためのヘルパーです。
+func logGeneric[T any](logger *slog.Logger, v T) { logger.Info("generic", "value", v) }
+
+func TestWithSourceFunctionTrim(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []sloggcloud.Option
+		log          func(*slog.Logger)
+		wantFunction string
+		wantStack    []string
+	}{
+		{
+			name:         "デフォルトではインポートパスを含めて出力",
+			opts:         nil,
+			log:          func(l *slog.Logger) { infof(l, "trim") },
+			wantFunction: "github.com/p1ass/go-pkg/sloggcloud_test.infof",
+			wantStack:    nil,
+		},
+		{
+			name:         "パッケージ名と関数名に短縮",
+			opts:         []sloggcloud.Option{sloggcloud.WithSourceFunctionTrim(true)},
+			log:          func(l *slog.Logger) { infof(l, "trim") },
+			wantFunction: "sloggcloud_test.infof",
+			wantStack:    nil,
+		},
+		{
+			name:         "型引数を持つ関数も短縮",
+			opts:         []sloggcloud.Option{sloggcloud.WithSourceFunctionTrim(true)},
+			log:          func(l *slog.Logger) { logGeneric(l, 1) },
+			wantFunction: "sloggcloud_test.logGeneric[...]",
+			wantStack:    nil,
+		},
+		{
+			name:         "callStackの関数名も短縮",
+			opts:         []sloggcloud.Option{sloggcloud.WithSourceFunctionTrim(true), sloggcloud.WithSourceStackDepth(2)},
+			log:          func(l *slog.Logger) { logFrom3(l) },
+			wantFunction: "sloggcloud_test.logFrom1",
+			wantStack:    []string{"sloggcloud_test.logFrom1", "sloggcloud_test.logFrom2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append([]sloggcloud.Option{sloggcloud.WithSource(true)}, tt.opts...)...))
+
+			tt.log(logger)
+
+			var got struct {
+				Source struct {
+					Function string `json:"function"`
+				} `json:"logging.googleapis.com/sourceLocation"`
+				CallStack []struct {
+					Function string `json:"function"`
+				} `json:"callStack"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantFunction, got.Source.Function); diff != "" {
+				t.Errorf("function mismatch (-want +got):\n%s", diff)
+			}
+			var gotStack []string
+			for _, f := range got.CallStack {
+				gotStack = append(gotStack, f.Function)
+			}
+			if diff := cmp.Diff(tt.wantStack, gotStack); diff != "" {
+				t.Errorf("callStack mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
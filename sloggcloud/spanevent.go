@@ -0,0 +1,79 @@
+package sloggcloud
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// spanEventSeverityKey はスパンのイベントにログの severity を記録する属性のキーです。
+	spanEventSeverityKey = "log.severity"
+	// spanEventMessageKey はスパンのイベントにログのメッセージを記録する属性のキーです。
+	spanEventMessageKey = "log.message"
+)
+
+// recordSpanEvent はコンテキストの記録中のスパンに、レコードをイベントとして記録します。
+// レコードの属性にエラーがある場合は span.RecordError で、ない場合は span.AddEvent でメッセージと属性を記録し、
+// トレースのウォーターフォールからエラーのログを辿れるようにします。
+func (h *Handler) recordSpanEvent(ctx context.Context, r slog.Record) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String(spanEventSeverityKey, h.opts.severityMapper(r.Level)),
+		attribute.String(spanEventMessageKey, r.Message),
+	}
+	var recordErr error
+	appendAttr := func(a slog.Attr) {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindAny && recordErr == nil {
+			if err, ok := a.Value.Any().(error); ok {
+				recordErr = err
+				return
+			}
+		}
+		if kv, ok := attrToAttribute(a); ok {
+			attrs = append(attrs, kv)
+		}
+	}
+	for _, a := range h.attrs {
+		appendAttr(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(a)
+		return true
+	})
+
+	if recordErr != nil {
+		span.RecordError(recordErr, trace.WithAttributes(attrs...), trace.WithTimestamp(r.Time))
+		return
+	}
+	span.AddEvent(r.Message, trace.WithAttributes(attrs...), trace.WithTimestamp(r.Time))
+}
+
+// attrToAttribute は slog.Attr を OpenTelemetry の属性に変換します。
+// グループはスパンの属性で表現できないため変換しません。
+func attrToAttribute(a slog.Attr) (attribute.KeyValue, bool) {
+	switch a.Value.Kind() {
+	case slog.KindBool:
+		return attribute.Bool(a.Key, a.Value.Bool()), true
+	case slog.KindInt64:
+		return attribute.Int64(a.Key, a.Value.Int64()), true
+	case slog.KindFloat64:
+		return attribute.Float64(a.Key, a.Value.Float64()), true
+	case slog.KindString:
+		return attribute.String(a.Key, a.Value.String()), true
+	case slog.KindTime:
+		return attribute.String(a.Key, a.Value.Time().Format(time.RFC3339Nano)), true
+	case slog.KindGroup:
+		return attribute.KeyValue{}, false
+	case slog.KindAny, slog.KindDuration, slog.KindLogValuer, slog.KindUint64:
+	}
+	return attribute.String(a.Key, a.Value.String()), true
+}
@@ -0,0 +1,123 @@
+package sloggcloud_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanEvent はスパンに記録されたイベントです。
+type spanEvent struct {
+	Name  string
+	Err   string
+	Attrs map[string]string
+}
+
+// eventSpan は記録中のスパンとして、記録されたイベントを保持します。
+type eventSpan struct {
+	trace.Span
+	events *[]spanEvent
+}
+
+func (s eventSpan) IsRecording() bool {
+	return true
+}
+
+func (s eventSpan) AddEvent(name string, opts ...trace.EventOption) {
+	*s.events = append(*s.events, spanEvent{Name: name, Err: "", Attrs: eventAttrs(opts)})
+}
+
+func (s eventSpan) RecordError(err error, opts ...trace.EventOption) {
+	*s.events = append(*s.events, spanEvent{Name: "exception", Err: err.Error(), Attrs: eventAttrs(opts)})
+}
+
+func eventAttrs(opts []trace.EventOption) map[string]string {
+	attrs := map[string]string{}
+	cfg := trace.NewEventConfig(opts...)
+	for _, kv := range cfg.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	return attrs
+}
+
+func TestWithSpanEventOnError(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		log     func(ctx context.Context, logger *slog.Logger)
+		want    []spanEvent
+	}{
+		{
+			name:    "ERRORのログをイベントとして記録",
+			enabled: true,
+			log: func(ctx context.Context, logger *slog.Logger) {
+				logger.With("user", "u-1").ErrorContext(ctx, "failed to charge", "amount", 100)
+			},
+			want: []spanEvent{
+				{
+					Name: "failed to charge",
+					Attrs: map[string]string{
+						"log.severity": "ERROR",
+						"log.message":  "failed to charge",
+						"user":         "u-1",
+						"amount":       "100",
+					},
+				},
+			},
+		},
+		{
+			name:    "エラーの属性がある場合はRecordErrorで記録",
+			enabled: true,
+			log: func(ctx context.Context, logger *slog.Logger) {
+				logger.ErrorContext(ctx, "failed to charge", "error", errors.New("card declined"))
+			},
+			want: []spanEvent{
+				{
+					Name: "exception",
+					Err:  "card declined",
+					Attrs: map[string]string{
+						"log.severity": "ERROR",
+						"log.message":  "failed to charge",
+					},
+				},
+			},
+		},
+		{
+			name:    "INFOのログは記録しない",
+			enabled: true,
+			log: func(ctx context.Context, logger *slog.Logger) {
+				logger.InfoContext(ctx, "charged")
+			},
+			want: nil,
+		},
+		{
+			name:    "無効の場合は記録しない",
+			enabled: false,
+			log: func(ctx context.Context, logger *slog.Logger) {
+				logger.ErrorContext(ctx, "failed to charge")
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []spanEvent
+			span := eventSpan{Span: trace.SpanFromContext(context.Background()), events: &events}
+			ctx := trace.ContextWithSpan(context.Background(), span)
+			logger := slog.New(sloggcloud.New(io.Discard, sloggcloud.WithSpanEventOnError(tt.enabled)))
+
+			tt.log(ctx, logger)
+
+			if diff := cmp.Diff(tt.want, events); diff != "" {
+				t.Errorf("events mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
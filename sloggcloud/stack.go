@@ -0,0 +1,66 @@
+package sloggcloud
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// maxStackDepth はスタックトレースとして取得するフレーム数の上限です。
+const maxStackDepth = 64
+
+// stackTraceKey は Error Reporting がスタックトレースとして扱うキーです。
+const stackTraceKey = "stack_trace"
+
+// stackFrom はログの出力箇所 pc から呼び出し元をたどったプログラムカウンタの一覧を返します。
+// Handle は pc を記録した goroutine と同じ goroutine で呼び出されるため、現在のスタックから pc を探し、
+// それより上の slog やハンドラの内部のフレームを取り除きます。
+// pc が見つからない場合は pc のみを返します。
+func stackFrom(pc uintptr) []uintptr {
+	if pc == 0 {
+		return nil
+	}
+
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(1, pcs)
+	pcs = pcs[:n]
+	if i := slices.Index(pcs, pc); i >= 0 {
+		return pcs[i:]
+	}
+	return []uintptr{pc}
+}
+
+// formatStack はプログラムカウンタの一覧を runtime/debug.Stack と同じ形式の文字列に変換します。
+func formatStack(message string, pcs []uintptr) string {
+	var b strings.Builder
+	b.WriteString(message)
+	// Error Reporting は goroutine のヘッダー行で Go のスタックトレースを判別するため、ヘッダーを付与する
+	b.WriteString("\n\ngoroutine 1 [running]:\n")
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s(...)\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// stackTraceAttr はレコードに stack_trace を付与する必要がある場合に、その属性を返します。
+// WithStackTrace で指定したレベル以上のレコードと、Error Reporting に送るレコードが対象です。
+func (h *Handler) stackTraceAttr(r slog.Record) (slog.Attr, bool) {
+	enabled := (h.opts.addStackTrace && r.Level >= h.opts.stackTraceLevel) ||
+		(h.opts.errorReporting != nil && r.Level >= slog.LevelError)
+	if !enabled {
+		return slog.Attr{}, false
+	}
+
+	pcs := stackFrom(r.PC)
+	if len(pcs) == 0 {
+		return slog.Attr{}, false
+	}
+	return slog.String(stackTraceKey, formatStack(r.Message, pcs)), true
+}
@@ -0,0 +1,75 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithStackTrace(t *testing.T) {
+	tests := []struct {
+		name           string
+		level          slog.Level
+		opts           []sloggcloud.Option
+		wantStackTrace bool
+	}{
+		{
+			name:           "最小レベル以上のログにスタックトレースを付与",
+			level:          slog.LevelError,
+			opts:           []sloggcloud.Option{sloggcloud.WithStackTrace(slog.LevelError)},
+			wantStackTrace: true,
+		},
+		{
+			name:           "最小レベル未満のログにはスタックトレースを付与しない",
+			level:          slog.LevelInfo,
+			opts:           []sloggcloud.Option{sloggcloud.WithStackTrace(slog.LevelError)},
+			wantStackTrace: false,
+		},
+		{
+			name:           "最小レベルをWARNにした場合はWARNにも付与",
+			level:          slog.LevelWarn,
+			opts:           []sloggcloud.Option{sloggcloud.WithStackTrace(slog.LevelWarn)},
+			wantStackTrace: true,
+		},
+		{
+			name:           "オプションを指定しない場合はERRORでも付与しない",
+			level:          slog.LevelError,
+			opts:           []sloggcloud.Option{},
+			wantStackTrace: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := append([]sloggcloud.Option{sloggcloud.WithSource(false)}, tt.opts...)
+			logger := slog.New(sloggcloud.New(&buf, opts...))
+
+			logger.Log(t.Context(), tt.level, "stack trace message")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+
+			stackTrace, ok := got["stack_trace"].(string)
+			if ok != tt.wantStackTrace {
+				t.Fatalf("stack_trace presence = %v, want %v", ok, tt.wantStackTrace)
+			}
+			if !ok {
+				return
+			}
+
+			// 最初のフレームがログの出力箇所になっていることを確認する
+			_, frames, _ := strings.Cut(stackTrace, "goroutine 1 [running]:\n")
+			firstFrame, _, _ := strings.Cut(frames, "\n")
+			if !strings.Contains(firstFrame, "TestWithStackTrace") {
+				t.Errorf("first frame = %q, want the test function", firstFrame)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package sloggcloud
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// warnf はオプションの設定の誤りを記録します。
+// WithStrict(true) を後に指定した場合にも panic できるように、警告の出力は全てのオプションを適用した後の checkConfig で行います。
+func (o *options) warnf(format string, args ...any) {
+	o.configErrors = append(o.configErrors, fmt.Sprintf(format, args...))
+}
+
+// checkConfig は記録した設定の誤りを警告として出力します。
+// strict の場合は、出力できてもログが Cloud Logging で正しく扱われない設定も誤りとして扱い、panic します。
+func (o *options) checkConfig() {
+	if !o.strict {
+		for _, msg := range o.configErrors {
+			warnf("%s", msg)
+		}
+		return
+	}
+
+	errs := slices.Clone(o.configErrors)
+	if o.addTraceInfo && o.projectID == "" {
+		// Project ID がないとトレース ID を projects/[PROJECT_ID]/traces/[TRACE_ID] の形式にできず、Cloud Trace と関連付けられない
+		errs = append(errs, "project ID is empty: trace IDs cannot be linked to Cloud Trace")
+	}
+	if o.console && (o.maxMessageBytes > 0 || o.maxEntryBytes > 0 || o.entrySizeHook != nil) {
+		errs = append(errs, "WithMaxMessageBytes, WithMaxEntryBytes and WithEntrySizeHook have no effect with WithConsole")
+	}
+	for _, key := range slices.Sorted(maps.Keys(o.labels)) {
+		if sanitizeLabelKey(key) != key {
+			errs = append(errs, fmt.Sprintf("label key %q is rejected by Cloud Logging: use up to 63 characters of [a-z0-9_-] starting with a lowercase letter", key))
+		}
+	}
+	if len(errs) > 0 {
+		panic("sloggcloud: invalid configuration: " + strings.Join(errs, "; "))
+	}
+}
@@ -0,0 +1,99 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithStrict(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []sloggcloud.Option
+		wantPanic string
+		wantWarn  string
+	}{
+		{
+			name:      "正しい設定では panic しない",
+			opts:      []sloggcloud.Option{sloggcloud.WithStrict(true), sloggcloud.WithProjectID("test-project"), sloggcloud.WithLabels(map[string]string{"env": "prod"})},
+			wantPanic: "",
+			wantWarn:  "",
+		},
+		{
+			name:      "オプションの誤りで panic",
+			opts:      []sloggcloud.Option{sloggcloud.WithStrict(true), sloggcloud.WithProjectID("test-project"), sloggcloud.WithGzip(42)},
+			wantPanic: "sloggcloud: invalid configuration: gzip level 42 is invalid",
+			wantWarn:  "",
+		},
+		{
+			name:      "WithStrict を後に指定してもオプションの誤りで panic",
+			opts:      []sloggcloud.Option{sloggcloud.WithLogName(""), sloggcloud.WithProjectID("test-project"), sloggcloud.WithStrict(true)},
+			wantPanic: "log name must not be empty",
+			wantWarn:  "",
+		},
+		{
+			name:      "トレースを出力するのに Project ID がない場合は panic",
+			opts:      []sloggcloud.Option{sloggcloud.WithStrict(true)},
+			wantPanic: "project ID is empty",
+			wantWarn:  "",
+		},
+		{
+			name:      "トレースを出力しない場合は Project ID がなくても panic しない",
+			opts:      []sloggcloud.Option{sloggcloud.WithStrict(true), sloggcloud.WithTraceInfo(false)},
+			wantPanic: "",
+			wantWarn:  "",
+		},
+		{
+			name:      "コンソール形式で効果のないオプションを指定すると panic",
+			opts:      []sloggcloud.Option{sloggcloud.WithStrict(true), sloggcloud.WithProjectID("test-project"), sloggcloud.WithConsole(true), sloggcloud.WithMaxEntryBytes(1024)},
+			wantPanic: "have no effect with WithConsole",
+			wantWarn:  "",
+		},
+		{
+			name:      "使えないラベルのキーで panic",
+			opts:      []sloggcloud.Option{sloggcloud.WithStrict(true), sloggcloud.WithProjectID("test-project"), sloggcloud.WithLabels(map[string]string{"Env": "prod"})},
+			wantPanic: `label key "Env" is rejected by Cloud Logging`,
+			wantWarn:  "",
+		},
+		{
+			name:      "strict でない場合は警告して動作を続ける",
+			opts:      []sloggcloud.Option{sloggcloud.WithGzip(42), sloggcloud.WithConsole(true), sloggcloud.WithMaxEntryBytes(1024), sloggcloud.WithLabels(map[string]string{"Env": "prod"})},
+			wantPanic: "",
+			wantWarn:  "sloggcloud: gzip level 42 is invalid: use gzip.DefaultCompression instead\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+			t.Setenv("GCLOUD_PROJECT", "")
+			var warn bytes.Buffer
+			sloggcloud.SetWarnOutput(t, &warn)
+
+			var gotPanic string
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						gotPanic = fmt.Sprint(r)
+					}
+				}()
+				var buf bytes.Buffer
+				slog.New(sloggcloud.New(&buf, tt.opts...)).Info("strict message")
+			}()
+
+			if tt.wantPanic == "" && gotPanic != "" {
+				t.Errorf("New() panicked: %s", gotPanic)
+			}
+			if tt.wantPanic != "" && !strings.Contains(gotPanic, tt.wantPanic) {
+				t.Errorf("panic = %q, want containing %q", gotPanic, tt.wantPanic)
+			}
+			if got := warn.String(); got != tt.wantWarn {
+				t.Errorf("warning = %q, want %q", got, tt.wantWarn)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package sloggcloud
+
+import "time"
+
+// TimestampSource はログの時刻として出力する時刻の取得元です。
+type TimestampSource string
+
+const (
+	// TimestampSourceRecord はレコードを作成した時刻を出力します。Cloud Logging のエントリの timestamp (イベントの発生時刻) に相当します。
+	TimestampSourceRecord TimestampSource = "record"
+	// TimestampSourceNow はハンドラがレコードを処理した時刻を出力します。
+	TimestampSourceNow TimestampSource = "now"
+)
+
+// recordTime は WithTimestampSource の設定に従ってレコードの時刻 t を置き換えます。
+func (h *Handler) recordTime(t time.Time) time.Time {
+	if h.opts.timestampSource == TimestampSourceNow {
+		return time.Now()
+	}
+	return t
+}
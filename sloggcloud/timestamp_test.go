@@ -0,0 +1,82 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithTimestampSource(t *testing.T) {
+	recorded := time.Now().Add(-10 * time.Minute)
+
+	tests := []struct {
+		name       string
+		opts       []sloggcloud.Option
+		wantRecord bool
+		wantWarn   bool
+	}{
+		{
+			name:       "指定しない場合はレコードの時刻を出力",
+			opts:       nil,
+			wantRecord: true,
+			wantWarn:   false,
+		},
+		{
+			name:       "recordの場合はレコードの時刻を出力",
+			opts:       []sloggcloud.Option{sloggcloud.WithTimestampSource(sloggcloud.TimestampSourceRecord)},
+			wantRecord: true,
+			wantWarn:   false,
+		},
+		{
+			name:       "nowの場合は処理した時刻を出力",
+			opts:       []sloggcloud.Option{sloggcloud.WithTimestampSource("now")},
+			wantRecord: false,
+			wantWarn:   false,
+		},
+		{
+			name:       "不正な値の場合は警告してレコードの時刻を出力",
+			opts:       []sloggcloud.Option{sloggcloud.WithTimestampSource("ingest")},
+			wantRecord: true,
+			wantWarn:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warn bytes.Buffer
+			sloggcloud.SetWarnOutput(t, &warn)
+
+			var buf bytes.Buffer
+			handler := sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...)
+
+			before := time.Now()
+			r := slog.NewRecord(recorded, slog.LevelInfo, "hello", 0)
+			if err := handler.Handle(context.Background(), r); err != nil {
+				t.Fatalf("failed to handle record: %v", err)
+			}
+			after := time.Now()
+
+			var got struct {
+				Time time.Time `json:"time"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			if tt.wantRecord {
+				if !got.Time.Equal(recorded) {
+					t.Errorf("time = %v, want %v", got.Time, recorded)
+				}
+			} else if got.Time.Before(before) || got.Time.After(after) {
+				t.Errorf("time = %v, want between %v and %v", got.Time, before, after)
+			}
+			if gotWarn := warn.Len() > 0; gotWarn != tt.wantWarn {
+				t.Errorf("warned = %v, want %v: %s", gotWarn, tt.wantWarn, warn.String())
+			}
+		})
+	}
+}
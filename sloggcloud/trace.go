@@ -0,0 +1,149 @@
+package sloggcloud
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// traceKey はトレース ID を出力するキーです。
+	traceKey = "logging.googleapis.com/trace"
+	// spanIDKey はスパン ID を出力するキーです。
+	spanIDKey = "logging.googleapis.com/spanId"
+	// traceSampledKey はトレースがサンプリングされたかどうかを出力するキーです。
+	traceSampledKey = "logging.googleapis.com/trace_sampled"
+)
+
+// traceInfo は OpenTelemetry 以外から取得したトレース情報です。
+type traceInfo struct {
+	traceID string
+	spanID  string
+	sampled bool
+}
+
+type traceInfoKey struct{}
+
+// ContextWithTrace はトレース情報を設定したコンテキストを返します。
+// コンテキストに有効な OpenTelemetry のスパンがない場合、Handler はここで設定したトレース情報を出力します。
+// traceID は 32 文字、spanID は 16 文字の 16 進数文字列で指定します。spanID は空でも構いません。
+func ContextWithTrace(ctx context.Context, traceID, spanID string, sampled bool) context.Context {
+	return context.WithValue(ctx, traceInfoKey{}, traceInfo{
+		traceID: traceID,
+		spanID:  spanID,
+		sampled: sampled,
+	})
+}
+
+// traceInfoFromContext はレコードに出力するトレース情報をコンテキストから取得します。
+// OpenTelemetry のスパンが有効な場合はそちらを優先します。
+func traceInfoFromContext(ctx context.Context) (traceInfo, bool) {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if spanCtx.IsValid() {
+		return traceInfo{
+			traceID: spanCtx.TraceID().String(),
+			spanID:  spanCtx.SpanID().String(),
+			sampled: spanCtx.IsSampled(),
+		}, true
+	}
+
+	info, ok := ctx.Value(traceInfoKey{}).(traceInfo)
+	if !ok || info.traceID == "" {
+		return traceInfo{}, false
+	}
+	return info, true
+}
+
+// traceInfo はレコードに出力するトレース情報を取得します。
+// WithTraceIDFunc で設定した関数がトレース ID を返した場合はそちらを優先します。
+func (h *Handler) traceInfo(ctx context.Context) (traceInfo, bool) {
+	if h.opts.traceIDFunc != nil {
+		info, _ := callContextFunc(ctx, h.opts.contextFuncTimeout, func(ctx context.Context) traceInfo {
+			traceID, spanID, sampled := h.opts.traceIDFunc(ctx)
+			return traceInfo{traceID: traceID, spanID: spanID, sampled: sampled}
+		})
+		if info.traceID != "" {
+			return info, true
+		}
+	}
+	return traceInfoFromContext(ctx)
+}
+
+// traceAttrs はコンテキストのトレース情報を Cloud Logging の形式の属性に変換します。
+func (h *Handler) traceAttrs(ctx context.Context) []slog.Attr {
+	info, ok := h.traceInfo(ctx)
+	if !ok {
+		return nil
+	}
+
+	attrs := []slog.Attr{
+		slog.String(traceKey, h.formatTraceID(info.traceID)),
+	}
+	if info.spanID != "" {
+		attrs = append(attrs, slog.String(spanIDKey, info.spanID))
+	}
+	attrs = append(attrs, slog.Bool(traceSampledKey, info.sampled))
+	return attrs
+}
+
+// isTraceKey は key がハンドラがトレース情報を出力するキーかどうかを返します。
+func isTraceKey(key string) bool {
+	return key == traceKey || key == spanIDKey || key == traceSampledKey
+}
+
+// hasTraceAttr は attrs にトップレベルのトレース情報の属性が含まれるかどうかを返します。
+func hasTraceAttr(attrs []slog.Attr) bool {
+	return slices.ContainsFunc(attrs, func(a slog.Attr) bool { return isTraceKey(a.Key) })
+}
+
+// formatTraceID は Google Cloud Logging の要件に従ってトレース ID をフォーマットします。
+func (h *Handler) formatTraceID(traceID string) string {
+	if h.opts.projectID == "" {
+		return traceID
+	}
+	return fmt.Sprintf("projects/%s/traces/%s", h.opts.projectID, traceID)
+}
+
+// attributesSpan は属性を参照できるスパンです。
+// OpenTelemetry SDK の ReadOnlySpan などが満たすため、SDK に依存せずに属性を取得できます。
+type attributesSpan interface {
+	Attributes() []attribute.KeyValue
+}
+
+// spanAttrs はコンテキストのスパンが持つ属性のうち、WithSpanAttributes で指定したキーのものを返します。
+// スパンが属性を参照できない場合は何も返しません。
+func (h *Handler) spanAttrs(ctx context.Context) []slog.Attr {
+	span, ok := trace.SpanFromContext(ctx).(attributesSpan)
+	if !ok {
+		return nil
+	}
+
+	attrs := make([]slog.Attr, 0, len(h.opts.spanAttributeKeys))
+	for _, kv := range span.Attributes() {
+		if _, ok := h.opts.spanAttributeKeys[string(kv.Key)]; ok {
+			attrs = append(attrs, attributeToAttr(kv))
+		}
+	}
+	return attrs
+}
+
+// attributeToAttr は OpenTelemetry の属性を slog.Attr に変換します。
+func attributeToAttr(kv attribute.KeyValue) slog.Attr {
+	key := string(kv.Key)
+	switch kv.Value.Type() {
+	case attribute.BOOL:
+		return slog.Bool(key, kv.Value.AsBool())
+	case attribute.INT64:
+		return slog.Int64(key, kv.Value.AsInt64())
+	case attribute.FLOAT64:
+		return slog.Float64(key, kv.Value.AsFloat64())
+	case attribute.STRING:
+		return slog.String(key, kv.Value.AsString())
+	case attribute.INVALID, attribute.BOOLSLICE, attribute.INT64SLICE, attribute.FLOAT64SLICE, attribute.STRINGSLICE:
+	}
+	return slog.Any(key, kv.Value.AsInterface())
+}
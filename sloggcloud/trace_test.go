@@ -0,0 +1,346 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestContextWithTrace(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		opts   []sloggcloud.Option
+		want   map[string]interface{}
+	}{
+		{
+			name:   "ヘッダーから取得したトレース情報を出力",
+			header: "0102030405060708090a0b0c0d0e0f10/1;o=1",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithProjectID("test-project"),
+				sloggcloud.WithSource(false),
+			},
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "message with trace",
+				"logging.googleapis.com/trace":         "projects/test-project/traces/0102030405060708090a0b0c0d0e0f10",
+				"logging.googleapis.com/spanId":        "0000000000000001",
+				"logging.googleapis.com/trace_sampled": true,
+			},
+		},
+		{
+			name:   "スパンIDがない場合はトレースIDのみ出力",
+			header: "0102030405060708090a0b0c0d0e0f10;o=0",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithProjectID("test-project"),
+				sloggcloud.WithSource(false),
+			},
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "message with trace",
+				"logging.googleapis.com/trace":         "projects/test-project/traces/0102030405060708090a0b0c0d0e0f10",
+				"logging.googleapis.com/trace_sampled": false,
+			},
+		},
+		{
+			name:   "プロジェクトIDがない場合はトレースIDをそのまま出力",
+			header: "0102030405060708090a0b0c0d0e0f10/1;o=1",
+			opts: []sloggcloud.Option{
+				sloggcloud.WithSource(false),
+			},
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "message with trace",
+				"logging.googleapis.com/trace":         "0102030405060708090a0b0c0d0e0f10",
+				"logging.googleapis.com/spanId":        "0000000000000001",
+				"logging.googleapis.com/trace_sampled": true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOOGLE_CLOUD_PROJECT", "")
+			t.Setenv("GCLOUD_PROJECT", "")
+
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, tt.opts...))
+
+			traceID, spanID, sampled, ok := sloggcloud.TraceFromCloudHeader(tt.header)
+			if !ok {
+				t.Fatalf("failed to parse header: %s", tt.header)
+			}
+			ctx := sloggcloud.ContextWithTrace(context.Background(), traceID, spanID, sampled)
+			logger.InfoContext(ctx, "message with trace")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+type requestTraceKey struct{}
+
+func TestWithTraceIDFunc(t *testing.T) {
+	// ヘッダーなどから取得したトレース ID をコンテキストに保持する独自のトレーシングを想定する
+	traceIDFunc := func(ctx context.Context) (string, string, bool) {
+		traceID, _ := ctx.Value(requestTraceKey{}).(string)
+		return traceID, "0000000000000002", true
+	}
+	otelCtx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+		SpanID:     trace.SpanID{0, 0, 0, 0, 0, 0, 0, 0x01},
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want map[string]interface{}
+	}{
+		{
+			name: "関数から取得したトレース情報をプロジェクトIDを付けて出力",
+			ctx:  context.WithValue(context.Background(), requestTraceKey{}, "custom-trace-id"),
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "message with trace",
+				"logging.googleapis.com/trace":         "projects/test-project/traces/custom-trace-id",
+				"logging.googleapis.com/spanId":        "0000000000000002",
+				"logging.googleapis.com/trace_sampled": true,
+			},
+		},
+		{
+			name: "OpenTelemetryのスパンよりも優先",
+			ctx:  context.WithValue(otelCtx, requestTraceKey{}, "custom-trace-id"),
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "message with trace",
+				"logging.googleapis.com/trace":         "projects/test-project/traces/custom-trace-id",
+				"logging.googleapis.com/spanId":        "0000000000000002",
+				"logging.googleapis.com/trace_sampled": true,
+			},
+		},
+		{
+			name: "関数がトレースIDを返さない場合はOpenTelemetryのスパンを使用",
+			ctx:  otelCtx,
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "message with trace",
+				"logging.googleapis.com/trace":         "projects/test-project/traces/0102030405060708090a0b0c0d0e0f10",
+				"logging.googleapis.com/spanId":        "0000000000000001",
+				"logging.googleapis.com/trace_sampled": true,
+			},
+		},
+		{
+			name: "どちらもない場合はトレース情報を出力しない",
+			ctx:  context.Background(),
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message with trace",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf,
+				sloggcloud.WithProjectID("test-project"),
+				sloggcloud.WithSource(false),
+				sloggcloud.WithTraceIDFunc(traceIDFunc),
+			))
+
+			logger.InfoContext(tt.ctx, "message with trace")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// recordingSpan は OpenTelemetry SDK の ReadOnlySpan と同様に属性を参照できるスパンです。
+type recordingSpan struct {
+	trace.Span
+	attrs []attribute.KeyValue
+}
+
+func (s recordingSpan) Attributes() []attribute.KeyValue {
+	return s.attrs
+}
+
+func TestWithSpanAttributes(t *testing.T) {
+	spanAttrs := []attribute.KeyValue{
+		attribute.String("http.route", "/users/{id}"),
+		attribute.Int64("user.id", 42),
+		attribute.Bool("cache.hit", true),
+		attribute.StringSlice("tags", []string{"a", "b"}),
+		attribute.String("secret", "do-not-copy"),
+	}
+
+	tests := []struct {
+		name string
+		span trace.Span
+		keys []string
+		want map[string]interface{}
+	}{
+		{
+			name: "指定したキーの属性を出力",
+			span: recordingSpan{Span: trace.SpanFromContext(context.Background()), attrs: spanAttrs},
+			keys: []string{"http.route", "user.id", "cache.hit", "tags"},
+			want: map[string]interface{}{
+				"severity":   "INFO",
+				"message":    "message with span attributes",
+				"http.route": "/users/{id}",
+				"user.id":    float64(42),
+				"cache.hit":  true,
+				"tags":       []interface{}{"a", "b"},
+			},
+		},
+		{
+			name: "スパンにないキーは出力しない",
+			span: recordingSpan{Span: trace.SpanFromContext(context.Background()), attrs: spanAttrs},
+			keys: []string{"missing"},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message with span attributes",
+			},
+		},
+		{
+			name: "属性を参照できないスパンの場合は何も出力しない",
+			span: trace.SpanFromContext(context.Background()),
+			keys: []string{"http.route"},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "message with span attributes",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf,
+				sloggcloud.WithSpanAttributes(tt.keys...),
+				sloggcloud.WithSource(false),
+			))
+
+			ctx := trace.ContextWithSpan(context.Background(), tt.span)
+			logger.InfoContext(ctx, "message with span attributes")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_explicitTrace(t *testing.T) {
+	spanCtx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+		SpanID:     trace.SpanID{0, 0, 0, 0, 0, 0, 0, 0x01},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	explicitTrace := slog.String("logging.googleapis.com/trace", "projects/other-project/traces/explicit")
+
+	tests := []struct {
+		name  string
+		opts  []sloggcloud.Option
+		attrs []slog.Attr
+		args  []any
+		// wantTraceFields は出力に含まれる logging.googleapis.com/trace のフィールドの数
+		wantTraceFields int
+		want            map[string]interface{}
+	}{
+		{
+			name:            "レコードで指定したトレースをスパンより優先",
+			opts:            nil,
+			args:            []any{explicitTrace},
+			wantTraceFields: 1,
+			want: map[string]interface{}{
+				"severity":                     "INFO",
+				"message":                      "explicit trace",
+				"logging.googleapis.com/trace": "projects/other-project/traces/explicit",
+			},
+		},
+		{
+			name:            "WithAttrsで指定したトレースをスパンより優先",
+			opts:            nil,
+			attrs:           []slog.Attr{explicitTrace, slog.String("logging.googleapis.com/spanId", "0000000000000009")},
+			wantTraceFields: 1,
+			want: map[string]interface{}{
+				"severity":                      "INFO",
+				"message":                       "explicit trace",
+				"logging.googleapis.com/trace":  "projects/other-project/traces/explicit",
+				"logging.googleapis.com/spanId": "0000000000000009",
+			},
+		},
+		{
+			name:            "nestedではattributesの下に出力しスパンのトレースも出力",
+			opts:            []sloggcloud.Option{sloggcloud.WithPayloadMode(sloggcloud.PayloadModeNested)},
+			args:            []any{explicitTrace},
+			wantTraceFields: 2,
+			want: map[string]interface{}{
+				"severity":                             "INFO",
+				"message":                              "explicit trace",
+				"logging.googleapis.com/trace":         "projects/test-project/traces/0102030405060708090a0b0c0d0e0f10",
+				"logging.googleapis.com/spanId":        "0000000000000001",
+				"logging.googleapis.com/trace_sampled": true,
+				"attributes": map[string]interface{}{
+					"logging.googleapis.com/trace": "projects/other-project/traces/explicit",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var handler slog.Handler = sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithProjectID("test-project"), sloggcloud.WithSource(false))...)
+			handler = handler.WithAttrs(tt.attrs)
+
+			slog.New(handler).InfoContext(spanCtx, "explicit trace", tt.args...)
+
+			// JSON を map に変換すると重複したキーは後の値で上書きされるため、変換する前に数える
+			if got := strings.Count(buf.String(), `"logging.googleapis.com/trace":`); got != tt.wantTraceFields {
+				t.Errorf("trace fields = %d, want %d: %s", got, tt.wantTraceFields, buf.String())
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
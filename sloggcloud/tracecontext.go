@@ -0,0 +1,113 @@
+package sloggcloud
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// cloudTraceContextHeader は App Engine や Cloud Run が付与するトレースヘッダーです。
+const cloudTraceContextHeader = "X-Cloud-Trace-Context"
+
+// traceparentHeader は W3C Trace Context のトレースヘッダーです。
+const traceparentHeader = "traceparent"
+
+// TraceFromCloudHeader は "TRACE_ID/SPAN_ID;o=1" 形式の X-Cloud-Trace-Context ヘッダーを解析します。
+// ヘッダーでは SPAN_ID が 10 進数で表現されますが、spanID は Cloud Logging の spanId と同じ 16 文字の 16 進数文字列で返します。
+// SPAN_ID が含まれない場合、spanID は空文字列になります。
+// 戻り値の値は ContextWithTrace にそのまま渡すことができます。
+func TraceFromCloudHeader(header string) (traceID, spanID string, sampled bool, ok bool) {
+	header, opts, _ := strings.Cut(header, ";")
+	traceIDStr, spanIDStr, hasSpan := strings.Cut(header, "/")
+
+	tid, err := trace.TraceIDFromHex(traceIDStr)
+	if err != nil {
+		return "", "", false, false
+	}
+
+	if hasSpan {
+		spanIDUint, err := strconv.ParseUint(spanIDStr, 10, 64)
+		if err != nil || spanIDUint == 0 {
+			return "", "", false, false
+		}
+		var sid trace.SpanID
+		binary.BigEndian.PutUint64(sid[:], spanIDUint)
+		spanID = sid.String()
+	}
+
+	return tid.String(), spanID, opts == "o=1", true
+}
+
+// parseCloudTraceContext は X-Cloud-Trace-Context ヘッダーを OpenTelemetry の SpanContext に変換します。
+// SpanContext はスパン ID が必須のため、SPAN_ID を含まないヘッダーは変換できません。
+func parseCloudTraceContext(header string) (trace.SpanContext, bool) {
+	traceIDStr, spanIDStr, sampled, ok := TraceFromCloudHeader(header)
+	if !ok || spanIDStr == "" {
+		return trace.SpanContext{}, false
+	}
+	traceID, _ := trace.TraceIDFromHex(traceIDStr)
+	spanID, _ := trace.SpanIDFromHex(spanIDStr)
+
+	var flags trace.TraceFlags
+	if sampled {
+		flags = trace.FlagsSampled
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		TraceState: trace.TraceState{},
+		Remote:     true,
+	}), true
+}
+
+// TraceFromTraceparent は "VERSION-TRACE_ID-SPAN_ID-FLAGS" 形式の W3C traceparent ヘッダーを解析します。
+// VERSION と FLAGS は 2 文字、TRACE_ID は 32 文字、SPAN_ID は 16 文字の小文字の 16 進数である必要があります。
+// 仕様で無効とされるバージョン ff と、すべて 0 のトレース ID とスパン ID は受け付けません。
+// 将来のバージョンとの互換性のため、00 より新しいバージョンでは FLAGS の後に続くフィールドを無視します。
+func TraceFromTraceparent(header string) (trace.SpanContext, bool) {
+	parts := strings.Split(header, "-")
+	if len(parts) < 4 || !isLowerHex(parts[0], 2) || parts[0] == "ff" || !isLowerHex(parts[3], 2) {
+		return trace.SpanContext{}, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return trace.SpanContext{}, false
+	}
+
+	traceID, err := trace.TraceIDFromHex(parts[1])
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	spanID, err := trace.SpanIDFromHex(parts[2])
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.TraceFlags(flags) & trace.FlagsSampled,
+		TraceState: trace.TraceState{},
+		Remote:     true,
+	}), true
+}
+
+// isLowerHex は s が n 文字の小文字の 16 進数かどうかを返します。
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
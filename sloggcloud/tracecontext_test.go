@@ -0,0 +1,199 @@
+package sloggcloud_test
+
+import (
+	"testing"
+
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestTraceFromCloudHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		wantTraceID string
+		wantSpanID  string
+		wantSampled bool
+		wantOK      bool
+	}{
+		{
+			name:        "サンプリングされたヘッダー",
+			header:      "0102030405060708090a0b0c0d0e0f10/1;o=1",
+			wantTraceID: "0102030405060708090a0b0c0d0e0f10",
+			wantSpanID:  "0000000000000001",
+			wantSampled: true,
+			wantOK:      true,
+		},
+		{
+			name:        "サンプリングされていないヘッダー",
+			header:      "0102030405060708090a0b0c0d0e0f10/18446744073709551615;o=0",
+			wantTraceID: "0102030405060708090a0b0c0d0e0f10",
+			wantSpanID:  "ffffffffffffffff",
+			wantSampled: false,
+			wantOK:      true,
+		},
+		{
+			name:        "オプションのないヘッダー",
+			header:      "0102030405060708090a0b0c0d0e0f10/256",
+			wantTraceID: "0102030405060708090a0b0c0d0e0f10",
+			wantSpanID:  "0000000000000100",
+			wantSampled: false,
+			wantOK:      true,
+		},
+		{
+			name:        "スパンIDのないヘッダー",
+			header:      "0102030405060708090a0b0c0d0e0f10;o=1",
+			wantTraceID: "0102030405060708090a0b0c0d0e0f10",
+			wantSpanID:  "",
+			wantSampled: true,
+			wantOK:      true,
+		},
+		{
+			name:   "空のヘッダー",
+			header: "",
+			wantOK: false,
+		},
+		{
+			name:   "トレースIDの長さが不正なヘッダー",
+			header: "010203/1;o=1",
+			wantOK: false,
+		},
+		{
+			name:   "トレースIDが16進数でないヘッダー",
+			header: "zz02030405060708090a0b0c0d0e0f10/1;o=1",
+			wantOK: false,
+		},
+		{
+			name:   "スパンIDが10進数でないヘッダー",
+			header: "0102030405060708090a0b0c0d0e0f10/abc;o=1",
+			wantOK: false,
+		},
+		{
+			name:   "スパンIDが空のヘッダー",
+			header: "0102030405060708090a0b0c0d0e0f10/;o=1",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTraceID, gotSpanID, gotSampled, gotOK := sloggcloud.TraceFromCloudHeader(tt.header)
+			if gotOK != tt.wantOK {
+				t.Fatalf("TraceFromCloudHeader() ok = %v, want %v", gotOK, tt.wantOK)
+			}
+			if gotTraceID != tt.wantTraceID {
+				t.Errorf("TraceFromCloudHeader() traceID = %v, want %v", gotTraceID, tt.wantTraceID)
+			}
+			if gotSpanID != tt.wantSpanID {
+				t.Errorf("TraceFromCloudHeader() spanID = %v, want %v", gotSpanID, tt.wantSpanID)
+			}
+			if gotSampled != tt.wantSampled {
+				t.Errorf("TraceFromCloudHeader() sampled = %v, want %v", gotSampled, tt.wantSampled)
+			}
+		})
+	}
+}
+
+func TestTraceFromTraceparent(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		wantTraceID string
+		wantSpanID  string
+		wantSampled bool
+		wantOK      bool
+	}{
+		{
+			name:        "サンプリングされたヘッダー",
+			header:      "00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01",
+			wantTraceID: "0102030405060708090a0b0c0d0e0f10",
+			wantSpanID:  "0102030405060708",
+			wantSampled: true,
+			wantOK:      true,
+		},
+		{
+			name:        "サンプリングされていないヘッダー",
+			header:      "00-0102030405060708090a0b0c0d0e0f10-0102030405060708-00",
+			wantTraceID: "0102030405060708090a0b0c0d0e0f10",
+			wantSpanID:  "0102030405060708",
+			wantSampled: false,
+			wantOK:      true,
+		},
+		{
+			name:        "新しいバージョンでは後続のフィールドを無視",
+			header:      "01-0102030405060708090a0b0c0d0e0f10-0102030405060708-01-extra",
+			wantTraceID: "0102030405060708090a0b0c0d0e0f10",
+			wantSpanID:  "0102030405060708",
+			wantSampled: true,
+			wantOK:      true,
+		},
+		{
+			name:   "無効なバージョン",
+			header: "ff-0102030405060708090a0b0c0d0e0f10-0102030405060708-01",
+			wantOK: false,
+		},
+		{
+			name:   "バージョンが16進数でない",
+			header: "zz-0102030405060708090a0b0c0d0e0f10-0102030405060708-01",
+			wantOK: false,
+		},
+		{
+			name:   "バージョン00で後続のフィールドがある",
+			header: "00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01-extra",
+			wantOK: false,
+		},
+		{
+			name:   "トレースIDの長さが不正",
+			header: "00-010203-0102030405060708-01",
+			wantOK: false,
+		},
+		{
+			name:   "スパンIDが16進数でない",
+			header: "00-0102030405060708090a0b0c0d0e0f10-zz02030405060708-01",
+			wantOK: false,
+		},
+		{
+			name:   "すべて0のトレースID",
+			header: "00-00000000000000000000000000000000-0102030405060708-01",
+			wantOK: false,
+		},
+		{
+			name:   "フラグの長さが不正",
+			header: "00-0102030405060708090a0b0c0d0e0f10-0102030405060708-1",
+			wantOK: false,
+		},
+		{
+			name:   "フィールドが足りない",
+			header: "00-0102030405060708090a0b0c0d0e0f10-0102030405060708",
+			wantOK: false,
+		},
+		{
+			name:   "空のヘッダー",
+			header: "",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOK := sloggcloud.TraceFromTraceparent(tt.header)
+			if gotOK != tt.wantOK {
+				t.Fatalf("TraceFromTraceparent() ok = %v, want %v", gotOK, tt.wantOK)
+			}
+			if !gotOK {
+				return
+			}
+			if got.TraceID().String() != tt.wantTraceID {
+				t.Errorf("TraceFromTraceparent() traceID = %v, want %v", got.TraceID(), tt.wantTraceID)
+			}
+			if got.SpanID().String() != tt.wantSpanID {
+				t.Errorf("TraceFromTraceparent() spanID = %v, want %v", got.SpanID(), tt.wantSpanID)
+			}
+			if got.IsSampled() != tt.wantSampled {
+				t.Errorf("TraceFromTraceparent() sampled = %v, want %v", got.IsSampled(), tt.wantSampled)
+			}
+			if !got.IsRemote() {
+				t.Error("TraceFromTraceparent() span context is not remote")
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package sloggcloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// truncatedSuffix は切り詰めたメッセージの末尾に付与する文字列です。
+	truncatedSuffix = "...[truncated]"
+	// truncatedLabelKey はエントリを切り詰めたことを示すラベルのキーです。
+	truncatedLabelKey = "truncated"
+)
+
+// truncatingWriter は slog.JSONHandler が出力した 1 行の JSON を、メッセージとエントリ全体の大きさの上限に収めて書き込みます。
+// Cloud Logging は 256KB を超えるエントリを受け付けず、エージェントが行ごと破棄することがあるため、
+// 上限を超えた場合は情報を減らしてでもエントリを残します。
+// 上限を切り詰めの結果と合わせて観測できるように、書き込んだエントリの大きさを WithEntrySizeHook の関数に通知します。
+type truncatingWriter struct {
+	w           io.Writer
+	severityKey string
+	messageKey  string
+	timeKey     string
+	// maxMessageBytes はメッセージの最大バイト数で、0 以下の場合は制限しない
+	maxMessageBytes int
+	// maxEntryBytes は改行を含むエントリ全体の最大バイト数で、0 以下の場合は制限しない
+	maxEntryBytes int
+	// sizeHook は書き込んだエントリのバイト数と切り詰めたかどうかを受け取る関数で、nil の場合は通知しない
+	sizeHook func(bytes int, truncated bool)
+}
+
+func newTruncatingWriter(w io.Writer, o *options) *truncatingWriter {
+	return &truncatingWriter{
+		w:               w,
+		severityKey:     o.severityKey,
+		messageKey:      o.messageKey,
+		timeKey:         o.timeKey,
+		maxMessageBytes: o.maxMessageBytes,
+		maxEntryBytes:   o.maxEntryBytes,
+		sizeHook:        o.entrySizeHook,
+	}
+}
+
+// Write は p を上限に収まるように切り詰めて書き込みます。
+func (w *truncatingWriter) Write(p []byte) (int, error) {
+	entry, truncated := w.truncate(p)
+	if _, err := w.w.Write(entry); err != nil {
+		return 0, fmt.Errorf("failed to write entry: %w", err)
+	}
+	if w.sizeHook != nil {
+		w.sizeHook(len(entry), truncated)
+	}
+	// slog.JSONHandler に対しては受け取った行をすべて書き込んだものとして報告する
+	return len(p), nil
+}
+
+// truncate は p を上限に収まるように切り詰めたエントリと、切り詰めたかどうかを返します。
+// メッセージはエントリより長くならないため、p が上限以下の場合は JSON を解析せずにそのまま返します。
+// 解析できない場合は切り詰めようがないため、ログを失わないようにそのまま返します。
+func (w *truncatingWriter) truncate(p []byte) ([]byte, bool) {
+	if !w.exceeds(len(p), w.maxMessageBytes) && !w.exceeds(len(p), w.maxEntryBytes) {
+		return p, false
+	}
+
+	fields, err := decodeJSONObject(p)
+	if err != nil {
+		return p, false
+	}
+
+	entry := truncatedEntry{fields: fields, messageKey: w.messageKey, truncated: false, messageTruncated: false}
+	if w.maxMessageBytes > 0 {
+		if err := entry.truncateMessage(w.maxMessageBytes); err != nil {
+			return p, false
+		}
+	}
+	if w.maxEntryBytes > 0 {
+		if err := entry.fit(w.maxEntryBytes, w.isEssentialKey); err != nil {
+			return p, false
+		}
+	}
+	if !entry.truncated {
+		return p, false
+	}
+
+	b, err := entry.encode()
+	if err != nil {
+		return p, false
+	}
+	return b, true
+}
+
+// exceeds は size が上限 limit を超えているかどうかを返します。
+func (w *truncatingWriter) exceeds(size, limit int) bool {
+	return limit > 0 && size > limit
+}
+
+// isEssentialKey はエントリ全体を切り詰める際にも残すフィールドかどうかを返します。
+func (w *truncatingWriter) isEssentialKey(key string) bool {
+	return key == w.severityKey || key == w.messageKey || key == w.timeKey || strings.HasPrefix(key, reservedKeyNamespace)
+}
+
+// jsonField は JSON オブジェクトの 1 つのフィールドです。出力の順序を保つためにスライスで保持します。
+type jsonField struct {
+	key   string
+	value json.RawMessage
+}
+
+// decodeJSONObject は 1 行の JSON オブジェクトをフィールドの順序を保ったまま解析します。
+func decodeJSONObject(p []byte) ([]jsonField, error) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("entry is not a JSON object")
+	}
+
+	var fields []jsonField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key: %w", err)
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, errors.New("key is not a string")
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("failed to read value of %s: %w", key, err)
+		}
+		fields = append(fields, jsonField{key: key, value: value})
+	}
+	return fields, nil
+}
+
+// truncatedEntry は切り詰めの対象となるエントリです。
+type truncatedEntry struct {
+	fields     []jsonField
+	messageKey string
+	// truncated はメッセージやフィールドを切り詰めたかどうか
+	truncated bool
+	// messageTruncated はメッセージを切り詰めたかどうか
+	messageTruncated bool
+}
+
+// truncateMessage はメッセージを maxBytes 以下に切り詰めます。
+func (e *truncatedEntry) truncateMessage(maxBytes int) error {
+	i := e.index(e.messageKey)
+	if i < 0 {
+		return nil
+	}
+	var msg string
+	if err := json.Unmarshal(e.fields[i].value, &msg); err != nil {
+		return fmt.Errorf("failed to decode message: %w", err)
+	}
+	if len(msg) <= maxBytes {
+		return nil
+	}
+	return e.setMessage(i, truncateString(msg, maxBytes))
+}
+
+// fit はエントリ全体が maxBytes 以下になるように、isEssentialKey が false のフィールドを大きい順に取り除きます。
+// それでも収まらない場合はメッセージを切り詰めます。
+func (e *truncatedEntry) fit(maxBytes int, isEssentialKey func(string) bool) error {
+	size, err := e.size()
+	if err != nil {
+		return err
+	}
+	if size <= maxBytes {
+		return nil
+	}
+	// 切り詰めたことを示すラベルの分も含めて上限に収める
+	if err := e.markTruncated(); err != nil {
+		return err
+	}
+
+	for {
+		if size, err = e.size(); err != nil {
+			return err
+		}
+		if size <= maxBytes {
+			return nil
+		}
+		largest := -1
+		for i, f := range e.fields {
+			if !isEssentialKey(f.key) && (largest < 0 || len(f.value) > len(e.fields[largest].value)) {
+				largest = i
+			}
+		}
+		if largest < 0 {
+			break
+		}
+		e.fields = slices.Delete(e.fields, largest, largest+1)
+	}
+
+	i := e.index(e.messageKey)
+	if i < 0 {
+		return nil
+	}
+	var msg string
+	if err := json.Unmarshal(e.fields[i].value, &msg); err != nil {
+		return fmt.Errorf("failed to decode message: %w", err)
+	}
+	// 既に切り詰めたメッセージを再度切り詰める際に、接尾辞が重ならないように元の部分だけを使う
+	if e.messageTruncated {
+		msg = strings.TrimSuffix(msg, truncatedSuffix)
+	}
+	// エスケープで長くなる分があるため、収まるまでメッセージを短くする
+	for limit := len(msg); size > maxBytes && limit > 0; {
+		limit = max(limit-(size-maxBytes), 0)
+		if err := e.setMessage(i, truncateString(msg, limit)); err != nil {
+			return err
+		}
+		if size, err = e.size(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setMessage は i 番目のフィールドのメッセージを msg に置き換え、切り詰めたことを記録します。
+func (e *truncatedEntry) setMessage(i int, msg string) error {
+	value, err := marshalJSON(msg)
+	if err != nil {
+		return err
+	}
+	e.fields[i].value = value
+	e.messageTruncated = true
+	return e.markTruncated()
+}
+
+// markTruncated は切り詰めたことを示す truncated ラベルを付与します。
+func (e *truncatedEntry) markTruncated() error {
+	if e.truncated {
+		return nil
+	}
+	e.truncated = true
+
+	labels := map[string]string{}
+	i := e.index(labelsKey)
+	if i >= 0 {
+		if err := json.Unmarshal(e.fields[i].value, &labels); err != nil {
+			return fmt.Errorf("failed to decode labels: %w", err)
+		}
+	}
+	labels[truncatedLabelKey] = "true"
+	value, err := marshalJSON(labels)
+	if err != nil {
+		return err
+	}
+	if i < 0 {
+		e.fields = append(e.fields, jsonField{key: labelsKey, value: value})
+		return nil
+	}
+	e.fields[i].value = value
+	return nil
+}
+
+// index は key のフィールドの位置を返します。存在しない場合は -1 を返します。
+func (e *truncatedEntry) index(key string) int {
+	return slices.IndexFunc(e.fields, func(f jsonField) bool { return f.key == key })
+}
+
+// size は改行を含めたエントリのバイト数を返します。
+func (e *truncatedEntry) size() (int, error) {
+	b, err := e.encode()
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// encode はエントリを改行で終わる 1 行の JSON に変換します。
+func (e *truncatedEntry) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range e.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := marshalJSON(f.key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(f.value)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), nil
+}
+
+// marshalJSON は slog.JSONHandler と同じく HTML の特殊文字をエスケープせずに v を JSON に変換します。
+func marshalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// truncateString は s を末尾の truncatedSuffix を含めて maxBytes 以下に切り詰めます。
+// マルチバイト文字の途中では切らないため、maxBytes より短くなることがあります。
+func truncateString(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	if maxBytes <= len(truncatedSuffix) {
+		return truncateUTF8(s, maxBytes)
+	}
+	return truncateUTF8(s, maxBytes-len(truncatedSuffix)) + truncatedSuffix
+}
+
+// truncateUTF8 は s を UTF-8 の文字の境界で n バイト以下に切り詰めます。
+func truncateUTF8(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
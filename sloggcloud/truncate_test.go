@@ -0,0 +1,210 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithMaxMessageBytes(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []sloggcloud.Option
+		msg        string
+		wantMaxLen int
+		wantTrunc  bool
+	}{
+		{
+			name:       "1MBのメッセージを切り詰め",
+			opts:       []sloggcloud.Option{sloggcloud.WithMaxMessageBytes(1024)},
+			msg:        strings.Repeat("a", 1<<20),
+			wantMaxLen: 1024,
+			wantTrunc:  true,
+		},
+		{
+			name:       "マルチバイト文字の途中では切らない",
+			opts:       []sloggcloud.Option{sloggcloud.WithMaxMessageBytes(1024)},
+			msg:        strings.Repeat("あ", 1<<18),
+			wantMaxLen: 1024,
+			wantTrunc:  true,
+		},
+		{
+			name:       "上限以下の場合は切り詰めない",
+			opts:       []sloggcloud.Option{sloggcloud.WithMaxMessageBytes(1024)},
+			msg:        strings.Repeat("a", 1024),
+			wantMaxLen: 1024,
+			wantTrunc:  false,
+		},
+		{
+			name:       "エントリ全体の上限に収まるようにメッセージを切り詰め",
+			opts:       []sloggcloud.Option{sloggcloud.WithMaxEntryBytes(4096)},
+			msg:        strings.Repeat("a", 1<<20),
+			wantMaxLen: 4096,
+			wantTrunc:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts, sloggcloud.WithSource(false))...))
+
+			logger.Info(tt.msg)
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			msg, _ := got["message"].(string)
+			if len(msg) > tt.wantMaxLen {
+				t.Errorf("message length = %d, want <= %d", len(msg), tt.wantMaxLen)
+			}
+			if !utf8.ValidString(msg) {
+				t.Errorf("message is not valid UTF-8")
+			}
+			if got := strings.HasSuffix(msg, "...[truncated]"); got != tt.wantTrunc {
+				t.Errorf("message has truncated suffix = %v, want %v", got, tt.wantTrunc)
+			}
+			labels, _ := got["logging.googleapis.com/labels"].(map[string]interface{})
+			if got := labels["truncated"] == "true"; got != tt.wantTrunc {
+				t.Errorf("truncated label = %v, want %v", got, tt.wantTrunc)
+			}
+		})
+	}
+}
+
+func TestWithMaxEntryBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []sloggcloud.Option
+		args []any
+		want map[string]interface{}
+	}{
+		{
+			name: "大きい属性から取り除く",
+			opts: []sloggcloud.Option{sloggcloud.WithMaxEntryBytes(1024)},
+			args: []any{"blob", strings.Repeat("a", 1<<20), "small", "kept"},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "entry",
+				"small":    "kept",
+				"logging.googleapis.com/labels": map[string]interface{}{
+					"env":       "test",
+					"truncated": "true",
+				},
+			},
+		},
+		{
+			name: "上限以下の場合は切り詰めない",
+			opts: []sloggcloud.Option{sloggcloud.WithMaxEntryBytes(1024)},
+			args: []any{"small", "kept"},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "entry",
+				"small":    "kept",
+				"logging.googleapis.com/labels": map[string]interface{}{
+					"env": "test",
+				},
+			},
+		},
+		{
+			name: "デフォルトでは制限しない",
+			opts: nil,
+			args: []any{"blob", strings.Repeat("a", 1<<20)},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "entry",
+				"blob":     strings.Repeat("a", 1<<20),
+				"logging.googleapis.com/labels": map[string]interface{}{
+					"env": "test",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := append([]sloggcloud.Option{
+				sloggcloud.WithSource(false),
+				sloggcloud.WithLabels(map[string]string{"env": "test"}),
+			}, tt.opts...)
+			logger := slog.New(sloggcloud.New(&buf, opts...))
+
+			logger.Info("entry", tt.args...)
+
+			if buf.Len() > 1024 && len(tt.opts) > 0 {
+				t.Errorf("entry length = %d, want <= 1024", buf.Len())
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			delete(got, "time")
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithEntrySizeHook(t *testing.T) {
+	type size struct {
+		bytes     int
+		truncated bool
+	}
+
+	tests := []struct {
+		name          string
+		opts          []sloggcloud.Option
+		msgs          []string
+		wantTruncated []bool
+	}{
+		{
+			name:          "切り詰めない場合は出力したバイト数を通知",
+			opts:          nil,
+			msgs:          []string{"short", strings.Repeat("a", 1000), strings.Repeat("あ", 1000)},
+			wantTruncated: []bool{false, false, false},
+		},
+		{
+			name:          "切り詰めた場合は切り詰めた後のバイト数を通知",
+			opts:          []sloggcloud.Option{sloggcloud.WithMaxEntryBytes(512)},
+			msgs:          []string{"short", strings.Repeat("a", 1000), strings.Repeat("あ", 1000)},
+			wantTruncated: []bool{false, true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []size
+			var buf bytes.Buffer
+			logger := slog.New(sloggcloud.New(&buf, append(tt.opts,
+				sloggcloud.WithSource(false),
+				sloggcloud.WithEntrySizeHook(func(bytes int, truncated bool) {
+					got = append(got, size{bytes: bytes, truncated: truncated})
+				}),
+			)...))
+
+			for _, msg := range tt.msgs {
+				logger.Info(msg)
+			}
+
+			lines := strings.SplitAfter(buf.String(), "\n")
+			lines = lines[:len(lines)-1]
+			want := make([]size, len(lines))
+			for i, line := range lines {
+				want[i] = size{bytes: len(line), truncated: tt.wantTruncated[i]}
+			}
+			if diff := cmp.Diff(want, got, cmp.AllowUnexported(size{})); diff != "" {
+				t.Errorf("sizes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
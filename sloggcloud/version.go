@@ -0,0 +1,41 @@
+package sloggcloud
+
+import (
+	"runtime/debug"
+)
+
+// readBuildInfo はビルド情報を取得します。テストで差し替えられるように変数にしています。
+var readBuildInfo = debug.ReadBuildInfo
+
+// versionFromBuildInfo はビルド情報からバージョンを取得します。
+// VCS のリビジョンが埋め込まれている場合はそれを使い、未コミットの変更がある場合は "-dirty" を付けます。
+// リビジョンがない場合は go install などで指定されたモジュールのバージョンを使います。
+func versionFromBuildInfo() string {
+	info, ok := readBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision != "" {
+		if modified {
+			return revision + "-dirty"
+		}
+		return revision
+	}
+
+	// ローカルでビルドした場合は "(devel)" となり、バージョンを特定できない
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return ""
+}
@@ -0,0 +1,157 @@
+package sloggcloud_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"runtime/debug"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p1ass/go-pkg/sloggcloud"
+)
+
+func TestWithVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   string
+		buildInfo *debug.BuildInfo
+		opts      []sloggcloud.Option
+		level     slog.Level
+		want      map[string]interface{}
+	}{
+		{
+			name:      "指定したバージョンをラベルに出力",
+			version:   "v1.2.3",
+			buildInfo: &debug.BuildInfo{},
+			opts:      []sloggcloud.Option{},
+			level:     slog.LevelInfo,
+			want: map[string]interface{}{
+				"logging.googleapis.com/labels": map[string]interface{}{"version": "v1.2.3"},
+			},
+		},
+		{
+			name:    "空の場合はビルド情報のリビジョンを出力",
+			version: "",
+			buildInfo: &debug.BuildInfo{
+				Main: debug.Module{Version: "(devel)"},
+				Settings: []debug.BuildSetting{
+					{Key: "vcs", Value: "git"},
+					{Key: "vcs.revision", Value: "0123456789abcdef"},
+					{Key: "vcs.modified", Value: "false"},
+				},
+			},
+			opts:  []sloggcloud.Option{},
+			level: slog.LevelInfo,
+			want: map[string]interface{}{
+				"logging.googleapis.com/labels": map[string]interface{}{"version": "0123456789abcdef"},
+			},
+		},
+		{
+			name:    "未コミットの変更がある場合はdirtyを付与",
+			version: "",
+			buildInfo: &debug.BuildInfo{
+				Settings: []debug.BuildSetting{
+					{Key: "vcs.revision", Value: "0123456789abcdef"},
+					{Key: "vcs.modified", Value: "true"},
+				},
+			},
+			opts:  []sloggcloud.Option{},
+			level: slog.LevelInfo,
+			want: map[string]interface{}{
+				"logging.googleapis.com/labels": map[string]interface{}{"version": "0123456789abcdef-dirty"},
+			},
+		},
+		{
+			name:      "リビジョンがない場合はモジュールのバージョンを出力",
+			version:   "",
+			buildInfo: &debug.BuildInfo{Main: debug.Module{Version: "v0.1.0"}},
+			opts:      []sloggcloud.Option{},
+			level:     slog.LevelInfo,
+			want: map[string]interface{}{
+				"logging.googleapis.com/labels": map[string]interface{}{"version": "v0.1.0"},
+			},
+		},
+		{
+			name:      "ビルド情報から特定できない場合は出力しない",
+			version:   "",
+			buildInfo: &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}},
+			opts:      []sloggcloud.Option{},
+			level:     slog.LevelInfo,
+			want:      map[string]interface{}{},
+		},
+		{
+			name:      "Error ReportingのserviceContextにも出力",
+			version:   "v1.2.3",
+			buildInfo: &debug.BuildInfo{},
+			opts:      []sloggcloud.Option{sloggcloud.WithErrorReporting("test-service", "")},
+			level:     slog.LevelError,
+			want: map[string]interface{}{
+				"logging.googleapis.com/labels": map[string]interface{}{"version": "v1.2.3"},
+				"@type":                         "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent",
+				"serviceContext": map[string]interface{}{
+					"service": "test-service",
+					"version": "v1.2.3",
+				},
+			},
+		},
+		{
+			name:      "WithErrorReportingで指定したバージョンを優先",
+			version:   "v1.2.3",
+			buildInfo: &debug.BuildInfo{},
+			opts:      []sloggcloud.Option{sloggcloud.WithErrorReporting("test-service", "v9.9.9")},
+			level:     slog.LevelError,
+			want: map[string]interface{}{
+				"logging.googleapis.com/labels": map[string]interface{}{"version": "v1.2.3"},
+				"@type":                         "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent",
+				"serviceContext": map[string]interface{}{
+					"service": "test-service",
+					"version": "v9.9.9",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sloggcloud.SetReadBuildInfo(t, func() (*debug.BuildInfo, bool) {
+				return tt.buildInfo, true
+			})
+
+			var buf bytes.Buffer
+			opts := append([]sloggcloud.Option{sloggcloud.WithVersion(tt.version), sloggcloud.WithSource(false)}, tt.opts...)
+			logger := slog.New(sloggcloud.New(&buf, opts...))
+
+			logger.Log(t.Context(), tt.level, "versioned")
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			for _, key := range []string{"time", "severity", "message", "stack_trace"} {
+				delete(got, key)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("ビルド情報を取得できない場合は出力しない", func(t *testing.T) {
+		sloggcloud.SetReadBuildInfo(t, func() (*debug.BuildInfo, bool) {
+			return nil, false
+		})
+
+		var buf bytes.Buffer
+		slog.New(sloggcloud.New(&buf, sloggcloud.WithVersion(""))).Info("versioned")
+
+		var got map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		if labels, ok := got["logging.googleapis.com/labels"]; ok {
+			t.Errorf("labels should not be emitted: %v", labels)
+		}
+	})
+}